@@ -30,33 +30,72 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/create": {
+        "/admin/export": {
             "post": {
-                "description": "Создает новую запись с автозаполнением возраста, пола и национальности при помощи открытых API.",
+                "description": "Ставит в очередь задачу выгрузки записей в формате NDJSON (одна запись JSON на строку). Записи отбираются теми же фильтрами, что и в /select (name, age_gte, enrichment_status и т.д.), без фильтров выгружаются все неудаленные записи. Готовая выгрузка доступна по /admin/export/{id}, где id — идентификатор задачи.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Операции"
+                    "Администрирование"
                 ],
-                "summary": "Создание записи",
-                "operationId": "create",
+                "summary": "Выгрузка записей",
+                "operationId": "export",
                 "parameters": [
                     {
-                        "description": "Тело запроса",
-                        "name": "body",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/effectivemobile.CreateRequest"
-                        }
+                        "type": "string",
+                        "description": "Имя или список имен через запятую",
+                        "name": "name",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Фамилия или список фамилий через запятую",
+                        "name": "surname",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Возраст больше или равен",
+                        "name": "age_gte",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Возраст меньше или равен",
+                        "name": "age_lte",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Пол или список полов через запятую",
+                        "name": "gender",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Национальность или список национальностей через запятую",
+                        "name": "nationality",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Записи, у которых часть полей не заполнена из-за низкой уверенности",
+                        "name": "needs_review",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Статус обогащения или список статусов через запятую (pending_enrichment, enriched, enrichment_failed)",
+                        "name": "enrichment_status",
+                        "in": "query"
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "Возвращается, если создание прошло успешно",
+                    "202": {
+                        "description": "Возвращается, если задача поставлена в очередь",
                         "schema": {
-                            "$ref": "#/definitions/effectivemobile.CreateResponse"
+                            "$ref": "#/definitions/effectivemobile.ExportResponse"
                         }
                     },
                     "400": {
@@ -65,12 +104,6 @@ const docTemplate = `{
                             "$ref": "#/definitions/effectivemobile.BadRequestResponse"
                         }
                     },
-                    "404": {
-                        "description": "Возвращается, если запрашиваемая запись не была найдена/во внешних API нет данных",
-                        "schema": {
-                            "$ref": "#/definitions/effectivemobile.NotFoundResponse"
-                        }
-                    },
                     "405": {
                         "description": "Возвращается, если был использован неправильный метод",
                         "schema": {
@@ -78,29 +111,36 @@ const docTemplate = `{
                         }
                     },
                     "500": {
-                        "description": "Возвращается, если во время работы хранилища/клиента произошла ошибка",
+                        "description": "Возвращается, если во время работы хранилища произошла ошибка",
                         "schema": {
                             "$ref": "#/definitions/effectivemobile.InternalServerErrorResponse"
                         }
+                    },
+                    "504": {
+                        "description": "Возвращается, если операция не уложилась в отведенное время",
+                        "schema": {
+                            "$ref": "#/definitions/effectivemobile.GatewayTimeoutResponse"
+                        }
                     }
                 }
             }
         },
-        "/delete/{id}": {
-            "delete": {
-                "description": "Удаляет запись из базы данных по заданному идентификатору.",
+        "/admin/export/{id}": {
+            "get": {
+                "description": "Отдает готовую выгрузку записей в формате NDJSON. Пока задача выгрузки не завершена, возвращается 202 с ее статусом (queued или running) и последней ошибкой, если попытки уже были. Если задача исчерпала попытки, возвращается 410 со статусом dead и последней ошибкой. 404 возвращается только для неизвестных идентификаторов.",
                 "produces": [
+                    "application/x-ndjson",
                     "application/json"
                 ],
                 "tags": [
-                    "Операции"
+                    "Администрирование"
                 ],
-                "summary": "Удаление записи по ID",
-                "operationId": "delete",
+                "summary": "Получение выгрузки",
+                "operationId": "exportFile",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "Идентификатор записи",
+                        "type": "integer",
+                        "description": "Идентификатор задачи выгрузки",
                         "name": "id",
                         "in": "path",
                         "required": true
@@ -108,9 +148,15 @@ const docTemplate = `{
                 ],
                 "responses": {
                     "200": {
-                        "description": "Возвращается, если удаление прошло успешно",
+                        "description": "Возвращается, если выгрузка готова",
                         "schema": {
-                            "$ref": "#/definitions/effectivemobile.DeleteByIDResponse"
+                            "type": "string"
+                        }
+                    },
+                    "202": {
+                        "description": "Возвращается, если выгрузка еще не готова",
+                        "schema": {
+                            "$ref": "#/definitions/effectivemobile.ExportStatusResponse"
                         }
                     },
                     "400": {
@@ -120,7 +166,7 @@ const docTemplate = `{
                         }
                     },
                     "404": {
-                        "description": "Возвращается, если запрашиваемая запись не была найдена",
+                        "description": "Возвращается, если задача выгрузки не найдена",
                         "schema": {
                             "$ref": "#/definitions/effectivemobile.NotFoundResponse"
                         }
@@ -131,63 +177,187 @@ const docTemplate = `{
                             "$ref": "#/definitions/effectivemobile.MethodNotAllowedResponse"
                         }
                     },
+                    "410": {
+                        "description": "Возвращается, если задача выгрузки завершилась ошибкой",
+                        "schema": {
+                            "$ref": "#/definitions/effectivemobile.ExportStatusResponse"
+                        }
+                    },
                     "500": {
-                        "description": "Возвращается, если во время работы хранилища произошла ошибка",
+                        "description": "Возвращается, если выгрузку не удалось прочитать",
                         "schema": {
                             "$ref": "#/definitions/effectivemobile.InternalServerErrorResponse"
                         }
+                    },
+                    "504": {
+                        "description": "Возвращается, если операция не уложилась в отведенное время",
+                        "schema": {
+                            "$ref": "#/definitions/effectivemobile.GatewayTimeoutResponse"
+                        }
                     }
                 }
             }
         },
-        "/select": {
-            "get": {
-                "description": "Возвращает запись/список записей с возможностью фильтрации и пагинации.",
+        "/admin/purge": {
+            "post": {
+                "description": "Окончательно удаляет записи, помеченные как удаленные раньше, чем истек срок хранения.\nПо умолчанию используется срок хранения из конфигурации (SERVER_PURGERETENTION).",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Операции"
+                    "Администрирование"
                 ],
-                "summary": "Получение записи(ей)",
-                "operationId": "select",
+                "summary": "Очистка удаленных записей",
+                "operationId": "purge",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Идентификатор записи",
-                        "name": "id",
+                        "description": "Срок хранения удаленных записей (например, 720h)",
+                        "name": "retention",
                         "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Возвращается, если очистка прошла успешно",
+                        "schema": {
+                            "$ref": "#/definitions/effectivemobile.PurgeResponse"
+                        }
                     },
-                    {
-                        "type": "string",
-                        "description": "Тип фильтра (name, surname, etc.)",
-                        "name": "filter",
-                        "in": "query"
+                    "400": {
+                        "description": "Возвращается, если запрос был сформирован неправильно",
+                        "schema": {
+                            "$ref": "#/definitions/effectivemobile.BadRequestResponse"
+                        }
+                    },
+                    "405": {
+                        "description": "Возвращается, если был использован неправильный метод",
+                        "schema": {
+                            "$ref": "#/definitions/effectivemobile.MethodNotAllowedResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Возвращается, если во время работы хранилища произошла ошибка",
+                        "schema": {
+                            "$ref": "#/definitions/effectivemobile.InternalServerErrorResponse"
+                        }
                     },
+                    "504": {
+                        "description": "Возвращается, если операция не уложилась в отведенное время",
+                        "schema": {
+                            "$ref": "#/definitions/effectivemobile.GatewayTimeoutResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/reenrich": {
+            "post": {
+                "description": "Ставит в очередь задачу повторного обогащения записей, подходящих под фильтр. Записи отбираются по национальности, дате создания (created_before) и низкой уверенности (low_confidence: запись требует проверки или детали оценки ниже текущих порогов), условия объединяются через И. Должно быть задано хотя бы одно условие. Провайдеры опрашиваются в обход кэша, изменения фиксируются в истории записей.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Администрирование"
+                ],
+                "summary": "Пакетное повторное обогащение записей",
+                "operationId": "reenrichBatch",
+                "parameters": [
                     {
-                        "type": "string",
-                        "description": "Значение фильтра",
-                        "name": "value",
-                        "in": "query"
+                        "description": "Тело запроса",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/effectivemobile.ReenrichRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Возвращается, если задача поставлена в очередь",
+                        "schema": {
+                            "$ref": "#/definitions/effectivemobile.ReenrichResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Возвращается, если запрос был сформирован неправильно",
+                        "schema": {
+                            "$ref": "#/definitions/effectivemobile.BadRequestResponse"
+                        }
                     },
+                    "405": {
+                        "description": "Возвращается, если был использован неправильный метод",
+                        "schema": {
+                            "$ref": "#/definitions/effectivemobile.MethodNotAllowedResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Возвращается, если во время работы хранилища произошла ошибка",
+                        "schema": {
+                            "$ref": "#/definitions/effectivemobile.InternalServerErrorResponse"
+                        }
+                    },
+                    "504": {
+                        "description": "Возвращается, если операция не уложилась в отведенное время",
+                        "schema": {
+                            "$ref": "#/definitions/effectivemobile.GatewayTimeoutResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/create": {
+            "post": {
+                "description": "Создает новую запись с автозаполнением возраста, пола и национальности при помощи открытых API. Необязательный country_id (ISO 3166-1 alpha-2) уточняет оценку возраста и пола для страны, использованная локализация сохраняется в поле localization. Поля, уверенность в которых ниже настроенных порогов, остаются пустыми, а запись помечается needs_review. С параметром async=true запись сохраняется сразу со статусом pending_enrichment, а обогащение выполняется в фоне и завершается статусом enriched или enrichment_failed. При политике SERVER_ENRICHMENTPOLICY=best_effort ошибка части провайдеров не прерывает создание: недостающие поля остаются пустыми, а статус каждого поля (enriched, low_confidence, failed) возвращается в enrichment_fields. Поля отключенных провайдеров остаются пустыми со статусом skipped при любой политике.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Операции"
+                ],
+                "summary": "Создание записи",
+                "operationId": "create",
+                "parameters": [
                     {
-                        "type": "integer",
-                        "description": "Начальная позиция",
-                        "name": "start",
-                        "in": "query"
+                        "description": "Тело запроса",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/effectivemobile.CreateRequest"
+                        }
                     },
                     {
-                        "type": "integer",
-                        "description": "Конечная позиция",
-                        "name": "end",
+                        "type": "boolean",
+                        "description": "Сохранить запись сразу и обогатить ее в фоне",
+                        "name": "async",
                         "in": "query"
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "Возвращается, если получение прошло успешно",
+                    "201": {
+                        "description": "Возвращается, если создание прошло успешно, заголовок Location указывает на созданную запись",
                         "schema": {
-                            "$ref": "#/definitions/effectivemobile.SelectResponse"
+                            "$ref": "#/definitions/effectivemobile.CreateResponse"
+                        },
+                        "headers": {
+                            "Location": {
+                                "type": "string",
+                                "description": "Путь до созданной записи"
+                            }
+                        }
+                    },
+                    "202": {
+                        "description": "Возвращается, если запись сохранена и поставлена в очередь обогащения",
+                        "schema": {
+                            "$ref": "#/definitions/effectivemobile.CreateResponse"
+                        },
+                        "headers": {
+                            "Location": {
+                                "type": "string",
+                                "description": "Путь до созданной записи"
+                            }
                         }
                     },
                     "400": {
@@ -197,7 +367,7 @@ const docTemplate = `{
                         }
                     },
                     "404": {
-                        "description": "Возвращается, если запрашиваемая запись(и) не была найдена",
+                        "description": "Возвращается, если запрашиваемая запись не была найдена/во внешних API нет данных",
                         "schema": {
                             "$ref": "#/definitions/effectivemobile.NotFoundResponse"
                         }
@@ -209,25 +379,43 @@ const docTemplate = `{
                         }
                     },
                     "500": {
-                        "description": "Возвращается, если во время работы хранилища произошла ошибка",
+                        "description": "Возвращается, если во время работы хранилища/клиента произошла ошибка",
                         "schema": {
                             "$ref": "#/definitions/effectivemobile.InternalServerErrorResponse"
                         }
+                    },
+                    "503": {
+                        "description": "Возвращается, если внешний API недоступен, его предохранитель разомкнут или исчерпан лимит запросов",
+                        "schema": {
+                            "$ref": "#/definitions/effectivemobile.ServiceUnavailableResponse"
+                        },
+                        "headers": {
+                            "Retry-After": {
+                                "type": "string",
+                                "description": "Через сколько секунд можно повторить запрос, если исчерпан лимит запросов"
+                            }
+                        }
+                    },
+                    "504": {
+                        "description": "Возвращается, если операция не уложилась в отведенное время",
+                        "schema": {
+                            "$ref": "#/definitions/effectivemobile.GatewayTimeoutResponse"
+                        }
                     }
                 }
             }
         },
-        "/update/{id}": {
-            "put": {
-                "description": "Обновляет существующую записи в базе данных по ID при помощи данных получаемых в теле запроса.",
+        "/delete/{id}": {
+            "delete": {
+                "description": "Помечает запись с заданным идентификатором как удаленную. Запись можно восстановить до очистки.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
                     "Операции"
                 ],
-                "summary": "Обновление записи по ID",
-                "operationId": "update",
+                "summary": "Удаление записи по ID",
+                "operationId": "delete",
                 "parameters": [
                     {
                         "type": "string",
@@ -237,20 +425,17 @@ const docTemplate = `{
                         "required": true
                     },
                     {
-                        "description": "Тело запроса",
-                        "name": "body",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/effectivemobile.UpdateByIDRequest"
-                        }
+                        "type": "string",
+                        "description": "ETag записи, полученный при чтении, список ETag через запятую или *",
+                        "name": "If-Match",
+                        "in": "header"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Возвращается, если обновление прошло успешно",
+                        "description": "Возвращается, если удаление прошло успешно",
                         "schema": {
-                            "$ref": "#/definitions/effectivemobile.UpdateByIDResponse"
+                            "$ref": "#/definitions/effectivemobile.DeleteByIDResponse"
                         }
                     },
                     "400": {
@@ -271,10 +456,10 @@ const docTemplate = `{
                             "$ref": "#/definitions/effectivemobile.MethodNotAllowedResponse"
                         }
                     },
-                    "409": {
-                        "description": "Возвращается, если переданные данные ничем не отличаются от уже существующих",
+                    "412": {
+                        "description": "Возвращается, если версия записи не совпадает ни с одним ETag из If-Match",
                         "schema": {
-                            "$ref": "#/definitions/effectivemobile.ConflictResponse"
+                            "$ref": "#/definitions/effectivemobile.PreconditionFailedResponse"
                         }
                     },
                     "500": {
@@ -282,45 +467,1284 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/effectivemobile.InternalServerErrorResponse"
                         }
+                    },
+                    "504": {
+                        "description": "Возвращается, если операция не уложилась в отведенное время",
+                        "schema": {
+                            "$ref": "#/definitions/effectivemobile.GatewayTimeoutResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/health": {
+            "get": {
+                "description": "Возвращает состояние сервиса и предохранителей внешних API.\nСтатус degraded означает, что хотя бы один предохранитель не замкнут и обогащение может быть недоступно.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Администрирование"
+                ],
+                "summary": "Состояние сервиса",
+                "operationId": "health",
+                "responses": {
+                    "200": {
+                        "description": "Возвращается, если сервис работает",
+                        "schema": {
+                            "$ref": "#/definitions/effectivemobile.HealthResponse"
+                        }
+                    },
+                    "405": {
+                        "description": "Возвращается, если был использован неправильный метод",
+                        "schema": {
+                            "$ref": "#/definitions/effectivemobile.MethodNotAllowedResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/metrics": {
+            "get": {
+                "description": "Возвращает метрики клиентов обогащения: попадания и промахи кэша и состояние предохранителей по каждому провайдеру.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Администрирование"
+                ],
+                "summary": "Метрики сервиса",
+                "operationId": "metrics",
+                "responses": {
+                    "200": {
+                        "description": "Возвращается, если метрики собраны успешно",
+                        "schema": {
+                            "$ref": "#/definitions/effectivemobile.MetricsResponse"
+                        }
+                    },
+                    "405": {
+                        "description": "Возвращается, если был использован неправильный метод",
+                        "schema": {
+                            "$ref": "#/definitions/effectivemobile.MethodNotAllowedResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/people/batch": {
+            "post": {
+                "description": "Создает несколько записей за один запрос, обогащая их пакетными запросами к открытым API. Записи, для которых во внешних API нет данных, пропускаются, их индексы возвращаются в поле skipped. При политике SERVER_ENRICHMENTPOLICY=best_effort пропускаются только записи, для которых нет данных ни от одного включенного провайдера, остальные сохраняются, а недостающие поля получают статус failed.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Операции"
+                ],
+                "summary": "Пакетное создание записей",
+                "operationId": "createBatch",
+                "parameters": [
+                    {
+                        "description": "Тело запроса",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/effectivemobile.CreateBatchRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Возвращается, если создание прошло успешно",
+                        "schema": {
+                            "$ref": "#/definitions/effectivemobile.CreateBatchResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Возвращается, если запрос был сформирован неправильно или записей больше допустимого",
+                        "schema": {
+                            "$ref": "#/definitions/effectivemobile.BadRequestResponse"
+                        }
+                    },
+                    "405": {
+                        "description": "Возвращается, если был использован неправильный метод",
+                        "schema": {
+                            "$ref": "#/definitions/effectivemobile.MethodNotAllowedResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Возвращается, если во время работы хранилища/клиента произошла ошибка",
+                        "schema": {
+                            "$ref": "#/definitions/effectivemobile.InternalServerErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Возвращается, если внешний API недоступен, его предохранитель разомкнут или исчерпан лимит запросов",
+                        "schema": {
+                            "$ref": "#/definitions/effectivemobile.ServiceUnavailableResponse"
+                        },
+                        "headers": {
+                            "Retry-After": {
+                                "type": "string",
+                                "description": "Через сколько секунд можно повторить запрос, если исчерпан лимит запросов"
+                            }
+                        }
+                    },
+                    "504": {
+                        "description": "Возвращается, если операция не уложилась в отведенное время",
+                        "schema": {
+                            "$ref": "#/definitions/effectivemobile.GatewayTimeoutResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/people/{id}": {
+            "patch": {
+                "description": "Частично обновляет запись по ID согласно RFC 7396 (JSON Merge Patch).\nПереданные поля заменяют текущие значения, null очищает поле, если оно допускает пустое значение (patronymic, age, gender, nationality).\nНеизвестные поля отклоняются.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Операции"
+                ],
+                "summary": "Частичное обновление записи по ID",
+                "operationId": "patch",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Идентификатор записи",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag записи, полученный при чтении, список ETag через запятую или *",
+                        "name": "If-Match",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Тело запроса (application/merge-patch+json)",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/effectivemobile.PatchByIDRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Возвращается, если обновление прошло успешно",
+                        "schema": {
+                            "$ref": "#/definitions/effectivemobile.PatchByIDResponse"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Новая версия записи"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Возвращается, если запрос был сформирован неправильно или содержит неизвестные поля",
+                        "schema": {
+                            "$ref": "#/definitions/effectivemobile.BadRequestResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Возвращается, если запрашиваемая запись не была найдена",
+                        "schema": {
+                            "$ref": "#/definitions/effectivemobile.NotFoundResponse"
+                        }
+                    },
+                    "405": {
+                        "description": "Возвращается, если был использован неправильный метод",
+                        "schema": {
+                            "$ref": "#/definitions/effectivemobile.MethodNotAllowedResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Возвращается, если переданные данные нарушают ограничения таблицы",
+                        "schema": {
+                            "$ref": "#/definitions/effectivemobile.ConflictResponse"
+                        }
+                    },
+                    "412": {
+                        "description": "Возвращается, если версия записи не совпадает ни с одним ETag из If-Match",
+                        "schema": {
+                            "$ref": "#/definitions/effectivemobile.PreconditionFailedResponse"
+                        }
+                    },
+                    "415": {
+                        "description": "Возвращается, если тело запроса не является JSON",
+                        "schema": {
+                            "$ref": "#/definitions/effectivemobile.UnsupportedMediaTypeResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Возвращается, если во время работы хранилища произошла ошибка",
+                        "schema": {
+                            "$ref": "#/definitions/effectivemobile.InternalServerErrorResponse"
+                        }
+                    },
+                    "504": {
+                        "description": "Возвращается, если операция не уложилась в отведенное время",
+                        "schema": {
+                            "$ref": "#/definitions/effectivemobile.GatewayTimeoutResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/people/{id}/enrich": {
+            "post": {
+                "description": "Повторно запрашивает возраст, пол и национальность у провайдеров в обход кэша и обновляет запись. Сохраненная локализация используется как country_id. Поля, которые провайдер не вернул или чей провайдер отключен, сохраняют прежние значения. Поля, уверенность в которых ниже текущих порогов, очищаются и получают статус low_confidence, а запись помечается needs_review. Изменения фиксируются в истории записи операцией enrich, если значения не изменились, запись остается прежней.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Операции"
+                ],
+                "summary": "Повторное обогащение записи по ID",
+                "operationId": "reenrich",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Идентификатор записи",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Возвращается, если повторное обогащение прошло успешно",
+                        "schema": {
+                            "$ref": "#/definitions/effectivemobile.ReenrichByIDResponse"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Версия записи"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Возвращается, если запрос был сформирован неправильно",
+                        "schema": {
+                            "$ref": "#/definitions/effectivemobile.BadRequestResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Возвращается, если запрашиваемая запись не была найдена/во внешних API нет данных",
+                        "schema": {
+                            "$ref": "#/definitions/effectivemobile.NotFoundResponse"
+                        }
+                    },
+                    "405": {
+                        "description": "Возвращается, если был использован неправильный метод",
+                        "schema": {
+                            "$ref": "#/definitions/effectivemobile.MethodNotAllowedResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Возвращается, если во время работы хранилища/клиента произошла ошибка",
+                        "schema": {
+                            "$ref": "#/definitions/effectivemobile.InternalServerErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Возвращается, если внешний API недоступен, его предохранитель разомкнут или исчерпан лимит запросов",
+                        "schema": {
+                            "$ref": "#/definitions/effectivemobile.ServiceUnavailableResponse"
+                        },
+                        "headers": {
+                            "Retry-After": {
+                                "type": "string",
+                                "description": "Через сколько секунд можно повторить запрос, если исчерпан лимит запросов"
+                            }
+                        }
+                    },
+                    "504": {
+                        "description": "Возвращается, если операция не уложилась в отведенное время",
+                        "schema": {
+                            "$ref": "#/definitions/effectivemobile.GatewayTimeoutResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/people/{id}/history": {
+            "get": {
+                "description": "Возвращает историю изменений записи: операцию, автора (заголовок X-Actor), время и состояние записи до и после изменения.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "История"
+                ],
+                "summary": "История изменений записи по ID",
+                "operationId": "history",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Идентификатор записи",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Возвращается, если получение прошло успешно",
+                        "schema": {
+                            "$ref": "#/definitions/effectivemobile.HistoryByIDResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Возвращается, если запрос был сформирован неправильно",
+                        "schema": {
+                            "$ref": "#/definitions/effectivemobile.BadRequestResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Возвращается, если запрашиваемая запись не была найдена",
+                        "schema": {
+                            "$ref": "#/definitions/effectivemobile.NotFoundResponse"
+                        }
+                    },
+                    "405": {
+                        "description": "Возвращается, если был использован неправильный метод",
+                        "schema": {
+                            "$ref": "#/definitions/effectivemobile.MethodNotAllowedResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Возвращается, если во время работы хранилища произошла ошибка",
+                        "schema": {
+                            "$ref": "#/definitions/effectivemobile.InternalServerErrorResponse"
+                        }
+                    },
+                    "504": {
+                        "description": "Возвращается, если операция не уложилась в отведенное время",
+                        "schema": {
+                            "$ref": "#/definitions/effectivemobile.GatewayTimeoutResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/people/{id}/restore": {
+            "post": {
+                "description": "Восстанавливает ранее удаленную запись по заданному идентификатору.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Операции"
+                ],
+                "summary": "Восстановление записи по ID",
+                "operationId": "restore",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Идентификатор записи",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag записи, полученный при чтении, список ETag через запятую или *",
+                        "name": "If-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Возвращается, если восстановление прошло успешно",
+                        "schema": {
+                            "$ref": "#/definitions/effectivemobile.RestoreByIDResponse"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Новая версия записи"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Возвращается, если запрос был сформирован неправильно",
+                        "schema": {
+                            "$ref": "#/definitions/effectivemobile.BadRequestResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Возвращается, если запрашиваемая запись не была найдена",
+                        "schema": {
+                            "$ref": "#/definitions/effectivemobile.NotFoundResponse"
+                        }
+                    },
+                    "405": {
+                        "description": "Возвращается, если был использован неправильный метод",
+                        "schema": {
+                            "$ref": "#/definitions/effectivemobile.MethodNotAllowedResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Возвращается, если запись не была удалена",
+                        "schema": {
+                            "$ref": "#/definitions/effectivemobile.ConflictResponse"
+                        }
+                    },
+                    "412": {
+                        "description": "Возвращается, если версия записи не совпадает ни с одним ETag из If-Match",
+                        "schema": {
+                            "$ref": "#/definitions/effectivemobile.PreconditionFailedResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Возвращается, если во время работы хранилища произошла ошибка",
+                        "schema": {
+                            "$ref": "#/definitions/effectivemobile.InternalServerErrorResponse"
+                        }
+                    },
+                    "504": {
+                        "description": "Возвращается, если операция не уложилась в отведенное время",
+                        "schema": {
+                            "$ref": "#/definitions/effectivemobile.GatewayTimeoutResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/people/{id}/snapshot": {
+            "get": {
+                "description": "Возвращает состояние записи на заданный момент времени, восстановленное по истории изменений.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "История"
+                ],
+                "summary": "Состояние записи на момент времени",
+                "operationId": "snapshot",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Идентификатор записи",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Момент времени в формате RFC 3339",
+                        "name": "as_of",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Возвращается, если получение прошло успешно",
+                        "schema": {
+                            "$ref": "#/definitions/effectivemobile.SnapshotByIDResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Возвращается, если запрос был сформирован неправильно",
+                        "schema": {
+                            "$ref": "#/definitions/effectivemobile.BadRequestResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Возвращается, если на заданный момент записи не существовало",
+                        "schema": {
+                            "$ref": "#/definitions/effectivemobile.NotFoundResponse"
+                        }
+                    },
+                    "405": {
+                        "description": "Возвращается, если был использован неправильный метод",
+                        "schema": {
+                            "$ref": "#/definitions/effectivemobile.MethodNotAllowedResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Возвращается, если во время работы хранилища произошла ошибка",
+                        "schema": {
+                            "$ref": "#/definitions/effectivemobile.InternalServerErrorResponse"
+                        }
+                    },
+                    "504": {
+                        "description": "Возвращается, если операция не уложилась в отведенное время",
+                        "schema": {
+                            "$ref": "#/definitions/effectivemobile.GatewayTimeoutResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/select": {
+            "get": {
+                "description": "Возвращает запись/список записей с возможностью фильтрации и пагинации.\nФильтры можно комбинировать: значения через запятую образуют список (nationality=RU,UA,KZ),\nсуффиксы _gt, _gte, _lt, _lte задают диапазон возраста, суффикс _prefix ищет по началу имени, фамилии или отчества.\nЕсли после страницы остались записи, в ответе возвращается next_cursor: передав его в параметре cursor\nвместо start/end, можно получить следующую страницу без OFFSET. Курсор действителен только для той же сортировки.\nСсылки на соседние страницы возвращаются в заголовке Link (rel=\"next\", rel=\"prev\").",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Операции"
+                ],
+                "summary": "Получение записи(ей)",
+                "operationId": "select",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Идентификатор записи",
+                        "name": "id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Тип фильтра (name, surname, etc.), устаревший вариант",
+                        "name": "filter",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Значение фильтра, устаревший вариант",
+                        "name": "value",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Имя или список имен через запятую",
+                        "name": "name",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Фамилия или список фамилий через запятую",
+                        "name": "surname",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Отчество или список отчеств через запятую",
+                        "name": "patronymic",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Возраст или список возрастов через запятую",
+                        "name": "age",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Пол или список полов через запятую",
+                        "name": "gender",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Национальность или список национальностей через запятую",
+                        "name": "nationality",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Начало имени",
+                        "name": "name_prefix",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Начало фамилии",
+                        "name": "surname_prefix",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Начало отчества",
+                        "name": "patronymic_prefix",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Возраст больше",
+                        "name": "age_gt",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Возраст больше или равен",
+                        "name": "age_gte",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Возраст меньше",
+                        "name": "age_lt",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Возраст меньше или равен",
+                        "name": "age_lte",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Размер выборки для оценки возраста больше",
+                        "name": "age_count_gt",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Размер выборки для оценки возраста больше или равен",
+                        "name": "age_count_gte",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Размер выборки для оценки возраста меньше",
+                        "name": "age_count_lt",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Размер выборки для оценки возраста меньше или равен",
+                        "name": "age_count_lte",
+                        "in": "query"
+                    },
+                    {
+                        "type": "number",
+                        "description": "Вероятность пола больше",
+                        "name": "gender_probability_gt",
+                        "in": "query"
+                    },
+                    {
+                        "type": "number",
+                        "description": "Вероятность пола больше или равна",
+                        "name": "gender_probability_gte",
+                        "in": "query"
+                    },
+                    {
+                        "type": "number",
+                        "description": "Вероятность пола меньше",
+                        "name": "gender_probability_lt",
+                        "in": "query"
+                    },
+                    {
+                        "type": "number",
+                        "description": "Вероятность пола меньше или равна",
+                        "name": "gender_probability_lte",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Размер выборки для оценки пола больше",
+                        "name": "gender_count_gt",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Размер выборки для оценки пола больше или равен",
+                        "name": "gender_count_gte",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Размер выборки для оценки пола меньше",
+                        "name": "gender_count_lt",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Размер выборки для оценки пола меньше или равен",
+                        "name": "gender_count_lte",
+                        "in": "query"
+                    },
+                    {
+                        "type": "number",
+                        "description": "Вероятность национальности больше",
+                        "name": "nationality_probability_gt",
+                        "in": "query"
+                    },
+                    {
+                        "type": "number",
+                        "description": "Вероятность национальности больше или равна",
+                        "name": "nationality_probability_gte",
+                        "in": "query"
+                    },
+                    {
+                        "type": "number",
+                        "description": "Вероятность национальности меньше",
+                        "name": "nationality_probability_lt",
+                        "in": "query"
+                    },
+                    {
+                        "type": "number",
+                        "description": "Вероятность национальности меньше или равна",
+                        "name": "nationality_probability_lte",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Размер выборки для оценки национальности больше",
+                        "name": "nationality_count_gt",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Размер выборки для оценки национальности больше или равен",
+                        "name": "nationality_count_gte",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Размер выборки для оценки национальности меньше",
+                        "name": "nationality_count_lt",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Размер выборки для оценки национальности меньше или равен",
+                        "name": "nationality_count_lte",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Записи, у которых часть полей не заполнена из-за низкой уверенности",
+                        "name": "needs_review",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Статус обогащения или список статусов через запятую (pending_enrichment, enriched, enrichment_failed)",
+                        "name": "enrichment_status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Ключи сортировки через запятую, минус перед ключом задает обратный порядок (-age,surname,name)",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Начальная позиция",
+                        "name": "start",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Конечная позиция",
+                        "name": "end",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Курсор следующей страницы из next_cursor",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Размер страницы при использовании курсора",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Посчитать общее количество подходящих записей",
+                        "name": "with_total",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Включить удаленные записи",
+                        "name": "include_deleted",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Возвращается, если получение прошло успешно",
+                        "schema": {
+                            "$ref": "#/definitions/effectivemobile.SelectResponse"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Версия записи, если она запрошена по идентификатору"
+                            },
+                            "Link": {
+                                "type": "string",
+                                "description": "Ссылки на следующую и предыдущую страницы"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Возвращается, если запрос был сформирован неправильно",
+                        "schema": {
+                            "$ref": "#/definitions/effectivemobile.BadRequestResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Возвращается, если запись с указанным идентификатором не была найдена, пустой список возвращается с кодом 200",
+                        "schema": {
+                            "$ref": "#/definitions/effectivemobile.NotFoundResponse"
+                        }
+                    },
+                    "405": {
+                        "description": "Возвращается, если был использован неправильный метод",
+                        "schema": {
+                            "$ref": "#/definitions/effectivemobile.MethodNotAllowedResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Возвращается, если во время работы хранилища произошла ошибка",
+                        "schema": {
+                            "$ref": "#/definitions/effectivemobile.InternalServerErrorResponse"
+                        }
+                    },
+                    "504": {
+                        "description": "Возвращается, если операция не уложилась в отведенное время",
+                        "schema": {
+                            "$ref": "#/definitions/effectivemobile.GatewayTimeoutResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/update/{id}": {
+            "put": {
+                "description": "Обновляет существующую записи в базе данных по ID при помощи данных получаемых в теле запроса.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Операции"
+                ],
+                "summary": "Обновление записи по ID",
+                "operationId": "update",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Идентификатор записи",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag записи, полученный при чтении, список ETag через запятую или *",
+                        "name": "If-Match",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Тело запроса",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/effectivemobile.UpdateByIDRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Возвращается, если обновление прошло успешно",
+                        "schema": {
+                            "$ref": "#/definitions/effectivemobile.UpdateByIDResponse"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Новая версия записи"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Возвращается, если запрос был сформирован неправильно",
+                        "schema": {
+                            "$ref": "#/definitions/effectivemobile.BadRequestResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Возвращается, если запрашиваемая запись не была найдена",
+                        "schema": {
+                            "$ref": "#/definitions/effectivemobile.NotFoundResponse"
+                        }
+                    },
+                    "405": {
+                        "description": "Возвращается, если был использован неправильный метод",
+                        "schema": {
+                            "$ref": "#/definitions/effectivemobile.MethodNotAllowedResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Возвращается, если переданные данные ничем не отличаются от уже существующих",
+                        "schema": {
+                            "$ref": "#/definitions/effectivemobile.ConflictResponse"
+                        }
+                    },
+                    "412": {
+                        "description": "Возвращается, если версия записи не совпадает ни с одним ETag из If-Match",
+                        "schema": {
+                            "$ref": "#/definitions/effectivemobile.PreconditionFailedResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Возвращается, если во время работы хранилища произошла ошибка",
+                        "schema": {
+                            "$ref": "#/definitions/effectivemobile.InternalServerErrorResponse"
+                        }
+                    },
+                    "504": {
+                        "description": "Возвращается, если операция не уложилась в отведенное время",
+                        "schema": {
+                            "$ref": "#/definitions/effectivemobile.GatewayTimeoutResponse"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "cache.Stats": {
+            "type": "object",
+            "properties": {
+                "hits": {
+                    "type": "integer",
+                    "example": 10
+                },
+                "misses": {
+                    "type": "integer",
+                    "example": 2
+                }
+            }
+        },
+        "client.Stats": {
+            "type": "object",
+            "properties": {
+                "breakers": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "cache": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "$ref": "#/definitions/cache.Stats"
+                    }
+                },
+                "quota": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "$ref": "#/definitions/quota.Quota"
+                    }
+                }
+            }
+        },
+        "effectivemobile.BadRequestResponse": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "integer",
+                    "example": 400
+                },
+                "message": {
+                    "type": "string",
+                    "example": "Bad Request"
+                }
+            }
+        },
+        "effectivemobile.ConflictResponse": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "integer",
+                    "example": 409
+                },
+                "message": {
+                    "type": "string",
+                    "example": "Conflict"
+                }
+            }
+        },
+        "effectivemobile.CreateBatchRequest": {
+            "type": "object",
+            "properties": {
+                "people": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/postgresql.Person"
+                    }
+                }
+            }
+        },
+        "effectivemobile.CreateBatchResponse": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "integer",
+                    "example": 201
+                },
+                "message": {
+                    "type": "string",
+                    "example": "entities have been created"
+                },
+                "result": {
+                    "$ref": "#/definitions/effectivemobile.CreateBatchResult"
+                }
+            }
+        },
+        "effectivemobile.CreateBatchResult": {
+            "type": "object",
+            "properties": {
+                "created": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/postgresql.Row"
+                    }
+                },
+                "skipped": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                }
+            }
+        },
+        "effectivemobile.CreateRequest": {
+            "type": "object",
+            "properties": {
+                "country_id": {
+                    "type": "string",
+                    "example": "RU"
+                },
+                "name": {
+                    "type": "string",
+                    "example": "Ivan"
+                },
+                "patronymic": {
+                    "type": "string",
+                    "example": "Ivanovich"
+                },
+                "surname": {
+                    "type": "string",
+                    "example": "Petrov"
+                }
+            }
+        },
+        "effectivemobile.CreateResponse": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "integer",
+                    "example": 201
+                },
+                "message": {
+                    "type": "string",
+                    "example": "entity has been created"
+                },
+                "result": {
+                    "$ref": "#/definitions/postgresql.Row"
+                }
+            }
+        },
+        "effectivemobile.DeleteByIDResponse": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "integer",
+                    "example": 200
+                },
+                "message": {
+                    "type": "string",
+                    "example": "entity has been deleted"
+                }
+            }
+        },
+        "effectivemobile.ExportResponse": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "integer",
+                    "example": 202
+                },
+                "job_id": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "message": {
+                    "type": "string",
+                    "example": "export has been scheduled"
+                }
+            }
+        },
+        "effectivemobile.ExportStatusResponse": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "integer",
+                    "example": 202
+                },
+                "job_id": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "last_error": {
+                    "type": "string",
+                    "example": "время ожидания истекло"
+                },
+                "message": {
+                    "type": "string",
+                    "example": "export is not ready yet"
+                },
+                "status": {
+                    "type": "string",
+                    "example": "queued"
+                }
+            }
+        },
+        "effectivemobile.GatewayTimeoutResponse": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "integer",
+                    "example": 504
+                },
+                "message": {
+                    "type": "string",
+                    "example": "Gateway Timeout"
+                }
+            }
+        },
+        "effectivemobile.HealthResponse": {
+            "type": "object",
+            "properties": {
+                "breakers": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
                     }
+                },
+                "code": {
+                    "type": "integer",
+                    "example": 200
+                },
+                "message": {
+                    "type": "string",
+                    "example": "service is running"
+                },
+                "status": {
+                    "type": "string",
+                    "example": "ok"
                 }
             }
-        }
-    },
-    "definitions": {
-        "effectivemobile.BadRequestResponse": {
+        },
+        "effectivemobile.HistoryByIDResponse": {
             "type": "object",
             "properties": {
                 "code": {
                     "type": "integer",
-                    "example": 400
+                    "example": 200
                 },
                 "message": {
                     "type": "string",
-                    "example": "Bad Request"
+                    "example": "history found"
+                },
+                "result": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/postgresql.HistoryEntry"
+                    }
                 }
             }
         },
-        "effectivemobile.ConflictResponse": {
+        "effectivemobile.InternalServerErrorResponse": {
             "type": "object",
             "properties": {
                 "code": {
                     "type": "integer",
-                    "example": 409
+                    "example": 500
                 },
                 "message": {
                     "type": "string",
-                    "example": "Conflict"
+                    "example": "Internal Server Error"
                 }
             }
         },
-        "effectivemobile.CreateRequest": {
+        "effectivemobile.MethodNotAllowedResponse": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "integer",
+                    "example": 405
+                },
+                "message": {
+                    "type": "string",
+                    "example": "Method Not Allowed"
+                }
+            }
+        },
+        "effectivemobile.MetricsResponse": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "integer",
+                    "example": 200
+                },
+                "message": {
+                    "type": "string",
+                    "example": "metrics collected"
+                },
+                "result": {
+                    "$ref": "#/definitions/client.Stats"
+                }
+            }
+        },
+        "effectivemobile.NotFoundResponse": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "integer",
+                    "example": 404
+                },
+                "message": {
+                    "type": "string",
+                    "example": "Not Found"
+                }
+            }
+        },
+        "effectivemobile.PatchByIDRequest": {
             "type": "object",
             "properties": {
+                "age": {
+                    "type": "integer",
+                    "example": 21
+                },
+                "gender": {
+                    "type": "string",
+                    "example": "male"
+                },
                 "name": {
                     "type": "string",
                     "example": "Ivan"
                 },
+                "nationality": {
+                    "type": "string",
+                    "example": "RU"
+                },
                 "patronymic": {
                     "type": "string",
                     "example": "Ivanovich"
@@ -331,7 +1755,7 @@ const docTemplate = `{
                 }
             }
         },
-        "effectivemobile.CreateResponse": {
+        "effectivemobile.PatchByIDResponse": {
             "type": "object",
             "properties": {
                 "code": {
@@ -340,11 +1764,27 @@ const docTemplate = `{
                 },
                 "message": {
                     "type": "string",
-                    "example": "entity has been created"
+                    "example": "entity has been patched"
+                },
+                "result": {
+                    "$ref": "#/definitions/postgresql.Row"
                 }
             }
         },
-        "effectivemobile.DeleteByIDResponse": {
+        "effectivemobile.PreconditionFailedResponse": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "integer",
+                    "example": 412
+                },
+                "message": {
+                    "type": "string",
+                    "example": "Precondition Failed"
+                }
+            }
+        },
+        "effectivemobile.PurgeResponse": {
             "type": "object",
             "properties": {
                 "code": {
@@ -353,46 +1793,83 @@ const docTemplate = `{
                 },
                 "message": {
                     "type": "string",
-                    "example": "entity has been deleted"
+                    "example": "deleted entities have been purged"
+                },
+                "purged": {
+                    "type": "integer",
+                    "example": 10
                 }
             }
         },
-        "effectivemobile.InternalServerErrorResponse": {
+        "effectivemobile.ReenrichByIDResponse": {
             "type": "object",
             "properties": {
                 "code": {
                     "type": "integer",
-                    "example": 500
+                    "example": 200
                 },
                 "message": {
                     "type": "string",
-                    "example": "Internal Server Error"
+                    "example": "entity has been re-enriched"
+                },
+                "result": {
+                    "$ref": "#/definitions/postgresql.Row"
                 }
             }
         },
-        "effectivemobile.MethodNotAllowedResponse": {
+        "effectivemobile.ReenrichRequest": {
+            "type": "object",
+            "properties": {
+                "created_before": {
+                    "type": "string",
+                    "example": "2024-01-01T00:00:00Z"
+                },
+                "low_confidence": {
+                    "type": "boolean",
+                    "example": true
+                },
+                "nationality": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "RU",
+                        "KZ"
+                    ]
+                }
+            }
+        },
+        "effectivemobile.ReenrichResponse": {
             "type": "object",
             "properties": {
                 "code": {
                     "type": "integer",
-                    "example": 405
+                    "example": 202
+                },
+                "job_id": {
+                    "type": "integer",
+                    "example": 1
                 },
                 "message": {
                     "type": "string",
-                    "example": "Method Not Allowed"
+                    "example": "re-enrichment has been scheduled"
                 }
             }
         },
-        "effectivemobile.NotFoundResponse": {
+        "effectivemobile.RestoreByIDResponse": {
             "type": "object",
             "properties": {
                 "code": {
                     "type": "integer",
-                    "example": 404
+                    "example": 200
                 },
                 "message": {
                     "type": "string",
-                    "example": "Not Found"
+                    "example": "entity has been restored"
+                },
+                "result": {
+                    "$ref": "#/definitions/postgresql.Row"
                 }
             }
         },
@@ -403,15 +1880,77 @@ const docTemplate = `{
                     "type": "integer",
                     "example": 200
                 },
+                "end": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "has_more": {
+                    "type": "boolean",
+                    "example": true
+                },
                 "message": {
                     "type": "string",
                     "example": "entity(ies) found"
                 },
+                "next_cursor": {
+                    "type": "string",
+                    "example": "eyJzIjoiaWQiLCJ2IjpbMTAwMF19"
+                },
                 "result": {
                     "type": "array",
                     "items": {
                         "$ref": "#/definitions/postgresql.Row"
                     }
+                },
+                "start": {
+                    "type": "integer",
+                    "example": 0
+                },
+                "total": {
+                    "type": "integer",
+                    "example": 42
+                }
+            }
+        },
+        "effectivemobile.ServiceUnavailableResponse": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "integer",
+                    "example": 503
+                },
+                "message": {
+                    "type": "string",
+                    "example": "Service Unavailable"
+                }
+            }
+        },
+        "effectivemobile.SnapshotByIDResponse": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "integer",
+                    "example": 200
+                },
+                "message": {
+                    "type": "string",
+                    "example": "snapshot found"
+                },
+                "result": {
+                    "$ref": "#/definitions/postgresql.Row"
+                }
+            }
+        },
+        "effectivemobile.UnsupportedMediaTypeResponse": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "integer",
+                    "example": 415
+                },
+                "message": {
+                    "type": "string",
+                    "example": "Unsupported Media Type"
                 }
             }
         },
@@ -457,6 +1996,71 @@ const docTemplate = `{
                 }
             }
         },
+        "postgresql.Candidate": {
+            "type": "object",
+            "properties": {
+                "country_id": {
+                    "type": "string",
+                    "example": "RU"
+                },
+                "probability": {
+                    "type": "number",
+                    "example": 0.65
+                }
+            }
+        },
+        "postgresql.HistoryEntry": {
+            "type": "object",
+            "properties": {
+                "actor": {
+                    "type": "string",
+                    "example": "operator"
+                },
+                "after": {
+                    "$ref": "#/definitions/postgresql.Row"
+                },
+                "before": {
+                    "$ref": "#/definitions/postgresql.Row"
+                },
+                "changed_at": {
+                    "type": "string",
+                    "example": "2024-01-01T00:00:00Z"
+                },
+                "id": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "operation": {
+                    "type": "string",
+                    "example": "update"
+                },
+                "person_id": {
+                    "type": "integer",
+                    "example": 1
+                }
+            }
+        },
+        "postgresql.Person": {
+            "type": "object",
+            "properties": {
+                "country_id": {
+                    "type": "string",
+                    "example": "RU"
+                },
+                "name": {
+                    "type": "string",
+                    "example": "Ivan"
+                },
+                "patronymic": {
+                    "type": "string",
+                    "example": "Ivanovich"
+                },
+                "surname": {
+                    "type": "string",
+                    "example": "Petrov"
+                }
+            }
+        },
         "postgresql.Row": {
             "type": "object",
             "properties": {
@@ -464,14 +2068,49 @@ const docTemplate = `{
                     "type": "integer",
                     "example": 21
                 },
+                "age_count": {
+                    "type": "integer",
+                    "example": 1200
+                },
+                "deleted_at": {
+                    "type": "string",
+                    "example": "2024-01-01T00:00:00Z"
+                },
+                "enrichment_fields": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    },
+                    "example": {
+                        "age": "enriched",
+                        "gender": "failed",
+                        "nationality": "low_confidence"
+                    }
+                },
+                "enrichment_status": {
+                    "type": "string",
+                    "example": "enriched"
+                },
                 "gender": {
                     "type": "string",
                     "example": "male"
                 },
+                "gender_count": {
+                    "type": "integer",
+                    "example": 1200
+                },
+                "gender_probability": {
+                    "type": "number",
+                    "example": 0.98
+                },
                 "id": {
                     "type": "integer",
                     "example": 1
                 },
+                "localization": {
+                    "type": "string",
+                    "example": "RU"
+                },
                 "name": {
                     "type": "string",
                     "example": "Ivan"
@@ -480,6 +2119,24 @@ const docTemplate = `{
                     "type": "string",
                     "example": "RU"
                 },
+                "nationality_candidates": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/postgresql.Candidate"
+                    }
+                },
+                "nationality_count": {
+                    "type": "integer",
+                    "example": 1200
+                },
+                "nationality_probability": {
+                    "type": "number",
+                    "example": 0.65
+                },
+                "needs_review": {
+                    "type": "boolean",
+                    "example": false
+                },
                 "patronymic": {
                     "type": "string",
                     "example": "Ivanovich"
@@ -487,6 +2144,24 @@ const docTemplate = `{
                 "surname": {
                     "type": "string",
                     "example": "Petrov"
+                },
+                "version": {
+                    "type": "integer",
+                    "example": 1
+                }
+            }
+        },
+        "quota.Quota": {
+            "type": "object",
+            "properties": {
+                "limit": {
+                    "type": "integer"
+                },
+                "remaining": {
+                    "type": "integer"
+                },
+                "reset_at": {
+                    "type": "string"
                 }
             }
         }
@@ -1,6 +1,7 @@
 package app
 
 import (
+	"context"
 	"log/slog"
 	"os"
 	"os/signal"
@@ -8,6 +9,7 @@ import (
 
 	effectivemobileapp "github.com/xoticdsign/effectivemobile/internal/app/effectivemobile"
 	"github.com/xoticdsign/effectivemobile/internal/lib/logger"
+	"github.com/xoticdsign/effectivemobile/internal/lib/queue"
 	storage "github.com/xoticdsign/effectivemobile/internal/storage/postgresql"
 	"github.com/xoticdsign/effectivemobile/internal/utils/config"
 )
@@ -17,6 +19,7 @@ const source = "app"
 type App struct {
 	EffectiveMobile *effectivemobileapp.App
 	Storage         *storage.Storage
+	Queue           *queue.Queue
 
 	log    *logger.Logger
 	config config.Config
@@ -61,11 +64,14 @@ func New() (*App, error) {
 		slog.Any("config", config.EffectiveMobile),
 	)
 
-	emapp := effectivemobileapp.New(config.EffectiveMobile, storage, log.Log)
+	q := queue.New(storage.DB.Implementation, config.Storage.PostgreSQL, config.Queue, log.Log)
+
+	emapp := effectivemobileapp.New(config.EffectiveMobile, storage, q, log.Log)
 
 	return &App{
 		EffectiveMobile: emapp,
 		Storage:         storage,
+		Queue:           q,
 
 		log:    log,
 		config: config,
@@ -80,6 +86,14 @@ func (a *App) Run() {
 
 	errChan := make(chan error, 1)
 
+	a.log.Log.Debug(
+		"запуск очереди задач",
+		slog.String("source", source),
+		slog.String("op", op),
+	)
+
+	a.Queue.Start()
+
 	a.log.Log.Debug(
 		"запуск сервера",
 		slog.String("source", source),
@@ -146,15 +160,15 @@ func (a *App) shutdown() []error {
 	var errs []error
 
 	a.log.Log.Debug(
-		"gracefull shutdown для хранилища",
+		"gracefull shutdown для effectivemobile",
 		slog.String("source", source),
 		slog.String("op", op),
 	)
 
-	err := a.Storage.Shutdown()
+	err := a.EffectiveMobile.Shutdown()
 	if err != nil {
 		a.log.Log.Error(
-			"хранилищу не удалось выполнить graceful shutdown, принудительная отсановка",
+			"effectivemobile не удалось выполнить graceful shutdown, принудительная отсановка",
 			slog.String("source", source),
 			slog.String("op", op),
 		)
@@ -163,15 +177,18 @@ func (a *App) shutdown() []error {
 	}
 
 	a.log.Log.Debug(
-		"shutdown для логов",
+		"gracefull shutdown для очереди задач",
 		slog.String("source", source),
 		slog.String("op", op),
 	)
 
-	err = a.log.Shutdown()
+	ctx, cancel := context.WithTimeout(context.Background(), a.config.Queue.DrainTimeout)
+	defer cancel()
+
+	err = a.Queue.Shutdown(ctx)
 	if err != nil {
 		a.log.Log.Error(
-			"логам не удалось выполнить shutdown, принудительная отсановка",
+			"очереди задач не удалось дождаться выполняемых задач, они возвращены в очередь",
 			slog.String("source", source),
 			slog.String("op", op),
 		)
@@ -180,15 +197,32 @@ func (a *App) shutdown() []error {
 	}
 
 	a.log.Log.Debug(
-		"gracefull shutdown для effectivemobile",
+		"gracefull shutdown для хранилища",
 		slog.String("source", source),
 		slog.String("op", op),
 	)
 
-	err = a.EffectiveMobile.Shutdown()
+	err = a.Storage.Shutdown()
 	if err != nil {
 		a.log.Log.Error(
-			"effectivemobile не удалось выполнить graceful shutdown, принудительная отсановка",
+			"хранилищу не удалось выполнить graceful shutdown, принудительная отсановка",
+			slog.String("source", source),
+			slog.String("op", op),
+		)
+
+		errs = append(errs, err)
+	}
+
+	a.log.Log.Debug(
+		"shutdown для логов",
+		slog.String("source", source),
+		slog.String("op", op),
+	)
+
+	err = a.log.Shutdown()
+	if err != nil {
+		a.log.Log.Error(
+			"логам не удалось выполнить shutdown, принудительная отсановка",
 			slog.String("source", source),
 			slog.String("op", op),
 		)
@@ -0,0 +1,9 @@
+//go:build !unix
+
+package effectivemobile
+
+import "syscall"
+
+func peerClosed(rc syscall.RawConn) bool {
+	return false
+}
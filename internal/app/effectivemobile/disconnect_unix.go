@@ -0,0 +1,31 @@
+//go:build unix
+
+package effectivemobile
+
+import (
+	"errors"
+	"syscall"
+)
+
+func peerClosed(rc syscall.RawConn) bool {
+	closed := false
+	buf := make([]byte, 1)
+
+	err := rc.Read(func(fd uintptr) bool {
+		n, _, err := syscall.Recvfrom(int(fd), buf, syscall.MSG_PEEK|syscall.MSG_DONTWAIT)
+		switch {
+		case err == nil:
+			closed = n == 0
+
+		case errors.Is(err, syscall.EAGAIN), errors.Is(err, syscall.EINTR):
+			closed = false
+
+		default:
+			closed = true
+		}
+
+		return true
+	})
+
+	return closed || err != nil
+}
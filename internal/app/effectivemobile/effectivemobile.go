@@ -1,10 +1,22 @@
 package effectivemobile
 
 import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"log/slog"
+	"math"
+	"mime"
 	"net"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
@@ -12,6 +24,9 @@ import (
 
 	_ "github.com/xoticdsign/effectivemobile/docs"
 	"github.com/xoticdsign/effectivemobile/internal/client"
+	"github.com/xoticdsign/effectivemobile/internal/client/breaker"
+	"github.com/xoticdsign/effectivemobile/internal/lib/actor"
+	"github.com/xoticdsign/effectivemobile/internal/lib/queue"
 	effectivemobileservice "github.com/xoticdsign/effectivemobile/internal/service/effectivemobile"
 	storage "github.com/xoticdsign/effectivemobile/internal/storage/postgresql"
 	"github.com/xoticdsign/effectivemobile/internal/utils/config"
@@ -19,21 +34,53 @@ import (
 
 const source = "effectivemobile"
 
+const (
+	HeaderActor    = "X-Actor"
+	ActorMaxLength = 100
+)
+
 var (
-	DeleteByIDHanlder    = "delete"
-	DeleteByIDParameters = ":id"
-	UpdateByIDHandler    = "update"
-	UpdateByIDParameters = ":id"
-	CreateHandler        = "create"
-	SelectHandler        = "select"
-	SelectParameters     = ":id?"
+	DeleteByIDHanlder      = "delete"
+	DeleteByIDParameters   = ":id"
+	UpdateByIDHandler      = "update"
+	UpdateByIDParameters   = ":id"
+	PeopleHandler          = "people"
+	PatchByIDParameters    = ":id"
+	RestoreByIDParameters  = ":id/restore"
+	HistoryByIDParameters  = ":id/history"
+	SnapshotByIDParameters = ":id/snapshot"
+	ReenrichByIDParameters = ":id/enrich"
+	CreateBatchParameters  = "batch"
+	MetricsHandler         = "metrics"
+	HealthHandler          = "health"
+	PurgeHandler           = "admin/purge"
+	ReenrichHandler        = "admin/reenrich"
+	ExportHandler          = "admin/export"
+	ExportFileParameters   = ":id"
+	CreateHandler          = "create"
+	SelectHandler          = "select"
+	SelectParameters       = ":id?"
 )
 
 var (
-	DeleteByIDSuccess = "entity has been deleted"
-	UpdateByIDSuccess = "entity has been updated"
-	CreateSuccess     = "entity has been created"
-	SelectSuccess     = "entity(ies) found"
+	DeleteByIDSuccess   = "entity has been deleted"
+	UpdateByIDSuccess   = "entity has been updated"
+	PatchByIDSuccess    = "entity has been patched"
+	RestoreByIDSuccess  = "entity has been restored"
+	PurgeSuccess        = "deleted entities have been purged"
+	HistoryByIDSuccess  = "history found"
+	SnapshotByIDSuccess = "snapshot found"
+	MetricsSuccess      = "metrics collected"
+	HealthSuccess       = "service is running"
+	CreateSuccess       = "entity has been created"
+	CreateAsyncSuccess  = "entity has been accepted for enrichment"
+	CreateBatchSuccess  = "entities have been created"
+	ReenrichByIDSuccess = "entity has been re-enriched"
+	ReenrichSuccess     = "re-enrichment has been scheduled"
+	ExportSuccess       = "export has been scheduled"
+	ExportPending       = "export is not ready yet"
+	ExportFailed        = "export has failed"
+	SelectSuccess       = "entity(ies) found"
 )
 
 type App struct {
@@ -46,8 +93,20 @@ type App struct {
 type Handlerer interface {
 	DeleteByID(c *fiber.Ctx) error
 	UpdateByID(c *fiber.Ctx) error
+	PatchByID(c *fiber.Ctx) error
+	RestoreByID(c *fiber.Ctx) error
+	Purge(c *fiber.Ctx) error
 	Create(c *fiber.Ctx) error
+	CreateBatch(c *fiber.Ctx) error
+	ReenrichByID(c *fiber.Ctx) error
+	Reenrich(c *fiber.Ctx) error
+	Export(c *fiber.Ctx) error
+	ExportFile(c *fiber.Ctx) error
 	Select(c *fiber.Ctx) error
+	HistoryByID(c *fiber.Ctx) error
+	SnapshotByID(c *fiber.Ctx) error
+	Metrics(c *fiber.Ctx) error
+	Health(c *fiber.Ctx) error
 }
 
 type Server struct {
@@ -55,7 +114,7 @@ type Server struct {
 	Handlers       Handlerer
 }
 
-func New(config config.EffectiveMobileConfig, storage *storage.Storage, log *slog.Logger) *App {
+func New(config config.EffectiveMobileConfig, storage *storage.Storage, q *queue.Queue, log *slog.Logger) *App {
 	f := fiber.New(fiber.Config{
 		ReadTimeout:  config.ReadTimeout,
 		WriteTimeout: config.WriteTimeout,
@@ -71,9 +130,9 @@ func New(config config.EffectiveMobileConfig, storage *storage.Storage, log *slo
 		AppName: "effectivemobile",
 	})
 
-	client := client.New(config, log)
+	client := client.New(config, storage.DB.Implementation, log)
 
-	emservice := effectivemobileservice.New(config, client, storage, log)
+	emservice := effectivemobileservice.New(config, client, storage, q, log)
 
 	h := Handlers{
 		Service: emservice.S.Handlers,
@@ -82,15 +141,39 @@ func New(config config.EffectiveMobileConfig, storage *storage.Storage, log *slo
 	}
 
 	f.Use(cors.New(cors.Config{
-		AllowOrigins: "*",
-		AllowMethods: "GET,POST,PUT,DELETE",
-		AllowHeaders: "Origin, Content-Type, Accept",
+		AllowOrigins:  "*",
+		AllowMethods:  "GET,POST,PUT,PATCH,DELETE",
+		AllowHeaders:  "Origin, Content-Type, Accept, If-Match, X-Actor",
+		ExposeHeaders: "Location, Link, ETag",
 	}))
+	f.Use(RequestContext(config.RequestTimeout))
+	f.Use(func(c *fiber.Ctx) error {
+		a := c.Get(HeaderActor)
+		if len(a) > ActorMaxLength {
+			return fiber.ErrBadRequest
+		}
+
+		c.SetUserContext(actor.WithActor(c.UserContext(), a))
+
+		return c.Next()
+	})
 
 	f.Delete(fmt.Sprintf("/%s/%s", DeleteByIDHanlder, DeleteByIDParameters), h.DeleteByID)
 	f.Put(fmt.Sprintf("/%s/%s", UpdateByIDHandler, UpdateByIDParameters), h.UpdateByID)
+	f.Patch(fmt.Sprintf("/%s/%s", PeopleHandler, PatchByIDParameters), h.PatchByID)
+	f.Post(fmt.Sprintf("/%s/%s", PeopleHandler, RestoreByIDParameters), h.RestoreByID)
+	f.Post(fmt.Sprintf("/%s", PurgeHandler), h.Purge)
 	f.Post(fmt.Sprintf("/%s", CreateHandler), h.Create)
+	f.Post(fmt.Sprintf("/%s/%s", PeopleHandler, CreateBatchParameters), h.CreateBatch)
+	f.Post(fmt.Sprintf("/%s/%s", PeopleHandler, ReenrichByIDParameters), h.ReenrichByID)
+	f.Post(fmt.Sprintf("/%s", ReenrichHandler), h.Reenrich)
+	f.Post(fmt.Sprintf("/%s", ExportHandler), h.Export)
+	f.Get(fmt.Sprintf("/%s/%s", ExportHandler, ExportFileParameters), h.ExportFile)
 	f.Get(fmt.Sprintf("/%s/%s", SelectHandler, SelectParameters), h.Select)
+	f.Get(fmt.Sprintf("/%s/%s", PeopleHandler, HistoryByIDParameters), h.HistoryByID)
+	f.Get(fmt.Sprintf("/%s/%s", PeopleHandler, SnapshotByIDParameters), h.SnapshotByID)
+	f.Get(fmt.Sprintf("/%s", MetricsHandler), h.Metrics)
+	f.Get(fmt.Sprintf("/%s", HealthHandler), h.Health)
 	f.Get("/swagger/*", swagger.New(swagger.ConfigDefault))
 
 	return &App{
@@ -117,21 +200,80 @@ func (a *App) Run() error {
 func (a *App) Shutdown() error {
 	const op = "effectivemobile.Shutdown()"
 
-	a.Client.Shutdown()
-
 	err := a.Server.Implementation.Shutdown()
 	if err != nil {
 		return err
 	}
 
+	a.Client.Shutdown()
+
 	return nil
 }
 
 type Servicer interface {
-	DeleteByID(id string) error
-	UpdateByID(id string, data []byte) error
-	Create(name string, surname string, patronymic string) error
-	Select(id string, limit []int, filter string, value string) ([]storage.Row, error)
+	DeleteByID(ctx context.Context, id string, versions []int) error
+	UpdateByID(ctx context.Context, id string, data []byte, versions []int) (storage.Row, error)
+	PatchByID(ctx context.Context, id string, patch storage.Patch, versions []int) (storage.Row, error)
+	RestoreByID(ctx context.Context, id string, versions []int) (storage.Row, error)
+	Purge(ctx context.Context, retention time.Duration) (int64, error)
+	Create(ctx context.Context, name string, surname string, patronymic string, countryID string) (storage.Row, error)
+	CreateAsync(ctx context.Context, name string, surname string, patronymic string, countryID string) (storage.Row, error)
+	CreateBatch(ctx context.Context, people []storage.Person) (effectivemobileservice.CreateBatchResult, error)
+	Reenrich(ctx context.Context, id string) (storage.Row, error)
+	ReenrichBatch(ctx context.Context, f storage.ReenrichFilter) (int64, error)
+	Export(ctx context.Context, filters []storage.Filter) (int64, error)
+	ExportFile(ctx context.Context, id int64) (effectivemobileservice.ExportResult, error)
+	Select(ctx context.Context, q storage.SelectQuery) (storage.SelectResult, error)
+	History(ctx context.Context, id string) ([]storage.HistoryEntry, error)
+	SelectAsOf(ctx context.Context, id string, asOf time.Time) (storage.Row, error)
+	Stats() client.Stats
+}
+
+const disconnectInterval = 100 * time.Millisecond
+
+func RequestContext(timeout time.Duration) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx, cancel := context.WithTimeout(c.UserContext(), timeout)
+		defer cancel()
+
+		conn := c.Context().Conn()
+		if tc, ok := conn.(*tls.Conn); ok {
+			conn = tc.NetConn()
+		}
+
+		if sc, ok := conn.(syscall.Conn); ok {
+			rc, err := sc.SyscallConn()
+			if err == nil {
+				done := make(chan struct{})
+				defer close(done)
+
+				go watchDisconnect(rc, cancel, done)
+			}
+		}
+
+		c.SetUserContext(ctx)
+
+		return c.Next()
+	}
+}
+
+func watchDisconnect(rc syscall.RawConn, cancel context.CancelFunc, done <-chan struct{}) {
+	t := time.NewTicker(disconnectInterval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+
+		case <-t.C:
+			if peerClosed(rc) {
+				cancel()
+
+				return
+			}
+		}
+	}
 }
 
 type Handlers struct {
@@ -162,29 +304,112 @@ type ConflictResponse struct {
 	Message string `json:"message" example:"Conflict"`
 }
 
+type PreconditionFailedResponse struct {
+	Code    int    `json:"code" example:"412"`
+	Message string `json:"message" example:"Precondition Failed"`
+}
+
+type UnsupportedMediaTypeResponse struct {
+	Code    int    `json:"code" example:"415"`
+	Message string `json:"message" example:"Unsupported Media Type"`
+}
+
 type InternalServerErrorResponse struct {
 	Code    int    `json:"code" example:"500"`
 	Message string `json:"message" example:"Internal Server Error"`
 }
 
+type ServiceUnavailableResponse struct {
+	Code    int    `json:"code" example:"503"`
+	Message string `json:"message" example:"Service Unavailable"`
+}
+
+type GatewayTimeoutResponse struct {
+	Code    int    `json:"code" example:"504"`
+	Message string `json:"message" example:"Gateway Timeout"`
+}
+
+func etag(version int) string {
+	return fmt.Sprintf("\"%d\"", version)
+}
+
+var errNoStrongEntityTag = fmt.Errorf("If-Match doesn't contain strong entity tags")
+
+func parseIfMatch(c *fiber.Ctx) ([]int, error) {
+	header := strings.TrimSpace(c.Get(fiber.HeaderIfMatch))
+	if header == "" || header == "*" {
+		return nil, nil
+	}
+
+	versions := []int{}
+	tags := 0
+
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "" {
+			continue
+		}
+		tags++
+
+		weak := strings.HasPrefix(tag, "W/")
+		if weak {
+			tag = tag[2:]
+		}
+
+		if len(tag) < 2 || tag[0] != '"' || tag[len(tag)-1] != '"' {
+			return nil, fmt.Errorf("invalid entity tag %s", tag)
+		}
+
+		version, err := strconv.Atoi(tag[1 : len(tag)-1])
+		if err != nil || version <= 0 {
+			return nil, fmt.Errorf("invalid entity tag %s", tag)
+		}
+
+		if !weak {
+			versions = append(versions, version)
+		}
+	}
+
+	if tags == 0 {
+		return nil, fmt.Errorf("invalid entity tag %s", header)
+	}
+
+	if len(versions) == 0 {
+		return nil, errNoStrongEntityTag
+	}
+
+	return versions, nil
+}
+
+func setRetryAfter(c *fiber.Ctx, err error) {
+	var e *client.RateLimitError
+
+	if errors.As(err, &e) {
+		c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(math.Ceil(e.RetryAfter.Seconds()))))
+	}
+}
+
 type DeleteByIDResponse struct {
 	Code    int    `json:"code" example:"200"`
 	Message string `json:"message" example:"entity has been deleted"`
 }
 
-// @description Удаляет запись из базы данных по заданному идентификатору.
+// @description Помечает запись с заданным идентификатором как удаленную. Запись можно восстановить до очистки.
 //
 // @id          delete
 // @tags        Операции
 //
 // @summary     Удаление записи по ID
 // @produce     json
-// @param       id  path     string                      true "Идентификатор записи"
-// @success     200 {object} DeleteByIDResponse          "Возвращается, если удаление прошло успешно"
-// @failure     400 {object} BadRequestResponse          "Возвращается, если запрос был сформирован неправильно"
-// @failure     404 {object} NotFoundResponse            "Возвращается, если запрашиваемая запись не была найдена"
-// @failure     405 {object} MethodNotAllowedResponse    "Возвращается, если был использован неправильный метод"
-// @failure     500 {object} InternalServerErrorResponse "Возвращается, если во время работы хранилища произошла ошибка"
+// @param       id       path     string                      true  "Идентификатор записи"
+// @param       If-Match header   string                      false "ETag записи, полученный при чтении, список ETag через запятую или *"
+// @success     200      {object} DeleteByIDResponse          "Возвращается, если удаление прошло успешно"
+// @failure     400      {object} BadRequestResponse          "Возвращается, если запрос был сформирован неправильно"
+// @failure     404      {object} NotFoundResponse            "Возвращается, если запрашиваемая запись не была найдена"
+// @failure     405      {object} MethodNotAllowedResponse    "Возвращается, если был использован неправильный метод"
+// @failure     412      {object} PreconditionFailedResponse  "Возвращается, если версия записи не совпадает ни с одним ETag из If-Match"
+// @failure     500      {object} InternalServerErrorResponse "Возвращается, если во время работы хранилища произошла ошибка"
+// @failure     504      {object} GatewayTimeoutResponse      "Возвращается, если операция не уложилась в отведенное время"
 // @router      /delete/{id} [delete]
 func (h Handlers) DeleteByID(c *fiber.Ctx) error {
 	const op = "effectivemobile.DeleteByID()"
@@ -201,19 +426,40 @@ func (h Handlers) DeleteByID(c *fiber.Ctx) error {
 		return fiber.ErrBadRequest
 	}
 
+	versions, err := parseIfMatch(c)
+	if err != nil {
+		h.Log.Debug(
+			"неправильно сформирован заголовок If-Match",
+			slog.String("source", source),
+			slog.String("op", op),
+			slog.Any("error", err),
+		)
+
+		if errors.Is(err, errNoStrongEntityTag) {
+			return fiber.ErrPreconditionFailed
+		}
+		return fiber.ErrBadRequest
+	}
+
 	h.Log.Debug(
 		"получен запрос на удаление",
 		slog.String("source", source),
 		slog.String("op", op),
-		slog.Any("parameters", []string{id}),
+		slog.Any("parameters", []interface{}{id, versions}),
 	)
 
-	err := h.Service.DeleteByID(id)
+	err = h.Service.DeleteByID(c.UserContext(), id, versions)
 	if err != nil {
 		switch {
 		case errors.Is(err, effectivemobileservice.ErrStorageNotFound):
 			return fiber.ErrNotFound
 
+		case errors.Is(err, effectivemobileservice.ErrStorageVersion):
+			return fiber.ErrPreconditionFailed
+
+		case errors.Is(err, effectivemobileservice.ErrTimeout):
+			return fiber.ErrGatewayTimeout
+
 		default:
 			return fiber.ErrInternalServerError
 		}
@@ -251,14 +497,18 @@ type UpdateByIDResponse struct {
 //
 // @summary     Обновление записи по ID
 // @produce     json
-// @param       id   path     string                      true "Идентификатор записи"
-// @param       body body     UpdateByIDRequest           true "Тело запроса"
-// @success     200  {object} UpdateByIDResponse          "Возвращается, если обновление прошло успешно"
-// @failure     400  {object} BadRequestResponse          "Возвращается, если запрос был сформирован неправильно"
-// @failure     404  {object} NotFoundResponse            "Возвращается, если запрашиваемая запись не была найдена"
-// @failure     405  {object} MethodNotAllowedResponse    "Возвращается, если был использован неправильный метод"
-// @failure     409  {object} ConflictResponse            "Возвращается, если переданные данные ничем не отличаются от уже существующих"
-// @failure     500  {object} InternalServerErrorResponse "Возвращается, если во время работы хранилища произошла ошибка"
+// @param       id       path     string                      true  "Идентификатор записи"
+// @param       If-Match header   string                      false "ETag записи, полученный при чтении, список ETag через запятую или *"
+// @param       body     body     UpdateByIDRequest           true  "Тело запроса"
+// @success     200      {object} UpdateByIDResponse          "Возвращается, если обновление прошло успешно"
+// @header      200      {string} ETag                        "Новая версия записи"
+// @failure     400      {object} BadRequestResponse          "Возвращается, если запрос был сформирован неправильно"
+// @failure     404      {object} NotFoundResponse            "Возвращается, если запрашиваемая запись не была найдена"
+// @failure     405      {object} MethodNotAllowedResponse    "Возвращается, если был использован неправильный метод"
+// @failure     409      {object} ConflictResponse            "Возвращается, если переданные данные ничем не отличаются от уже существующих"
+// @failure     412      {object} PreconditionFailedResponse  "Возвращается, если версия записи не совпадает ни с одним ETag из If-Match"
+// @failure     500      {object} InternalServerErrorResponse "Возвращается, если во время работы хранилища произошла ошибка"
+// @failure     504      {object} GatewayTimeoutResponse      "Возвращается, если операция не уложилась в отведенное время"
 // @router      /update/{id} [put]
 func (h Handlers) UpdateByID(c *fiber.Ctx) error {
 	const op = "effectivemobile.UpdateByID()"
@@ -289,15 +539,30 @@ func (h Handlers) UpdateByID(c *fiber.Ctx) error {
 		return fiber.ErrBadRequest
 	}
 
+	versions, err := parseIfMatch(c)
+	if err != nil {
+		h.Log.Debug(
+			"неправильно сформирован заголовок If-Match",
+			slog.String("source", source),
+			slog.String("op", op),
+			slog.Any("error", err),
+		)
+
+		if errors.Is(err, errNoStrongEntityTag) {
+			return fiber.ErrPreconditionFailed
+		}
+		return fiber.ErrBadRequest
+	}
+
 	h.Log.Debug(
 		"получен запрос на обновление",
 		slog.String("source", source),
 		slog.String("op", op),
-		slog.Any("parameters", []string{id}),
+		slog.Any("parameters", []interface{}{id, versions}),
 		slog.Any("body", body),
 	)
 
-	err = h.Service.UpdateByID(id, c.Body())
+	r, err := h.Service.UpdateByID(c.UserContext(), id, c.Body(), versions)
 	if err != nil {
 		switch {
 		case errors.Is(err, effectivemobileservice.ErrStorageNotFound):
@@ -306,6 +571,15 @@ func (h Handlers) UpdateByID(c *fiber.Ctx) error {
 		case errors.Is(err, effectivemobileservice.ErrStorageConflict):
 			return fiber.ErrConflict
 
+		case errors.Is(err, effectivemobileservice.ErrStorageInvalid):
+			return fiber.ErrBadRequest
+
+		case errors.Is(err, effectivemobileservice.ErrStorageVersion):
+			return fiber.ErrPreconditionFailed
+
+		case errors.Is(err, effectivemobileservice.ErrTimeout):
+			return fiber.ErrGatewayTimeout
+
 		default:
 			return fiber.ErrInternalServerError
 		}
@@ -317,43 +591,143 @@ func (h Handlers) UpdateByID(c *fiber.Ctx) error {
 		slog.String("op", op),
 	)
 
+	c.Set(fiber.HeaderETag, etag(r.Version))
+
 	return c.JSON(&UpdateByIDResponse{
 		Code:    fiber.StatusOK,
 		Message: UpdateByIDSuccess,
 	})
 }
 
-type CreateRequest struct {
-	Name       string `json:"name" example:"Ivan"`
-	Surname    string `json:"surname" example:"Petrov"`
-	Patronymic string `json:"patronymic" example:"Ivanovich"`
+type PatchByIDRequest struct {
+	Name        *string `json:"name,omitempty" example:"Ivan"`
+	Surname     *string `json:"surname,omitempty" example:"Petrov"`
+	Patronymic  *string `json:"patronymic,omitempty" example:"Ivanovich"`
+	Age         *int    `json:"age,omitempty" example:"21"`
+	Gender      *string `json:"gender,omitempty" example:"male"`
+	Nationality *string `json:"nationality,omitempty" example:"RU"`
 }
 
-type CreateResponse struct {
-	Code    int    `json:"code" example:"200"`
-	Message string `json:"message" example:"entity has been created"`
+type PatchByIDResponse struct {
+	Code    int         `json:"code" example:"200"`
+	Message string      `json:"message" example:"entity has been patched"`
+	Result  storage.Row `json:"result"`
+}
+
+var patchFields = map[string]bool{
+	FilterName:        false,
+	FilterSurname:     false,
+	FilterPatronymic:  true,
+	FilterAge:         true,
+	FilterGender:      true,
+	FilterNationality: true,
+}
+
+func parsePatch(body []byte) (storage.Patch, error) {
+	var raw map[string]json.RawMessage
+
+	err := json.Unmarshal(body, &raw)
+	if err != nil {
+		return nil, err
+	}
+
+	if raw == nil {
+		return nil, fmt.Errorf("merge patch must be a JSON object")
+	}
+
+	patch := storage.Patch{}
+
+	for field, value := range raw {
+		nullable, ok := patchFields[field]
+		if !ok {
+			return nil, fmt.Errorf("unknown field %s", field)
+		}
+
+		if bytes.Equal(bytes.TrimSpace(value), []byte("null")) {
+			if !nullable {
+				return nil, fmt.Errorf("field %s can't be cleared", field)
+			}
+			patch[field] = nil
+
+			continue
+		}
+
+		if field == FilterAge {
+			var age int
+
+			err := json.Unmarshal(value, &age)
+			if err != nil || age < 0 {
+				return nil, fmt.Errorf("field %s must be a non-negative integer", field)
+			}
+			patch[field] = age
+
+			continue
+		}
+
+		var str string
+
+		err := json.Unmarshal(value, &str)
+		if err != nil || str == "" {
+			return nil, fmt.Errorf("field %s must be a non-empty string", field)
+		}
+		patch[field] = str
+	}
+
+	return patch, nil
 }
 
-// @description Создает новую запись с автозаполнением возраста, пола и национальности при помощи открытых API.
+// @description Частично обновляет запись по ID согласно RFC 7396 (JSON Merge Patch).
+// @description Переданные поля заменяют текущие значения, null очищает поле, если оно допускает пустое значение (patronymic, age, gender, nationality).
+// @description Неизвестные поля отклоняются.
 //
-// @id          create
+// @id          patch
 // @tags        Операции
 //
-// @summary     Создание записи
+// @summary     Частичное обновление записи по ID
+// @accept      json
 // @produce     json
-// @param       body body     CreateRequest               true "Тело запроса"
-// @success     200  {object} CreateResponse              "Возвращается, если создание прошло успешно"
-// @failure     400  {object} BadRequestResponse          "Возвращается, если запрос был сформирован неправильно"
-// @failure     404  {object} NotFoundResponse            "Возвращается, если запрашиваемая запись не была найдена/во внешних API нет данных"
-// @failure     405  {object} MethodNotAllowedResponse    "Возвращается, если был использован неправильный метод"
-// @failure     500  {object} InternalServerErrorResponse "Возвращается, если во время работы хранилища/клиента произошла ошибка"
-// @router      /create [post]
-func (h Handlers) Create(c *fiber.Ctx) error {
-	const op = "effectivemobile.Create()"
+// @param       id       path     string                       true  "Идентификатор записи"
+// @param       If-Match header   string                       false "ETag записи, полученный при чтении, список ETag через запятую или *"
+// @param       body     body     PatchByIDRequest             true  "Тело запроса (application/merge-patch+json)"
+// @success     200      {object} PatchByIDResponse            "Возвращается, если обновление прошло успешно"
+// @header      200      {string} ETag                         "Новая версия записи"
+// @failure     400      {object} BadRequestResponse           "Возвращается, если запрос был сформирован неправильно или содержит неизвестные поля"
+// @failure     404      {object} NotFoundResponse             "Возвращается, если запрашиваемая запись не была найдена"
+// @failure     405      {object} MethodNotAllowedResponse     "Возвращается, если был использован неправильный метод"
+// @failure     409      {object} ConflictResponse             "Возвращается, если переданные данные нарушают ограничения таблицы"
+// @failure     412      {object} PreconditionFailedResponse   "Возвращается, если версия записи не совпадает ни с одним ETag из If-Match"
+// @failure     415      {object} UnsupportedMediaTypeResponse "Возвращается, если тело запроса не является JSON"
+// @failure     500      {object} InternalServerErrorResponse  "Возвращается, если во время работы хранилища произошла ошибка"
+// @failure     504      {object} GatewayTimeoutResponse       "Возвращается, если операция не уложилась в отведенное время"
+// @router      /people/{id} [patch]
+func (h Handlers) PatchByID(c *fiber.Ctx) error {
+	const op = "effectivemobile.PatchByID()"
+
+	mediaType, _, err := mime.ParseMediaType(c.Get(fiber.HeaderContentType))
+	if err != nil || (mediaType != "application/merge-patch+json" && mediaType != fiber.MIMEApplicationJSON) {
+		h.Log.Debug(
+			"неподдерживаемый тип тела запроса",
+			slog.String("source", source),
+			slog.String("op", op),
+			slog.Any("error", err),
+		)
 
-	var body CreateRequest
+		return fiber.ErrUnsupportedMediaType
+	}
 
-	err := c.BodyParser(&body)
+	id := c.Params("id")
+	if id == "" {
+		h.Log.Debug(
+			"отсутсвуют параметры",
+			slog.String("source", source),
+			slog.String("op", op),
+			slog.String("error", "absent parameters"),
+		)
+
+		return fiber.ErrBadRequest
+	}
+
+	patch, err := parsePatch(c.Body())
 	if err != nil {
 		h.Log.Debug(
 			"неправильно сформирован запрос",
@@ -365,183 +739,1546 @@ func (h Handlers) Create(c *fiber.Ctx) error {
 		return fiber.ErrBadRequest
 	}
 
-	if body.Name == "" || body.Surname == "" {
+	versions, err := parseIfMatch(c)
+	if err != nil {
 		h.Log.Debug(
-			"неправильно сформирован запрос",
+			"неправильно сформирован заголовок If-Match",
 			slog.String("source", source),
 			slog.String("op", op),
 			slog.Any("error", err),
 		)
 
+		if errors.Is(err, errNoStrongEntityTag) {
+			return fiber.ErrPreconditionFailed
+		}
 		return fiber.ErrBadRequest
 	}
 
 	h.Log.Debug(
-		"получен запрос на создание",
+		"получен запрос на частичное обновление",
 		slog.String("source", source),
 		slog.String("op", op),
-		slog.Any("body", body),
+		slog.Any("parameters", []interface{}{id, versions}),
+		slog.Any("body", patch),
 	)
 
-	err = h.Service.Create(body.Name, body.Surname, body.Patronymic)
+	r, err := h.Service.PatchByID(c.UserContext(), id, patch, versions)
 	if err != nil {
 		switch {
 		case errors.Is(err, effectivemobileservice.ErrStorageNotFound):
 			return fiber.ErrNotFound
 
-		case errors.Is(err, effectivemobileservice.ErrClientNotFound):
-			return fiber.ErrNotFound
+		case errors.Is(err, effectivemobileservice.ErrStorageConflict):
+			return fiber.ErrConflict
+
+		case errors.Is(err, effectivemobileservice.ErrStorageInvalid):
+			return fiber.ErrBadRequest
+
+		case errors.Is(err, effectivemobileservice.ErrStorageVersion):
+			return fiber.ErrPreconditionFailed
+
+		case errors.Is(err, effectivemobileservice.ErrTimeout):
+			return fiber.ErrGatewayTimeout
 
 		default:
 			return fiber.ErrInternalServerError
 		}
 	}
 	h.Log.Debug(
-		"обработан запрос на создание",
+		"обработан запрос на частичное обновление",
 		slog.String("source", source),
 		slog.String("op", op),
 	)
 
-	return c.JSON(&CreateResponse{
+	c.Set(fiber.HeaderETag, etag(r.Version))
+
+	return c.JSON(&PatchByIDResponse{
 		Code:    fiber.StatusOK,
-		Message: CreateSuccess,
+		Message: PatchByIDSuccess,
+		Result:  r,
 	})
 }
 
-var (
-	FilterName        = "name"
-	FilterSurname     = "surname"
-	FilterPatronymic  = "patronymic"
-	FilterAge         = "age"
-	FilterGender      = "gender"
-	FilterNationality = "nationality"
-)
-
-type SelectResponse struct {
-	Code    int           `json:"code" example:"200"`
-	Message string        `json:"message" example:"entity(ies) found"`
-	Result  []storage.Row `json:"result"`
+type RestoreByIDResponse struct {
+	Code    int         `json:"code" example:"200"`
+	Message string      `json:"message" example:"entity has been restored"`
+	Result  storage.Row `json:"result"`
 }
 
-// @description Возвращает запись/список записей с возможностью фильтрации и пагинации.
+// @description Восстанавливает ранее удаленную запись по заданному идентификатору.
 //
-// @id          select
+// @id          restore
 // @tags        Операции
 //
-// @summary     Получение записи(ей)
+// @summary     Восстановление записи по ID
 // @produce     json
-// @param       id     query    string                      false "Идентификатор записи"
-// @param       filter query    string                      false "Тип фильтра (name, surname, etc.)"
-// @param       value  query    string                      false "Значение фильтра"
-// @param       start  query    int                         false "Начальная позиция"
-// @param       end    query    int                         false "Конечная позиция"
-// @success     200    {object} SelectResponse              "Возвращается, если получение прошло успешно"
-// @failure     400    {object} BadRequestResponse          "Возвращается, если запрос был сформирован неправильно"
-// @failure     404    {object} NotFoundResponse            "Возвращается, если запрашиваемая запись(и) не была найдена"
-// @failure     405    {object} MethodNotAllowedResponse    "Возвращается, если был использован неправильный метод"
-// @failure     500    {object} InternalServerErrorResponse "Возвращается, если во время работы хранилища произошла ошибка"
-// @router      /select [get]
-func (h Handlers) Select(c *fiber.Ctx) error {
-	const op = "effectivemobile.Select()"
-
-	var id string
+// @param       id       path     string                      true  "Идентификатор записи"
+// @param       If-Match header   string                      false "ETag записи, полученный при чтении, список ETag через запятую или *"
+// @success     200      {object} RestoreByIDResponse         "Возвращается, если восстановление прошло успешно"
+// @header      200      {string} ETag                        "Новая версия записи"
+// @failure     400      {object} BadRequestResponse          "Возвращается, если запрос был сформирован неправильно"
+// @failure     404      {object} NotFoundResponse            "Возвращается, если запрашиваемая запись не была найдена"
+// @failure     405      {object} MethodNotAllowedResponse    "Возвращается, если был использован неправильный метод"
+// @failure     409      {object} ConflictResponse            "Возвращается, если запись не была удалена"
+// @failure     412      {object} PreconditionFailedResponse  "Возвращается, если версия записи не совпадает ни с одним ETag из If-Match"
+// @failure     500      {object} InternalServerErrorResponse "Возвращается, если во время работы хранилища произошла ошибка"
+// @failure     504      {object} GatewayTimeoutResponse      "Возвращается, если операция не уложилась в отведенное время"
+// @router      /people/{id}/restore [post]
+func (h Handlers) RestoreByID(c *fiber.Ctx) error {
+	const op = "effectivemobile.RestoreByID()"
 
-	id = c.Params("id")
+	id := c.Params("id")
 	if id == "" {
-		id = c.Query("id")
-	}
-
-	filter := c.Query("filter")
-	value := c.Query("value")
-	start := c.QueryInt("start", 0)
-	end := c.QueryInt("end", h.Config.SelectLimit)
-
-	if start >= end || start < 0 {
-		h.Log.Debug(
-			"неправильно сформирован запрос",
-			slog.String("source", source),
-			slog.String("op", op),
-			slog.Any("error", "start parameter can't be greater than the end and can't be less then zero"),
-		)
-
-		return fiber.ErrBadRequest
-	}
-
-	if id == "" && (filter == "" && value == "") {
-		h.Log.Debug(
-			"неправильно сформирован запрос",
-			slog.String("source", source),
-			slog.String("op", op),
-			slog.Any("error", "both filter and id parameters are empty"),
-		)
-
-		return fiber.ErrBadRequest
-	}
-
-	if id != "" && (filter != "" && value != "") {
 		h.Log.Debug(
-			"неправильно сформирован запрос",
+			"отсутсвуют параметры",
 			slog.String("source", source),
 			slog.String("op", op),
-			slog.Any("error", "filter and id parameters can't be used at the same time"),
+			slog.String("error", "absent parameters"),
 		)
 
 		return fiber.ErrBadRequest
 	}
 
-	if (filter != "" && value == "") || (filter == "" && value != "") {
+	versions, err := parseIfMatch(c)
+	if err != nil {
 		h.Log.Debug(
-			"неправильно сформирован запрос",
+			"неправильно сформирован заголовок If-Match",
 			slog.String("source", source),
 			slog.String("op", op),
-			slog.Any("error", "filter incomplete"),
+			slog.Any("error", err),
 		)
-		return fiber.ErrBadRequest
-	}
-
-	filters := map[string]bool{FilterName: true, FilterSurname: true, FilterPatronymic: true, FilterAge: true, FilterGender: true, FilterNationality: true}
 
-	if filter != "" && !filters[filter] {
-		h.Log.Debug(
-			"неправильно сформирован запрос",
-			slog.String("source", source),
-			slog.String("op", op),
-			slog.Any("error", "unknown filter"),
-		)
+		if errors.Is(err, errNoStrongEntityTag) {
+			return fiber.ErrPreconditionFailed
+		}
 		return fiber.ErrBadRequest
 	}
 
 	h.Log.Debug(
-		"получен запрос на получение",
+		"получен запрос на восстановление",
 		slog.String("source", source),
 		slog.String("op", op),
-		slog.Any("parameters", []interface{}{id, filter, value, start, end}),
+		slog.Any("parameters", []interface{}{id, versions}),
 	)
 
-	r, err := h.Service.Select(id, []int{start, end}, filter, value)
+	r, err := h.Service.RestoreByID(c.UserContext(), id, versions)
 	if err != nil {
 		switch {
 		case errors.Is(err, effectivemobileservice.ErrStorageNotFound):
 			return fiber.ErrNotFound
 
+		case errors.Is(err, effectivemobileservice.ErrStorageConflict):
+			return fiber.ErrConflict
+
+		case errors.Is(err, effectivemobileservice.ErrStorageVersion):
+			return fiber.ErrPreconditionFailed
+
+		case errors.Is(err, effectivemobileservice.ErrTimeout):
+			return fiber.ErrGatewayTimeout
+
 		default:
 			return fiber.ErrInternalServerError
 		}
 	}
-
+	h.Log.Debug(
+		"обработан запрос на восстановление",
+		slog.String("source", source),
+		slog.String("op", op),
+	)
+
+	c.Set(fiber.HeaderETag, etag(r.Version))
+
+	return c.JSON(&RestoreByIDResponse{
+		Code:    fiber.StatusOK,
+		Message: RestoreByIDSuccess,
+		Result:  r,
+	})
+}
+
+type PurgeResponse struct {
+	Code    int    `json:"code" example:"200"`
+	Message string `json:"message" example:"deleted entities have been purged"`
+	Purged  int64  `json:"purged" example:"10"`
+}
+
+// @description Окончательно удаляет записи, помеченные как удаленные раньше, чем истек срок хранения.
+// @description По умолчанию используется срок хранения из конфигурации (SERVER_PURGERETENTION).
+//
+// @id          purge
+// @tags        Администрирование
+//
+// @summary     Очистка удаленных записей
+// @produce     json
+// @param       retention query    string                      false "Срок хранения удаленных записей (например, 720h)"
+// @success     200       {object} PurgeResponse               "Возвращается, если очистка прошла успешно"
+// @failure     400       {object} BadRequestResponse          "Возвращается, если запрос был сформирован неправильно"
+// @failure     405       {object} MethodNotAllowedResponse    "Возвращается, если был использован неправильный метод"
+// @failure     500       {object} InternalServerErrorResponse "Возвращается, если во время работы хранилища произошла ошибка"
+// @failure     504       {object} GatewayTimeoutResponse      "Возвращается, если операция не уложилась в отведенное время"
+// @router      /admin/purge [post]
+func (h Handlers) Purge(c *fiber.Ctx) error {
+	const op = "effectivemobile.Purge()"
+
+	retention := h.Config.PurgeRetention
+
+	if value := c.Query("retention"); value != "" {
+		d, err := time.ParseDuration(value)
+		if err != nil || d <= 0 {
+			h.Log.Debug(
+				"неправильно сформирован запрос",
+				slog.String("source", source),
+				slog.String("op", op),
+				slog.Any("error", fmt.Errorf("invalid retention %s", value)),
+			)
+
+			return fiber.ErrBadRequest
+		}
+		retention = d
+	}
+
+	h.Log.Debug(
+		"получен запрос на очистку",
+		slog.String("source", source),
+		slog.String("op", op),
+		slog.Any("parameters", []interface{}{retention}),
+	)
+
+	purged, err := h.Service.Purge(c.UserContext(), retention)
+	if err != nil {
+		switch {
+		case errors.Is(err, effectivemobileservice.ErrTimeout):
+			return fiber.ErrGatewayTimeout
+
+		default:
+			return fiber.ErrInternalServerError
+		}
+	}
+	h.Log.Debug(
+		"обработан запрос на очистку",
+		slog.String("source", source),
+		slog.String("op", op),
+	)
+
+	return c.JSON(&PurgeResponse{
+		Code:    fiber.StatusOK,
+		Message: PurgeSuccess,
+		Purged:  purged,
+	})
+}
+
+type CreateRequest struct {
+	Name       string `json:"name" example:"Ivan"`
+	Surname    string `json:"surname" example:"Petrov"`
+	Patronymic string `json:"patronymic" example:"Ivanovich"`
+	CountryID  string `json:"country_id" example:"RU"`
+}
+
+func parseCountryID(value string) (string, error) {
+	value = strings.ToUpper(strings.TrimSpace(value))
+	if value == "" {
+		return "", nil
+	}
+
+	if len(value) != 2 || value[0] < 'A' || value[0] > 'Z' || value[1] < 'A' || value[1] > 'Z' {
+		return "", fmt.Errorf("invalid country_id %s", value)
+	}
+
+	return value, nil
+}
+
+type CreateResponse struct {
+	Code    int         `json:"code" example:"201"`
+	Message string      `json:"message" example:"entity has been created"`
+	Result  storage.Row `json:"result"`
+}
+
+// @description Создает новую запись с автозаполнением возраста, пола и национальности при помощи открытых API. Необязательный country_id (ISO 3166-1 alpha-2) уточняет оценку возраста и пола для страны, использованная локализация сохраняется в поле localization. Поля, уверенность в которых ниже настроенных порогов, остаются пустыми, а запись помечается needs_review. С параметром async=true запись сохраняется сразу со статусом pending_enrichment, а обогащение выполняется в фоне и завершается статусом enriched или enrichment_failed. При политике SERVER_ENRICHMENTPOLICY=best_effort ошибка части провайдеров не прерывает создание: недостающие поля остаются пустыми, а статус каждого поля (enriched, low_confidence, failed) возвращается в enrichment_fields. Поля отключенных провайдеров остаются пустыми со статусом skipped при любой политике.
+//
+// @id          create
+// @tags        Операции
+//
+// @summary     Создание записи
+// @produce     json
+// @param       body  body     CreateRequest               true  "Тело запроса"
+// @param       async query    bool                        false "Сохранить запись сразу и обогатить ее в фоне"
+// @success     201   {object} CreateResponse              "Возвращается, если создание прошло успешно, заголовок Location указывает на созданную запись"
+// @header      201   {string} Location                    "Путь до созданной записи"
+// @success     202   {object} CreateResponse              "Возвращается, если запись сохранена и поставлена в очередь обогащения"
+// @header      202   {string} Location                    "Путь до созданной записи"
+// @failure     400  {object} BadRequestResponse          "Возвращается, если запрос был сформирован неправильно"
+// @failure     404  {object} NotFoundResponse            "Возвращается, если запрашиваемая запись не была найдена/во внешних API нет данных"
+// @failure     405  {object} MethodNotAllowedResponse    "Возвращается, если был использован неправильный метод"
+// @failure     500  {object} InternalServerErrorResponse "Возвращается, если во время работы хранилища/клиента произошла ошибка"
+// @failure     503  {object} ServiceUnavailableResponse  "Возвращается, если внешний API недоступен, его предохранитель разомкнут или исчерпан лимит запросов"
+// @header      503  {string} Retry-After                 "Через сколько секунд можно повторить запрос, если исчерпан лимит запросов"
+// @failure     504  {object} GatewayTimeoutResponse      "Возвращается, если операция не уложилась в отведенное время"
+// @router      /create [post]
+func (h Handlers) Create(c *fiber.Ctx) error {
+	const op = "effectivemobile.Create()"
+
+	var body CreateRequest
+
+	err := c.BodyParser(&body)
+	if err != nil {
+		h.Log.Debug(
+			"неправильно сформирован запрос",
+			slog.String("source", source),
+			slog.String("op", op),
+			slog.Any("error", err),
+		)
+
+		return fiber.ErrBadRequest
+	}
+
+	if body.Name == "" || body.Surname == "" {
+		h.Log.Debug(
+			"неправильно сформирован запрос",
+			slog.String("source", source),
+			slog.String("op", op),
+			slog.Any("error", err),
+		)
+
+		return fiber.ErrBadRequest
+	}
+
+	countryID, err := parseCountryID(body.CountryID)
+	if err != nil {
+		h.Log.Debug(
+			"неправильно сформирован запрос",
+			slog.String("source", source),
+			slog.String("op", op),
+			slog.Any("error", err),
+		)
+
+		return fiber.ErrBadRequest
+	}
+
+	h.Log.Debug(
+		"получен запрос на создание",
+		slog.String("source", source),
+		slog.String("op", op),
+		slog.Any("body", body),
+	)
+
+	if c.QueryBool("async", false) {
+		r, err := h.Service.CreateAsync(c.UserContext(), body.Name, body.Surname, body.Patronymic, countryID)
+		if err != nil {
+			switch {
+			case errors.Is(err, effectivemobileservice.ErrTimeout):
+				return fiber.ErrGatewayTimeout
+
+			default:
+				return fiber.ErrInternalServerError
+			}
+		}
+		h.Log.Debug(
+			"запрос на создание принят в обработку",
+			slog.String("source", source),
+			slog.String("op", op),
+		)
+
+		c.Location(fmt.Sprintf("/%s/%d", SelectHandler, r.ID))
+
+		return c.Status(fiber.StatusAccepted).JSON(&CreateResponse{
+			Code:    fiber.StatusAccepted,
+			Message: CreateAsyncSuccess,
+			Result:  r,
+		})
+	}
+
+	r, err := h.Service.Create(c.UserContext(), body.Name, body.Surname, body.Patronymic, countryID)
+	if err != nil {
+		switch {
+		case errors.Is(err, effectivemobileservice.ErrStorageNotFound):
+			return fiber.ErrNotFound
+
+		case errors.Is(err, effectivemobileservice.ErrClientNotFound):
+			return fiber.ErrNotFound
+
+		case errors.Is(err, effectivemobileservice.ErrClientUnavailable):
+			return fiber.ErrServiceUnavailable
+
+		case errors.Is(err, effectivemobileservice.ErrClientUnauthorized), errors.Is(err, effectivemobileservice.ErrClientPaymentRequired):
+			return fiber.ErrServiceUnavailable
+
+		case errors.Is(err, effectivemobileservice.ErrClientRateLimited):
+			setRetryAfter(c, err)
+
+			return fiber.ErrServiceUnavailable
+
+		case errors.Is(err, effectivemobileservice.ErrTimeout):
+			return fiber.ErrGatewayTimeout
+
+		default:
+			return fiber.ErrInternalServerError
+		}
+	}
+	h.Log.Debug(
+		"обработан запрос на создание",
+		slog.String("source", source),
+		slog.String("op", op),
+	)
+
+	c.Location(fmt.Sprintf("/%s/%d", SelectHandler, r.ID))
+
+	return c.Status(fiber.StatusCreated).JSON(&CreateResponse{
+		Code:    fiber.StatusCreated,
+		Message: CreateSuccess,
+		Result:  r,
+	})
+}
+
+type CreateBatchRequest struct {
+	People []storage.Person `json:"people"`
+}
+
+type CreateBatchResponse struct {
+	Code    int                                      `json:"code" example:"201"`
+	Message string                                   `json:"message" example:"entities have been created"`
+	Result  effectivemobileservice.CreateBatchResult `json:"result"`
+}
+
+// @description Создает несколько записей за один запрос, обогащая их пакетными запросами к открытым API. Записи, для которых во внешних API нет данных, пропускаются, их индексы возвращаются в поле skipped. При политике SERVER_ENRICHMENTPOLICY=best_effort пропускаются только записи, для которых нет данных ни от одного включенного провайдера, остальные сохраняются, а недостающие поля получают статус failed.
+//
+// @id          createBatch
+// @tags        Операции
+//
+// @summary     Пакетное создание записей
+// @produce     json
+// @param       body body     CreateBatchRequest          true "Тело запроса"
+// @success     201  {object} CreateBatchResponse         "Возвращается, если создание прошло успешно"
+// @failure     400  {object} BadRequestResponse          "Возвращается, если запрос был сформирован неправильно или записей больше допустимого"
+// @failure     405  {object} MethodNotAllowedResponse    "Возвращается, если был использован неправильный метод"
+// @failure     500  {object} InternalServerErrorResponse "Возвращается, если во время работы хранилища/клиента произошла ошибка"
+// @failure     503  {object} ServiceUnavailableResponse  "Возвращается, если внешний API недоступен, его предохранитель разомкнут или исчерпан лимит запросов"
+// @header      503  {string} Retry-After                 "Через сколько секунд можно повторить запрос, если исчерпан лимит запросов"
+// @failure     504  {object} GatewayTimeoutResponse      "Возвращается, если операция не уложилась в отведенное время"
+// @router      /people/batch [post]
+func (h Handlers) CreateBatch(c *fiber.Ctx) error {
+	const op = "effectivemobile.CreateBatch()"
+
+	var body CreateBatchRequest
+
+	err := c.BodyParser(&body)
+	if err != nil {
+		h.Log.Debug(
+			"неправильно сформирован запрос",
+			slog.String("source", source),
+			slog.String("op", op),
+			slog.Any("error", err),
+		)
+
+		return fiber.ErrBadRequest
+	}
+
+	if len(body.People) == 0 || len(body.People) > h.Config.BatchLimit {
+		h.Log.Debug(
+			"неправильное количество записей в запросе",
+			slog.String("source", source),
+			slog.String("op", op),
+			slog.Int("count", len(body.People)),
+		)
+
+		return fiber.ErrBadRequest
+	}
+
+	for i, p := range body.People {
+		countryID, err := parseCountryID(p.CountryID)
+		if err != nil || p.Name == "" || p.Surname == "" {
+			h.Log.Debug(
+				"неправильно сформирован запрос",
+				slog.String("source", source),
+				slog.String("op", op),
+				slog.Any("error", err),
+			)
+
+			return fiber.ErrBadRequest
+		}
+		body.People[i].CountryID = countryID
+	}
+
+	h.Log.Debug(
+		"получен запрос на пакетное создание",
+		slog.String("source", source),
+		slog.String("op", op),
+		slog.Int("count", len(body.People)),
+	)
+
+	r, err := h.Service.CreateBatch(c.UserContext(), body.People)
+	if err != nil {
+		switch {
+		case errors.Is(err, effectivemobileservice.ErrClientUnavailable):
+			return fiber.ErrServiceUnavailable
+
+		case errors.Is(err, effectivemobileservice.ErrClientUnauthorized), errors.Is(err, effectivemobileservice.ErrClientPaymentRequired):
+			return fiber.ErrServiceUnavailable
+
+		case errors.Is(err, effectivemobileservice.ErrClientRateLimited):
+			setRetryAfter(c, err)
+
+			return fiber.ErrServiceUnavailable
+
+		case errors.Is(err, effectivemobileservice.ErrTimeout):
+			return fiber.ErrGatewayTimeout
+
+		default:
+			return fiber.ErrInternalServerError
+		}
+	}
+	h.Log.Debug(
+		"обработан запрос на пакетное создание",
+		slog.String("source", source),
+		slog.String("op", op),
+	)
+
+	return c.Status(fiber.StatusCreated).JSON(&CreateBatchResponse{
+		Code:    fiber.StatusCreated,
+		Message: CreateBatchSuccess,
+		Result:  r,
+	})
+}
+
+type ReenrichByIDResponse struct {
+	Code    int         `json:"code" example:"200"`
+	Message string      `json:"message" example:"entity has been re-enriched"`
+	Result  storage.Row `json:"result"`
+}
+
+// @description Повторно запрашивает возраст, пол и национальность у провайдеров в обход кэша и обновляет запись. Сохраненная локализация используется как country_id. Поля, которые провайдер не вернул или чей провайдер отключен, сохраняют прежние значения. Поля, уверенность в которых ниже текущих порогов, очищаются и получают статус low_confidence, а запись помечается needs_review. Изменения фиксируются в истории записи операцией enrich, если значения не изменились, запись остается прежней.
+//
+// @id          reenrich
+// @tags        Операции
+//
+// @summary     Повторное обогащение записи по ID
+// @produce     json
+// @param       id  path     string                      true "Идентификатор записи"
+// @success     200 {object} ReenrichByIDResponse        "Возвращается, если повторное обогащение прошло успешно"
+// @header      200 {string} ETag                        "Версия записи"
+// @failure     400 {object} BadRequestResponse          "Возвращается, если запрос был сформирован неправильно"
+// @failure     404 {object} NotFoundResponse            "Возвращается, если запрашиваемая запись не была найдена/во внешних API нет данных"
+// @failure     405 {object} MethodNotAllowedResponse    "Возвращается, если был использован неправильный метод"
+// @failure     500 {object} InternalServerErrorResponse "Возвращается, если во время работы хранилища/клиента произошла ошибка"
+// @failure     503 {object} ServiceUnavailableResponse  "Возвращается, если внешний API недоступен, его предохранитель разомкнут или исчерпан лимит запросов"
+// @header      503 {string} Retry-After                 "Через сколько секунд можно повторить запрос, если исчерпан лимит запросов"
+// @failure     504 {object} GatewayTimeoutResponse      "Возвращается, если операция не уложилась в отведенное время"
+// @router      /people/{id}/enrich [post]
+func (h Handlers) ReenrichByID(c *fiber.Ctx) error {
+	const op = "effectivemobile.ReenrichByID()"
+
+	id := c.Params("id")
+	if id == "" {
+		h.Log.Debug(
+			"отсутсвуют параметры",
+			slog.String("source", source),
+			slog.String("op", op),
+			slog.String("error", "absent parameters"),
+		)
+
+		return fiber.ErrBadRequest
+	}
+
+	h.Log.Debug(
+		"получен запрос на повторное обогащение",
+		slog.String("source", source),
+		slog.String("op", op),
+		slog.Any("parameters", []interface{}{id}),
+	)
+
+	r, err := h.Service.Reenrich(c.UserContext(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, effectivemobileservice.ErrStorageNotFound):
+			return fiber.ErrNotFound
+
+		case errors.Is(err, effectivemobileservice.ErrClientNotFound):
+			return fiber.ErrNotFound
+
+		case errors.Is(err, effectivemobileservice.ErrClientUnavailable):
+			return fiber.ErrServiceUnavailable
+
+		case errors.Is(err, effectivemobileservice.ErrClientUnauthorized), errors.Is(err, effectivemobileservice.ErrClientPaymentRequired):
+			return fiber.ErrServiceUnavailable
+
+		case errors.Is(err, effectivemobileservice.ErrClientRateLimited):
+			setRetryAfter(c, err)
+
+			return fiber.ErrServiceUnavailable
+
+		case errors.Is(err, effectivemobileservice.ErrTimeout):
+			return fiber.ErrGatewayTimeout
+
+		default:
+			return fiber.ErrInternalServerError
+		}
+	}
+	h.Log.Debug(
+		"обработан запрос на повторное обогащение",
+		slog.String("source", source),
+		slog.String("op", op),
+	)
+
+	c.Set(fiber.HeaderETag, etag(r.Version))
+
+	return c.JSON(&ReenrichByIDResponse{
+		Code:    fiber.StatusOK,
+		Message: ReenrichByIDSuccess,
+		Result:  r,
+	})
+}
+
+type ReenrichRequest struct {
+	Nationality   []string   `json:"nationality" example:"RU,KZ"`
+	CreatedBefore *time.Time `json:"created_before" example:"2024-01-01T00:00:00Z"`
+	LowConfidence bool       `json:"low_confidence" example:"true"`
+}
+
+type ReenrichResponse struct {
+	Code    int    `json:"code" example:"202"`
+	Message string `json:"message" example:"re-enrichment has been scheduled"`
+	JobID   int64  `json:"job_id" example:"1"`
+}
+
+// @description Ставит в очередь задачу повторного обогащения записей, подходящих под фильтр. Записи отбираются по национальности, дате создания (created_before) и низкой уверенности (low_confidence: запись требует проверки или детали оценки ниже текущих порогов), условия объединяются через И. Должно быть задано хотя бы одно условие. Провайдеры опрашиваются в обход кэша, изменения фиксируются в истории записей.
+//
+// @id          reenrichBatch
+// @tags        Администрирование
+//
+// @summary     Пакетное повторное обогащение записей
+// @produce     json
+// @param       body body     ReenrichRequest             true "Тело запроса"
+// @success     202  {object} ReenrichResponse            "Возвращается, если задача поставлена в очередь"
+// @failure     400  {object} BadRequestResponse          "Возвращается, если запрос был сформирован неправильно"
+// @failure     405  {object} MethodNotAllowedResponse    "Возвращается, если был использован неправильный метод"
+// @failure     500  {object} InternalServerErrorResponse "Возвращается, если во время работы хранилища произошла ошибка"
+// @failure     504  {object} GatewayTimeoutResponse      "Возвращается, если операция не уложилась в отведенное время"
+// @router      /admin/reenrich [post]
+func (h Handlers) Reenrich(c *fiber.Ctx) error {
+	const op = "effectivemobile.Reenrich()"
+
+	var body ReenrichRequest
+
+	err := c.BodyParser(&body)
+	if err != nil {
+		h.Log.Debug(
+			"неправильно сформирован запрос",
+			slog.String("source", source),
+			slog.String("op", op),
+			slog.Any("error", err),
+		)
+
+		return fiber.ErrBadRequest
+	}
+
+	if len(body.Nationality) == 0 && body.CreatedBefore == nil && !body.LowConfidence {
+		h.Log.Debug(
+			"неправильно сформирован запрос",
+			slog.String("source", source),
+			slog.String("op", op),
+			slog.String("error", "absent filters"),
+		)
+
+		return fiber.ErrBadRequest
+	}
+
+	f := storage.ReenrichFilter{
+		CreatedBefore: body.CreatedBefore,
+		LowConfidence: body.LowConfidence,
+	}
+
+	for _, n := range body.Nationality {
+		countryID, err := parseCountryID(n)
+		if err != nil || countryID == "" {
+			h.Log.Debug(
+				"неправильно сформирован запрос",
+				slog.String("source", source),
+				slog.String("op", op),
+				slog.Any("error", fmt.Errorf("invalid nationality %s", n)),
+			)
+
+			return fiber.ErrBadRequest
+		}
+		f.Nationality = append(f.Nationality, countryID)
+	}
+
+	h.Log.Debug(
+		"получен запрос на пакетное повторное обогащение",
+		slog.String("source", source),
+		slog.String("op", op),
+		slog.Any("body", f),
+	)
+
+	id, err := h.Service.ReenrichBatch(c.UserContext(), f)
+	if err != nil {
+		switch {
+		case errors.Is(err, effectivemobileservice.ErrTimeout):
+			return fiber.ErrGatewayTimeout
+
+		default:
+			return fiber.ErrInternalServerError
+		}
+	}
+	h.Log.Debug(
+		"запрос на пакетное повторное обогащение принят в обработку",
+		slog.String("source", source),
+		slog.String("op", op),
+	)
+
+	return c.Status(fiber.StatusAccepted).JSON(&ReenrichResponse{
+		Code:    fiber.StatusAccepted,
+		Message: ReenrichSuccess,
+		JobID:   id,
+	})
+}
+
+type ExportResponse struct {
+	Code    int    `json:"code" example:"202"`
+	Message string `json:"message" example:"export has been scheduled"`
+	JobID   int64  `json:"job_id" example:"1"`
+}
+
+// @description Ставит в очередь задачу выгрузки записей в формате NDJSON (одна запись JSON на строку). Записи отбираются теми же фильтрами, что и в /select (name, age_gte, enrichment_status и т.д.), без фильтров выгружаются все неудаленные записи. Готовая выгрузка доступна по /admin/export/{id}, где id — идентификатор задачи.
+//
+// @id          export
+// @tags        Администрирование
+//
+// @summary     Выгрузка записей
+// @produce     json
+// @param       name              query    string                      false "Имя или список имен через запятую"
+// @param       surname           query    string                      false "Фамилия или список фамилий через запятую"
+// @param       age_gte           query    int                         false "Возраст больше или равен"
+// @param       age_lte           query    int                         false "Возраст меньше или равен"
+// @param       gender            query    string                      false "Пол или список полов через запятую"
+// @param       nationality       query    string                      false "Национальность или список национальностей через запятую"
+// @param       needs_review      query    bool                        false "Записи, у которых часть полей не заполнена из-за низкой уверенности"
+// @param       enrichment_status query    string                      false "Статус обогащения или список статусов через запятую (pending_enrichment, enriched, enrichment_failed)"
+// @success     202               {object} ExportResponse              "Возвращается, если задача поставлена в очередь"
+// @failure     400               {object} BadRequestResponse          "Возвращается, если запрос был сформирован неправильно"
+// @failure     405               {object} MethodNotAllowedResponse    "Возвращается, если был использован неправильный метод"
+// @failure     500               {object} InternalServerErrorResponse "Возвращается, если во время работы хранилища произошла ошибка"
+// @failure     504               {object} GatewayTimeoutResponse      "Возвращается, если операция не уложилась в отведенное время"
+// @router      /admin/export [post]
+func (h Handlers) Export(c *fiber.Ctx) error {
+	const op = "effectivemobile.Export()"
+
+	filters, err := parseFilters(c)
+	if err != nil {
+		h.Log.Debug(
+			"неправильно сформирован запрос",
+			slog.String("source", source),
+			slog.String("op", op),
+			slog.Any("error", err),
+		)
+
+		return fiber.ErrBadRequest
+	}
+
+	h.Log.Debug(
+		"получен запрос на выгрузку",
+		slog.String("source", source),
+		slog.String("op", op),
+		slog.Any("parameters", filters),
+	)
+
+	id, err := h.Service.Export(c.UserContext(), filters)
+	if err != nil {
+		switch {
+		case errors.Is(err, effectivemobileservice.ErrTimeout):
+			return fiber.ErrGatewayTimeout
+
+		default:
+			return fiber.ErrInternalServerError
+		}
+	}
+	h.Log.Debug(
+		"запрос на выгрузку принят в обработку",
+		slog.String("source", source),
+		slog.String("op", op),
+	)
+
+	return c.Status(fiber.StatusAccepted).JSON(&ExportResponse{
+		Code:    fiber.StatusAccepted,
+		Message: ExportSuccess,
+		JobID:   id,
+	})
+}
+
+type ExportStatusResponse struct {
+	Code      int    `json:"code" example:"202"`
+	Message   string `json:"message" example:"export is not ready yet"`
+	JobID     int64  `json:"job_id" example:"1"`
+	Status    string `json:"status" example:"queued"`
+	LastError string `json:"last_error,omitempty" example:"время ожидания истекло"`
+}
+
+// @description Отдает готовую выгрузку записей в формате NDJSON. Пока задача выгрузки не завершена, возвращается 202 с ее статусом (queued или running) и последней ошибкой, если попытки уже были. Если задача исчерпала попытки, возвращается 410 со статусом dead и последней ошибкой. 404 возвращается только для неизвестных идентификаторов.
+//
+// @id          exportFile
+// @tags        Администрирование
+//
+// @summary     Получение выгрузки
+// @produce     application/x-ndjson
+// @produce     json
+// @param       id  path     int                         true "Идентификатор задачи выгрузки"
+// @success     200 {string} string                      "Возвращается, если выгрузка готова"
+// @success     202 {object} ExportStatusResponse        "Возвращается, если выгрузка еще не готова"
+// @failure     400 {object} BadRequestResponse          "Возвращается, если запрос был сформирован неправильно"
+// @failure     404 {object} NotFoundResponse            "Возвращается, если задача выгрузки не найдена"
+// @failure     405 {object} MethodNotAllowedResponse    "Возвращается, если был использован неправильный метод"
+// @failure     410 {object} ExportStatusResponse        "Возвращается, если задача выгрузки завершилась ошибкой"
+// @failure     500 {object} InternalServerErrorResponse "Возвращается, если выгрузку не удалось прочитать"
+// @failure     504 {object} GatewayTimeoutResponse      "Возвращается, если операция не уложилась в отведенное время"
+// @router      /admin/export/{id} [get]
+func (h Handlers) ExportFile(c *fiber.Ctx) error {
+	const op = "effectivemobile.ExportFile()"
+
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil || id <= 0 {
+		h.Log.Debug(
+			"неправильно сформирован запрос",
+			slog.String("source", source),
+			slog.String("op", op),
+			slog.String("error", "invalid id"),
+		)
+
+		return fiber.ErrBadRequest
+	}
+
+	h.Log.Debug(
+		"получен запрос на получение выгрузки",
+		slog.String("source", source),
+		slog.String("op", op),
+		slog.Any("parameters", []interface{}{id}),
+	)
+
+	r, err := h.Service.ExportFile(c.UserContext(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, effectivemobileservice.ErrStorageNotFound):
+			return fiber.ErrNotFound
+
+		case errors.Is(err, effectivemobileservice.ErrTimeout):
+			return fiber.ErrGatewayTimeout
+
+		default:
+			return fiber.ErrInternalServerError
+		}
+	}
+	h.Log.Debug(
+		"обработан запрос на получение выгрузки",
+		slog.String("source", source),
+		slog.String("op", op),
+	)
+
+	if r.File == nil {
+		code, message := fiber.StatusAccepted, ExportPending
+		if r.Status == queue.StatusDead {
+			code, message = fiber.StatusGone, ExportFailed
+		}
+
+		return c.Status(code).JSON(&ExportStatusResponse{
+			Code:      code,
+			Message:   message,
+			JobID:     id,
+			Status:    r.Status,
+			LastError: r.LastError,
+		})
+	}
+
+	c.Set(fiber.HeaderContentType, "application/x-ndjson")
+	c.Set(fiber.HeaderContentDisposition, fmt.Sprintf("attachment; filename=\"export-%d.ndjson\"", id))
+
+	return c.SendStream(r.File)
+}
+
+var (
+	FilterName        = "name"
+	FilterSurname     = "surname"
+	FilterPatronymic  = "patronymic"
+	FilterAge         = "age"
+	FilterGender      = "gender"
+	FilterNationality = "nationality"
+
+	FilterAgeCount               = "age_count"
+	FilterGenderProbability      = "gender_probability"
+	FilterGenderCount            = "gender_count"
+	FilterNationalityProbability = "nationality_probability"
+	FilterNationalityCount       = "nationality_count"
+	FilterNeedsReview            = "needs_review"
+	FilterEnrichmentStatus       = "enrichment_status"
+)
+
+var filterFields = []string{FilterName, FilterSurname, FilterPatronymic, FilterAge, FilterGender, FilterNationality, FilterAgeCount, FilterGenderProbability, FilterGenderCount, FilterNationalityProbability, FilterNationalityCount, FilterNeedsReview, FilterEnrichmentStatus}
+
+var filterOperators = map[string][]string{
+	FilterName:        {storage.OperatorIn, storage.OperatorPrefix},
+	FilterSurname:     {storage.OperatorIn, storage.OperatorPrefix},
+	FilterPatronymic:  {storage.OperatorIn, storage.OperatorPrefix},
+	FilterAge:         {storage.OperatorIn, storage.OperatorGt, storage.OperatorGte, storage.OperatorLt, storage.OperatorLte},
+	FilterGender:      {storage.OperatorIn},
+	FilterNationality: {storage.OperatorIn},
+
+	FilterAgeCount:               {storage.OperatorGt, storage.OperatorGte, storage.OperatorLt, storage.OperatorLte},
+	FilterGenderProbability:      {storage.OperatorGt, storage.OperatorGte, storage.OperatorLt, storage.OperatorLte},
+	FilterGenderCount:            {storage.OperatorGt, storage.OperatorGte, storage.OperatorLt, storage.OperatorLte},
+	FilterNationalityProbability: {storage.OperatorGt, storage.OperatorGte, storage.OperatorLt, storage.OperatorLte},
+	FilterNationalityCount:       {storage.OperatorGt, storage.OperatorGte, storage.OperatorLt, storage.OperatorLte},
+	FilterNeedsReview:            {storage.OperatorIn},
+	FilterEnrichmentStatus:       {storage.OperatorIn},
+}
+
+var integerFilters = map[string]bool{
+	FilterAge:              true,
+	FilterAgeCount:         true,
+	FilterGenderCount:      true,
+	FilterNationalityCount: true,
+}
+
+var probabilityFilters = map[string]bool{
+	FilterGenderProbability:      true,
+	FilterNationalityProbability: true,
+}
+
+var enrichmentStatuses = []string{storage.StatusPending, storage.StatusEnriched, storage.StatusFailed}
+
+func parseFilter(field string, operator string, value string) (storage.Filter, error) {
+	values := strings.Split(value, ",")
+
+	if operator != storage.OperatorIn && len(values) > 1 {
+		return storage.Filter{}, fmt.Errorf("operator %s accepts a single value", operator)
+	}
+
+	for _, v := range values {
+		if v == "" {
+			return storage.Filter{}, fmt.Errorf("filter %s contains an empty value", field)
+		}
+
+		if integerFilters[field] {
+			_, err := strconv.Atoi(v)
+			if err != nil {
+				return storage.Filter{}, fmt.Errorf("filter %s accepts only integers", field)
+			}
+		}
+
+		if field == FilterNeedsReview {
+			_, err := strconv.ParseBool(v)
+			if err != nil {
+				return storage.Filter{}, fmt.Errorf("filter %s accepts only booleans", field)
+			}
+		}
+
+		if field == FilterEnrichmentStatus && !slices.Contains(enrichmentStatuses, v) {
+			return storage.Filter{}, fmt.Errorf("filter %s accepts only %s", field, strings.Join(enrichmentStatuses, ", "))
+		}
+
+		if probabilityFilters[field] {
+			p, err := strconv.ParseFloat(v, 64)
+			if err != nil || p < 0 || p > 1 {
+				return storage.Filter{}, fmt.Errorf("filter %s accepts only numbers from 0 to 1", field)
+			}
+		}
+	}
+
+	return storage.Filter{
+		Field:    field,
+		Operator: operator,
+		Values:   values,
+	}, nil
+}
+
+func parseFilters(c *fiber.Ctx) ([]storage.Filter, error) {
+	filters := []storage.Filter{}
+
+	for _, field := range filterFields {
+		for _, operator := range filterOperators[field] {
+			key := field
+			if operator != storage.OperatorIn {
+				key = fmt.Sprintf("%s_%s", field, operator)
+			}
+
+			value := c.Query(key)
+			if value == "" {
+				continue
+			}
+
+			f, err := parseFilter(field, operator, value)
+			if err != nil {
+				return nil, err
+			}
+			filters = append(filters, f)
+		}
+	}
+
+	return filters, nil
+}
+
+var sortFields = map[string]bool{
+	"id":              true,
+	FilterName:        true,
+	FilterSurname:     true,
+	FilterPatronymic:  true,
+	FilterAge:         true,
+	FilterGender:      true,
+	FilterNationality: true,
+}
+
+func parseSort(value string) ([]storage.Sort, error) {
+	sort := []storage.Sort{}
+
+	if value == "" {
+		return sort, nil
+	}
+
+	seen := map[string]bool{}
+
+	for _, key := range strings.Split(value, ",") {
+		desc := strings.HasPrefix(key, "-")
+		field := strings.TrimPrefix(key, "-")
+
+		if !sortFields[field] {
+			return nil, fmt.Errorf("unknown sort key %s", field)
+		}
+
+		if seen[field] {
+			return nil, fmt.Errorf("duplicate sort key %s", field)
+		}
+		seen[field] = true
+
+		sort = append(sort, storage.Sort{
+			Field: field,
+			Desc:  desc,
+		})
+	}
+
+	return sort, nil
+}
+
+func pageLink(c *fiber.Ctx, params map[string]string) string {
+	query, _ := url.ParseQuery(string(c.Request().URI().QueryString()))
+
+	for k, v := range params {
+		query.Set(k, v)
+	}
+
+	return fmt.Sprintf("%s?%s", c.Path(), query.Encode())
+}
+
+type SelectResponse struct {
+	Code       int           `json:"code" example:"200"`
+	Message    string        `json:"message" example:"entity(ies) found"`
+	Result     []storage.Row `json:"result"`
+	Start      int           `json:"start" example:"0"`
+	End        int           `json:"end" example:"1"`
+	HasMore    bool          `json:"has_more" example:"true"`
+	Total      *int          `json:"total,omitempty" example:"42"`
+	NextCursor string        `json:"next_cursor,omitempty" example:"eyJzIjoiaWQiLCJ2IjpbMTAwMF19"`
+}
+
+// @description Возвращает запись/список записей с возможностью фильтрации и пагинации.
+// @description Фильтры можно комбинировать: значения через запятую образуют список (nationality=RU,UA,KZ),
+// @description суффиксы _gt, _gte, _lt, _lte задают диапазон возраста, суффикс _prefix ищет по началу имени, фамилии или отчества.
+// @description Если после страницы остались записи, в ответе возвращается next_cursor: передав его в параметре cursor
+// @description вместо start/end, можно получить следующую страницу без OFFSET. Курсор действителен только для той же сортировки.
+// @description Ссылки на соседние страницы возвращаются в заголовке Link (rel="next", rel="prev").
+//
+// @id          select
+// @tags        Операции
+//
+// @summary     Получение записи(ей)
+// @produce     json
+// @param       id                query    string                      false "Идентификатор записи"
+// @param       filter            query    string                      false "Тип фильтра (name, surname, etc.), устаревший вариант"
+// @param       value             query    string                      false "Значение фильтра, устаревший вариант"
+// @param       name              query    string                      false "Имя или список имен через запятую"
+// @param       surname           query    string                      false "Фамилия или список фамилий через запятую"
+// @param       patronymic        query    string                      false "Отчество или список отчеств через запятую"
+// @param       age               query    string                      false "Возраст или список возрастов через запятую"
+// @param       gender            query    string                      false "Пол или список полов через запятую"
+// @param       nationality       query    string                      false "Национальность или список национальностей через запятую"
+// @param       name_prefix       query    string                      false "Начало имени"
+// @param       surname_prefix    query    string                      false "Начало фамилии"
+// @param       patronymic_prefix query    string                      false "Начало отчества"
+// @param       age_gt            query    int                         false "Возраст больше"
+// @param       age_gte           query    int                         false "Возраст больше или равен"
+// @param       age_lt            query    int                         false "Возраст меньше"
+// @param       age_lte           query    int                         false "Возраст меньше или равен"
+// @param       age_count_gt                query    int    false "Размер выборки для оценки возраста больше"
+// @param       age_count_gte               query    int    false "Размер выборки для оценки возраста больше или равен"
+// @param       age_count_lt                query    int    false "Размер выборки для оценки возраста меньше"
+// @param       age_count_lte               query    int    false "Размер выборки для оценки возраста меньше или равен"
+// @param       gender_probability_gt       query    number false "Вероятность пола больше"
+// @param       gender_probability_gte      query    number false "Вероятность пола больше или равна"
+// @param       gender_probability_lt       query    number false "Вероятность пола меньше"
+// @param       gender_probability_lte      query    number false "Вероятность пола меньше или равна"
+// @param       gender_count_gt             query    int    false "Размер выборки для оценки пола больше"
+// @param       gender_count_gte            query    int    false "Размер выборки для оценки пола больше или равен"
+// @param       gender_count_lt             query    int    false "Размер выборки для оценки пола меньше"
+// @param       gender_count_lte            query    int    false "Размер выборки для оценки пола меньше или равен"
+// @param       nationality_probability_gt  query    number false "Вероятность национальности больше"
+// @param       nationality_probability_gte query    number false "Вероятность национальности больше или равна"
+// @param       nationality_probability_lt  query    number false "Вероятность национальности меньше"
+// @param       nationality_probability_lte query    number false "Вероятность национальности меньше или равна"
+// @param       nationality_count_gt        query    int    false "Размер выборки для оценки национальности больше"
+// @param       nationality_count_gte       query    int    false "Размер выборки для оценки национальности больше или равен"
+// @param       nationality_count_lt        query    int    false "Размер выборки для оценки национальности меньше"
+// @param       nationality_count_lte       query    int    false "Размер выборки для оценки национальности меньше или равен"
+// @param       needs_review                query    bool   false "Записи, у которых часть полей не заполнена из-за низкой уверенности"
+// @param       enrichment_status           query    string false "Статус обогащения или список статусов через запятую (pending_enrichment, enriched, enrichment_failed)"
+// @param       sort              query    string                      false "Ключи сортировки через запятую, минус перед ключом задает обратный порядок (-age,surname,name)"
+// @param       start             query    int                         false "Начальная позиция"
+// @param       end               query    int                         false "Конечная позиция"
+// @param       cursor            query    string                      false "Курсор следующей страницы из next_cursor"
+// @param       limit             query    int                         false "Размер страницы при использовании курсора"
+// @param       with_total        query    bool                        false "Посчитать общее количество подходящих записей"
+// @param       include_deleted   query    bool                        false "Включить удаленные записи"
+// @success     200               {object} SelectResponse              "Возвращается, если получение прошло успешно"
+// @header      200               {string} Link                        "Ссылки на следующую и предыдущую страницы"
+// @header      200               {string} ETag                        "Версия записи, если она запрошена по идентификатору"
+// @failure     400               {object} BadRequestResponse          "Возвращается, если запрос был сформирован неправильно"
+// @failure     404               {object} NotFoundResponse            "Возвращается, если запись с указанным идентификатором не была найдена, пустой список возвращается с кодом 200"
+// @failure     405               {object} MethodNotAllowedResponse    "Возвращается, если был использован неправильный метод"
+// @failure     500               {object} InternalServerErrorResponse "Возвращается, если во время работы хранилища произошла ошибка"
+// @failure     504               {object} GatewayTimeoutResponse      "Возвращается, если операция не уложилась в отведенное время"
+// @router      /select [get]
+func (h Handlers) Select(c *fiber.Ctx) error {
+	const op = "effectivemobile.Select()"
+
+	var id string
+
+	id = c.Params("id")
+	if id == "" {
+		id = c.Query("id")
+	}
+
+	filter := c.Query("filter")
+	value := c.Query("value")
+	cursor := c.Query("cursor")
+	withTotal := c.QueryBool("with_total", false)
+	includeDeleted := c.QueryBool("include_deleted", false)
+	start := c.QueryInt("start", 0)
+	end := c.QueryInt("end", h.Config.SelectLimit)
+
+	if cursor != "" {
+		if c.Query("start") != "" || c.Query("end") != "" || id != "" {
+			h.Log.Debug(
+				"неправильно сформирован запрос",
+				slog.String("source", source),
+				slog.String("op", op),
+				slog.Any("error", "cursor parameter can't be used with start, end or id parameters"),
+			)
+
+			return fiber.ErrBadRequest
+		}
+
+		start = 0
+		end = c.QueryInt("limit", h.Config.SelectLimit)
+	}
+
+	if start >= end || start < 0 {
+		h.Log.Debug(
+			"неправильно сформирован запрос",
+			slog.String("source", source),
+			slog.String("op", op),
+			slog.Any("error", "start parameter can't be greater than the end and can't be less then zero"),
+		)
+
+		return fiber.ErrBadRequest
+	}
+
+	if (filter != "" && value == "") || (filter == "" && value != "") {
+		h.Log.Debug(
+			"неправильно сформирован запрос",
+			slog.String("source", source),
+			slog.String("op", op),
+			slog.Any("error", "filter incomplete"),
+		)
+		return fiber.ErrBadRequest
+	}
+
+	if filter != "" && !slices.Contains(filterOperators[filter], storage.OperatorIn) {
+		h.Log.Debug(
+			"неправильно сформирован запрос",
+			slog.String("source", source),
+			slog.String("op", op),
+			slog.Any("error", "unknown filter"),
+		)
+		return fiber.ErrBadRequest
+	}
+
+	filters, err := parseFilters(c)
+	if err != nil {
+		h.Log.Debug(
+			"неправильно сформирован запрос",
+			slog.String("source", source),
+			slog.String("op", op),
+			slog.Any("error", err),
+		)
+
+		return fiber.ErrBadRequest
+	}
+
+	sort, err := parseSort(c.Query("sort"))
+	if err != nil {
+		h.Log.Debug(
+			"неправильно сформирован запрос",
+			slog.String("source", source),
+			slog.String("op", op),
+			slog.Any("error", err),
+		)
+
+		return fiber.ErrBadRequest
+	}
+
+	if filter != "" {
+		f, err := parseFilter(filter, storage.OperatorIn, value)
+		if err != nil {
+			h.Log.Debug(
+				"неправильно сформирован запрос",
+				slog.String("source", source),
+				slog.String("op", op),
+				slog.Any("error", err),
+			)
+
+			return fiber.ErrBadRequest
+		}
+		filters = append(filters, f)
+	}
+
+	if id == "" && len(filters) == 0 {
+		h.Log.Debug(
+			"неправильно сформирован запрос",
+			slog.String("source", source),
+			slog.String("op", op),
+			slog.Any("error", "both filter and id parameters are empty"),
+		)
+
+		return fiber.ErrBadRequest
+	}
+
+	if id != "" && len(filters) != 0 {
+		h.Log.Debug(
+			"неправильно сформирован запрос",
+			slog.String("source", source),
+			slog.String("op", op),
+			slog.Any("error", "filter and id parameters can't be used at the same time"),
+		)
+
+		return fiber.ErrBadRequest
+	}
+
+	h.Log.Debug(
+		"получен запрос на получение",
+		slog.String("source", source),
+		slog.String("op", op),
+		slog.Any("parameters", []interface{}{id, filters, sort, cursor, start, end}),
+	)
+
+	r, err := h.Service.Select(c.UserContext(), storage.SelectQuery{
+		ID:             id,
+		Limit:          []int{start, end},
+		Filters:        filters,
+		Sort:           sort,
+		Cursor:         cursor,
+		WithTotal:      withTotal,
+		IncludeDeleted: includeDeleted,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, effectivemobileservice.ErrStorageNotFound):
+			return fiber.ErrNotFound
+
+		case errors.Is(err, effectivemobileservice.ErrStorageInvalid):
+			return fiber.ErrBadRequest
+
+		case errors.Is(err, effectivemobileservice.ErrTimeout):
+			return fiber.ErrGatewayTimeout
+
+		default:
+			return fiber.ErrInternalServerError
+		}
+	}
+
 	h.Log.Debug(
 		"обработан запрос на получение",
 		slog.String("source", source),
 		slog.String("op", op),
 	)
 
+	links := []string{}
+
+	if r.HasMore {
+		if cursor != "" {
+			links = append(links, pageLink(c, map[string]string{"cursor": r.NextCursor}), "next")
+		} else {
+			links = append(links, pageLink(c, map[string]string{"start": strconv.Itoa(end), "end": strconv.Itoa(2*end - start)}), "next")
+		}
+	}
+
+	if cursor == "" && id == "" && start > 0 {
+		links = append(links, pageLink(c, map[string]string{"start": strconv.Itoa(max(0, 2*start-end)), "end": strconv.Itoa(start)}), "prev")
+	}
+
+	if len(links) != 0 {
+		c.Links(links...)
+	}
+
+	if id != "" && len(r.Rows) == 1 {
+		c.Set(fiber.HeaderETag, etag(r.Rows[0].Version))
+	}
+
+	var total *int
+
+	if withTotal {
+		total = &r.Total
+	}
+
 	return c.JSON(&SelectResponse{
+		Code:       fiber.StatusOK,
+		Message:    SelectSuccess,
+		Result:     r.Rows,
+		Start:      start,
+		End:        start + len(r.Rows),
+		HasMore:    r.HasMore,
+		Total:      total,
+		NextCursor: r.NextCursor,
+	})
+}
+
+type HistoryByIDResponse struct {
+	Code    int                    `json:"code" example:"200"`
+	Message string                 `json:"message" example:"history found"`
+	Result  []storage.HistoryEntry `json:"result"`
+}
+
+// @description Возвращает историю изменений записи: операцию, автора (заголовок X-Actor), время и состояние записи до и после изменения.
+//
+// @id          history
+// @tags        История
+//
+// @summary     История изменений записи по ID
+// @produce     json
+// @param       id  path     string                      true "Идентификатор записи"
+// @success     200 {object} HistoryByIDResponse         "Возвращается, если получение прошло успешно"
+// @failure     400 {object} BadRequestResponse          "Возвращается, если запрос был сформирован неправильно"
+// @failure     404 {object} NotFoundResponse            "Возвращается, если запрашиваемая запись не была найдена"
+// @failure     405 {object} MethodNotAllowedResponse    "Возвращается, если был использован неправильный метод"
+// @failure     500 {object} InternalServerErrorResponse "Возвращается, если во время работы хранилища произошла ошибка"
+// @failure     504 {object} GatewayTimeoutResponse      "Возвращается, если операция не уложилась в отведенное время"
+// @router      /people/{id}/history [get]
+func (h Handlers) HistoryByID(c *fiber.Ctx) error {
+	const op = "effectivemobile.HistoryByID()"
+
+	id := c.Params("id")
+	if id == "" {
+		h.Log.Debug(
+			"отсутсвуют параметры",
+			slog.String("source", source),
+			slog.String("op", op),
+			slog.String("error", "absent parameters"),
+		)
+
+		return fiber.ErrBadRequest
+	}
+
+	h.Log.Debug(
+		"получен запрос на получение истории",
+		slog.String("source", source),
+		slog.String("op", op),
+		slog.Any("parameters", []string{id}),
+	)
+
+	r, err := h.Service.History(c.UserContext(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, effectivemobileservice.ErrStorageNotFound):
+			return fiber.ErrNotFound
+
+		case errors.Is(err, effectivemobileservice.ErrTimeout):
+			return fiber.ErrGatewayTimeout
+
+		default:
+			return fiber.ErrInternalServerError
+		}
+	}
+	h.Log.Debug(
+		"обработан запрос на получение истории",
+		slog.String("source", source),
+		slog.String("op", op),
+	)
+
+	return c.JSON(&HistoryByIDResponse{
 		Code:    fiber.StatusOK,
-		Message: SelectSuccess,
+		Message: HistoryByIDSuccess,
 		Result:  r,
 	})
 }
 
+type SnapshotByIDResponse struct {
+	Code    int         `json:"code" example:"200"`
+	Message string      `json:"message" example:"snapshot found"`
+	Result  storage.Row `json:"result"`
+}
+
+// @description Возвращает состояние записи на заданный момент времени, восстановленное по истории изменений.
+//
+// @id          snapshot
+// @tags        История
+//
+// @summary     Состояние записи на момент времени
+// @produce     json
+// @param       id    path     string                      true "Идентификатор записи"
+// @param       as_of query    string                      true "Момент времени в формате RFC 3339"
+// @success     200   {object} SnapshotByIDResponse        "Возвращается, если получение прошло успешно"
+// @failure     400   {object} BadRequestResponse          "Возвращается, если запрос был сформирован неправильно"
+// @failure     404   {object} NotFoundResponse            "Возвращается, если на заданный момент записи не существовало"
+// @failure     405   {object} MethodNotAllowedResponse    "Возвращается, если был использован неправильный метод"
+// @failure     500   {object} InternalServerErrorResponse "Возвращается, если во время работы хранилища произошла ошибка"
+// @failure     504   {object} GatewayTimeoutResponse      "Возвращается, если операция не уложилась в отведенное время"
+// @router      /people/{id}/snapshot [get]
+func (h Handlers) SnapshotByID(c *fiber.Ctx) error {
+	const op = "effectivemobile.SnapshotByID()"
+
+	id := c.Params("id")
+	if id == "" {
+		h.Log.Debug(
+			"отсутсвуют параметры",
+			slog.String("source", source),
+			slog.String("op", op),
+			slog.String("error", "absent parameters"),
+		)
+
+		return fiber.ErrBadRequest
+	}
+
+	asOf, err := time.Parse(time.RFC3339, c.Query("as_of"))
+	if err != nil {
+		h.Log.Debug(
+			"неправильно сформирован запрос",
+			slog.String("source", source),
+			slog.String("op", op),
+			slog.Any("error", err),
+		)
+
+		return fiber.ErrBadRequest
+	}
+
+	h.Log.Debug(
+		"получен запрос на получение состояния записи",
+		slog.String("source", source),
+		slog.String("op", op),
+		slog.Any("parameters", []interface{}{id, asOf}),
+	)
+
+	r, err := h.Service.SelectAsOf(c.UserContext(), id, asOf)
+	if err != nil {
+		switch {
+		case errors.Is(err, effectivemobileservice.ErrStorageNotFound):
+			return fiber.ErrNotFound
+
+		case errors.Is(err, effectivemobileservice.ErrTimeout):
+			return fiber.ErrGatewayTimeout
+
+		default:
+			return fiber.ErrInternalServerError
+		}
+	}
+	h.Log.Debug(
+		"обработан запрос на получение состояния записи",
+		slog.String("source", source),
+		slog.String("op", op),
+	)
+
+	return c.JSON(&SnapshotByIDResponse{
+		Code:    fiber.StatusOK,
+		Message: SnapshotByIDSuccess,
+		Result:  r,
+	})
+}
+
+type MetricsResponse struct {
+	Code    int          `json:"code" example:"200"`
+	Message string       `json:"message" example:"metrics collected"`
+	Result  client.Stats `json:"result"`
+}
+
+// @description Возвращает метрики клиентов обогащения: попадания и промахи кэша и состояние предохранителей по каждому провайдеру.
+//
+// @id          metrics
+// @tags        Администрирование
+//
+// @summary     Метрики сервиса
+// @produce     json
+// @success     200 {object} MetricsResponse          "Возвращается, если метрики собраны успешно"
+// @failure     405 {object} MethodNotAllowedResponse "Возвращается, если был использован неправильный метод"
+// @router      /metrics [get]
+func (h Handlers) Metrics(c *fiber.Ctx) error {
+	const op = "effectivemobile.Metrics()"
+
+	h.Log.Debug(
+		"получен запрос на получение метрик",
+		slog.String("source", source),
+		slog.String("op", op),
+	)
+
+	return c.JSON(&MetricsResponse{
+		Code:    fiber.StatusOK,
+		Message: MetricsSuccess,
+		Result:  h.Service.Stats(),
+	})
+}
+
+var (
+	HealthStatusOK       = "ok"
+	HealthStatusDegraded = "degraded"
+)
+
+type HealthResponse struct {
+	Code     int               `json:"code" example:"200"`
+	Message  string            `json:"message" example:"service is running"`
+	Status   string            `json:"status" example:"ok"`
+	Breakers map[string]string `json:"breakers"`
+}
+
+// @description Возвращает состояние сервиса и предохранителей внешних API.
+// @description Статус degraded означает, что хотя бы один предохранитель не замкнут и обогащение может быть недоступно.
+//
+// @id          health
+// @tags        Администрирование
+//
+// @summary     Состояние сервиса
+// @produce     json
+// @success     200 {object} HealthResponse           "Возвращается, если сервис работает"
+// @failure     405 {object} MethodNotAllowedResponse "Возвращается, если был использован неправильный метод"
+// @router      /health [get]
+func (h Handlers) Health(c *fiber.Ctx) error {
+	const op = "effectivemobile.Health()"
+
+	h.Log.Debug(
+		"получен запрос на проверку состояния",
+		slog.String("source", source),
+		slog.String("op", op),
+	)
+
+	breakers := h.Service.Stats().Breakers
+	if breakers == nil {
+		breakers = map[string]string{}
+	}
+
+	status := HealthStatusOK
+
+	for _, state := range breakers {
+		if state != breaker.StateClosed {
+			status = HealthStatusDegraded
+		}
+	}
+
+	return c.JSON(&HealthResponse{
+		Code:     fiber.StatusOK,
+		Message:  HealthSuccess,
+		Status:   status,
+		Breakers: breakers,
+	})
+}
+
 // МОКИ
 
 type UnimplementedHandlers struct{}
@@ -554,10 +2291,58 @@ func (u UnimplementedHandlers) UpdateByID(c *fiber.Ctx) error {
 	return nil
 }
 
+func (u UnimplementedHandlers) PatchByID(c *fiber.Ctx) error {
+	return nil
+}
+
+func (u UnimplementedHandlers) RestoreByID(c *fiber.Ctx) error {
+	return nil
+}
+
+func (u UnimplementedHandlers) Purge(c *fiber.Ctx) error {
+	return nil
+}
+
 func (u UnimplementedHandlers) Create(c *fiber.Ctx) error {
 	return nil
 }
 
+func (u UnimplementedHandlers) CreateBatch(c *fiber.Ctx) error {
+	return nil
+}
+
+func (u UnimplementedHandlers) ReenrichByID(c *fiber.Ctx) error {
+	return nil
+}
+
+func (u UnimplementedHandlers) Reenrich(c *fiber.Ctx) error {
+	return nil
+}
+
+func (u UnimplementedHandlers) Export(c *fiber.Ctx) error {
+	return nil
+}
+
+func (u UnimplementedHandlers) ExportFile(c *fiber.Ctx) error {
+	return nil
+}
+
 func (u UnimplementedHandlers) Select(c *fiber.Ctx) error {
 	return nil
 }
+
+func (u UnimplementedHandlers) HistoryByID(c *fiber.Ctx) error {
+	return nil
+}
+
+func (u UnimplementedHandlers) SnapshotByID(c *fiber.Ctx) error {
+	return nil
+}
+
+func (u UnimplementedHandlers) Metrics(c *fiber.Ctx) error {
+	return nil
+}
+
+func (u UnimplementedHandlers) Health(c *fiber.Ctx) error {
+	return nil
+}
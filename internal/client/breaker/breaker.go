@@ -0,0 +1,136 @@
+package breaker
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+var ErrOpen = fmt.Errorf("предохранитель разомкнут")
+
+var (
+	StateClosed   = "closed"
+	StateOpen     = "open"
+	StateHalfOpen = "half-open"
+)
+
+type Breaker struct {
+	mu       sync.Mutex
+	state    string
+	failures int
+	probes   int
+	openedAt time.Time
+
+	threshold int
+	cooldown  time.Duration
+	maxProbes int
+
+	onChange func(from string, to string)
+}
+
+func New(threshold int, cooldown time.Duration, maxProbes int, onChange func(from string, to string)) *Breaker {
+	return &Breaker{
+		state: StateClosed,
+
+		threshold: threshold,
+		cooldown:  cooldown,
+		maxProbes: max(1, maxProbes),
+
+		onChange: onChange,
+	}
+}
+
+func (b *Breaker) setState(state string) {
+	if b.state == state {
+		return
+	}
+
+	from := b.state
+
+	b.state = state
+	b.failures = 0
+	b.probes = 0
+
+	if state == StateOpen {
+		b.openedAt = time.Now()
+	}
+
+	if b.onChange != nil {
+		b.onChange(from, state)
+	}
+}
+
+func (b *Breaker) Allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.threshold <= 0 {
+		return nil
+	}
+
+	if b.state == StateOpen {
+		if time.Since(b.openedAt) < b.cooldown {
+			return ErrOpen
+		}
+		b.setState(StateHalfOpen)
+	}
+
+	if b.state == StateHalfOpen {
+		if b.probes >= b.maxProbes {
+			return ErrOpen
+		}
+		b.probes++
+	}
+
+	return nil
+}
+
+func (b *Breaker) Success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == StateHalfOpen {
+		b.setState(StateClosed)
+	}
+	b.failures = 0
+}
+
+func (b *Breaker) Failure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.threshold <= 0 {
+		return
+	}
+
+	if b.state == StateHalfOpen {
+		b.setState(StateOpen)
+
+		return
+	}
+
+	b.failures++
+
+	if b.failures >= b.threshold {
+		b.setState(StateOpen)
+	}
+}
+
+func (b *Breaker) Release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == StateHalfOpen && b.probes > 0 {
+		b.probes--
+	}
+}
+
+func (b *Breaker) State() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == StateOpen && time.Since(b.openedAt) >= b.cooldown {
+		return StateHalfOpen
+	}
+	return b.state
+}
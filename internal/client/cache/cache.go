@@ -0,0 +1,150 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	TypeNone       = "none"
+	TypeMemory     = "memory"
+	TypePostgreSQL = "postgresql"
+)
+
+type Backend interface {
+	Get(ctx context.Context, provider string, name string) (string, bool, error)
+	Set(ctx context.Context, provider string, name string, value string) error
+}
+
+type Stats struct {
+	Hits   int64 `json:"hits" example:"10"`
+	Misses int64 `json:"misses" example:"2"`
+}
+
+func Key(provider string, name string) string {
+	return provider + ":" + Normalize(name)
+}
+
+func Normalize(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+type entry struct {
+	key       string
+	value     string
+	expiresAt time.Time
+}
+
+type LRU struct {
+	mu    sync.Mutex
+	items map[string]*list.Element
+	order *list.List
+	size  int
+	ttl   time.Duration
+}
+
+func NewLRU(size int, ttl time.Duration) *LRU {
+	return &LRU{
+		items: map[string]*list.Element{},
+		order: list.New(),
+		size:  size,
+		ttl:   ttl,
+	}
+}
+
+func (l *LRU) Get(ctx context.Context, provider string, name string) (string, bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	e, ok := l.items[Key(provider, name)]
+	if !ok {
+		return "", false, nil
+	}
+
+	en := e.Value.(*entry)
+
+	if time.Now().After(en.expiresAt) {
+		l.order.Remove(e)
+		delete(l.items, en.key)
+
+		return "", false, nil
+	}
+	l.order.MoveToFront(e)
+
+	return en.value, true, nil
+}
+
+func (l *LRU) Set(ctx context.Context, provider string, name string, value string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	key := Key(provider, name)
+	expiresAt := time.Now().Add(l.ttl)
+
+	if e, ok := l.items[key]; ok {
+		en := e.Value.(*entry)
+		en.value = value
+		en.expiresAt = expiresAt
+
+		l.order.MoveToFront(e)
+
+		return nil
+	}
+
+	l.items[key] = l.order.PushFront(&entry{
+		key:       key,
+		value:     value,
+		expiresAt: expiresAt,
+	})
+
+	for l.size > 0 && l.order.Len() > l.size {
+		last := l.order.Back()
+
+		l.order.Remove(last)
+		delete(l.items, last.Value.(*entry).key)
+	}
+
+	return nil
+}
+
+type PostgreSQL struct {
+	DB *sql.DB
+
+	table string
+	ttl   time.Duration
+}
+
+func NewPostgreSQL(db *sql.DB, table string, ttl time.Duration) *PostgreSQL {
+	return &PostgreSQL{
+		DB: db,
+
+		table: table,
+		ttl:   ttl,
+	}
+}
+
+func (p *PostgreSQL) Get(ctx context.Context, provider string, name string) (string, bool, error) {
+	var value string
+
+	err := p.DB.QueryRowContext(ctx, fmt.Sprintf("SELECT value FROM %s WHERE provider=$1 AND name=$2 AND expires_at > now();", p.table), provider, Normalize(name)).Scan(&value)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", false, nil
+		}
+		return "", false, err
+	}
+	return value, true, nil
+}
+
+func (p *PostgreSQL) Set(ctx context.Context, provider string, name string, value string) error {
+	query := fmt.Sprintf("INSERT INTO %s (provider, name, value, expires_at) VALUES($1, $2, $3, $4) ON CONFLICT (provider, name) DO UPDATE SET value=EXCLUDED.value, expires_at=EXCLUDED.expires_at;", p.table)
+
+	_, err := p.DB.ExecContext(ctx, query, provider, Normalize(name), value, time.Now().Add(p.ttl))
+	return err
+}
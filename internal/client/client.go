@@ -1,22 +1,70 @@
 package client
 
 import (
+	"cmp"
+	"context"
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
+	"maps"
+	"math/rand/v2"
+	"net"
 	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
 
+	"github.com/xoticdsign/effectivemobile/internal/client/breaker"
+	"github.com/xoticdsign/effectivemobile/internal/client/cache"
+	"github.com/xoticdsign/effectivemobile/internal/client/provider"
+	"github.com/xoticdsign/effectivemobile/internal/client/quota"
 	"github.com/xoticdsign/effectivemobile/internal/utils/config"
 )
 
 var (
 	ErrNotFound = fmt.Errorf("клиент ничего не нашел")
 	ErrInternal = fmt.Errorf("внутренняя ошибка")
+
+	ErrUnavailable = fmt.Errorf("провайдер недоступен")
+	ErrCircuitOpen = breaker.ErrOpen
+	ErrRateLimited = fmt.Errorf("превышен лимит запросов к провайдеру")
+
+	ErrUnauthorized    = fmt.Errorf("провайдер отклонил API-ключ")
+	ErrPaymentRequired = fmt.Errorf("подписка провайдера исчерпана")
+
+	ErrDisabled = fmt.Errorf("провайдер отключен")
 )
 
+type RateLimitError struct {
+	Provider   string
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("%v: %s", ErrRateLimited, e.Provider)
+}
+
+func (e *RateLimitError) Unwrap() error {
+	return ErrRateLimited
+}
+
 const source = "client"
 
+const (
+	ProviderAgify       = provider.NameAgify
+	ProviderGenderize   = provider.NameGenderize
+	ProviderNationalize = provider.NameNationalize
+)
+
+const MaxBatchSize = 10
+
 type Client struct {
 	C C
 
@@ -25,9 +73,19 @@ type Client struct {
 }
 
 type Handlerer interface {
-	GetAge(name string) (int, error)
-	GetGender(name string) (string, error)
-	GetNationality(name string) (string, error)
+	GetAge(ctx context.Context, name string, countryID string) (Age, error)
+	GetGender(ctx context.Context, name string, countryID string) (Gender, error)
+	GetNationality(ctx context.Context, name string) (Nationality, error)
+	GetAges(ctx context.Context, names []string, countryID string) (map[string]Age, error)
+	GetGenders(ctx context.Context, names []string, countryID string) (map[string]Gender, error)
+	GetNationalities(ctx context.Context, names []string) (map[string]Nationality, error)
+	Stats() Stats
+}
+
+type Stats struct {
+	Cache    map[string]cache.Stats `json:"cache,omitempty"`
+	Breakers map[string]string      `json:"breakers,omitempty"`
+	Quota    map[string]quota.Quota `json:"quota,omitempty"`
 }
 
 type C struct {
@@ -35,20 +93,110 @@ type C struct {
 	Handlers        Handlerer
 }
 
-func New(config config.EffectiveMobileConfig, log *slog.Logger) *Client {
-	client := http.Client{
-		Timeout: config.Client.Timeout,
+func New(config config.EffectiveMobileConfig, db *sql.DB, log *slog.Logger) *Client {
+	const op = "client.New()"
+
+	client := http.Client{}
+
+	endpoints := map[string]endpoint{}
+	breakers := map[string]*breaker.Breaker{}
+	quotas := map[string]*quota.Tracker{}
+
+	kinds := map[string]string{
+		provider.KindAge:         config.Client.AgeProvider,
+		provider.KindGender:      config.Client.GenderProvider,
+		provider.KindNationality: config.Client.NationalityProvider,
+	}
+
+	for kind, name := range kinds {
+		e, err := newEndpoint(kind, name, config.Client)
+		if err != nil {
+			log.Error(
+				"не удалось подключить провайдера, обогащение отключено",
+				slog.String("source", source),
+				slog.String("op", op),
+				slog.String("kind", kind),
+				slog.String("provider", name),
+				slog.Any("error", err),
+			)
+
+			continue
+		}
+
+		if !e.config.Enabled {
+			log.Info(
+				"провайдер отключен, обогащение не выполняется",
+				slog.String("source", source),
+				slog.String("op", op),
+				slog.String("kind", kind),
+				slog.String("provider", name),
+			)
+
+			continue
+		}
+		endpoints[kind] = e
+
+		p := e.provider.Name()
+
+		_, ok := breakers[p]
+		if ok {
+			continue
+		}
+
+		quotas[p] = quota.New(config.Client.QuotaReserve)
+
+		breakers[p] = breaker.New(config.Client.BreakerThreshold, config.Client.BreakerCooldown, config.Client.BreakerProbes, func(from string, to string) {
+			level := slog.LevelInfo
+			if to == breaker.StateOpen {
+				level = slog.LevelError
+			}
+
+			log.Log(
+				context.Background(),
+				level,
+				"состояние предохранителя провайдера изменилось",
+				slog.String("source", source),
+				slog.String("op", op),
+				slog.String("provider", p),
+				slog.String("from", from),
+				slog.String("to", to),
+			)
+		})
+	}
+
+	var h Handlerer = handlers{
+		Client: client,
+
+		endpoints: endpoints,
+		breakers:  breakers,
+		quotas:    quotas,
+
+		log:    log,
+		config: config,
+	}
+
+	switch config.Client.CacheType {
+	case cache.TypeMemory:
+		h = newCachedHandlers(h, endpoints, cache.NewLRU(config.Client.CacheSize, config.Client.CacheTTL), log)
+
+	case cache.TypePostgreSQL:
+		h = newCachedHandlers(h, endpoints, cache.NewPostgreSQL(db, config.Client.CacheTable, config.Client.CacheTTL), log)
+
+	case cache.TypeNone:
+
+	default:
+		log.Error(
+			"неизвестный тип кэша, кэширование отключено",
+			slog.String("source", source),
+			slog.String("op", op),
+			slog.String("cache", config.Client.CacheType),
+		)
 	}
 
 	return &Client{
 		C: C{
 			Implementations: client,
-			Handlers: handlers{
-				Client: client,
-
-				log:    log,
-				config: config,
-			},
+			Handlers:        h,
 		},
 	}
 }
@@ -62,57 +210,407 @@ type handlers struct {
 
 	Client http.Client
 
+	endpoints map[string]endpoint
+	breakers  map[string]*breaker.Breaker
+	quotas    map[string]*quota.Tracker
+
 	log    *slog.Logger
 	config config.EffectiveMobileConfig
 }
 
-type GetAgeResponse struct {
-	Count int    `json:"count"`
-	Name  string `json:"name"`
-	Age   int    `json:"age"`
+type endpoint struct {
+	provider provider.Provider
+	config   config.ProviderConfig
 }
 
-func (h handlers) GetAge(name string) (int, error) {
-	const op = "client.GetAge()"
+func newEndpoint(kind string, name string, c config.ClientConfig) (endpoint, error) {
+	p, err := provider.Default.Get(name)
+	if err != nil {
+		return endpoint{}, err
+	}
 
-	h.log.Debug(
-		"данные получены клиентом",
-		slog.String("source", source),
-		slog.String("op", op),
-	)
+	if p.Kind() != kind {
+		return endpoint{}, fmt.Errorf("%w: %s не определяет %s", provider.ErrUnknown, name, kind)
+	}
+
+	pc, ok := c.Providers[name]
+	if !ok {
+		return endpoint{}, fmt.Errorf("%w: нет настроек для %s", provider.ErrUnknown, name)
+	}
+
+	if pc.Timeout <= 0 {
+		pc.Timeout = c.Timeout
+	}
+
+	pc.Key, err = pc.LoadKey()
+	if err != nil {
+		return endpoint{}, fmt.Errorf("не удалось прочитать API-ключ %s: %v", name, err)
+	}
+
+	return endpoint{
+		provider: p,
+		config:   pc,
+	}, nil
+}
+
+func isTransient(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+
+	var e net.Error
+
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return true
+
+	case errors.Is(err, syscall.ECONNRESET), errors.Is(err, syscall.ECONNREFUSED):
+		return true
+
+	case errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		return true
+
+	case errors.As(err, &e) && e.Timeout():
+		return true
+	}
+	return false
+}
+
+func parseRetryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+
+	seconds, err := strconv.Atoi(value)
+	if err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	date, err := http.ParseTime(value)
+	if err != nil {
+		return 0, false
+	}
+	return max(0, time.Until(date)), true
+}
+
+func backoff(attempt int, base time.Duration, limit time.Duration) time.Duration {
+	d := base << attempt
+	if d <= 0 || d > limit {
+		d = limit
+	}
+	return d/2 + rand.N(d/2+1)
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+
+	select {
+	case <-ctx.Done():
+		timer.Stop()
+
+		return ctx.Err()
+
+	case <-timer.C:
+		return nil
+	}
+}
+
+func redact(err error, key config.Secret) error {
+	var e *url.Error
+
+	if key != "" && errors.As(err, &e) {
+		e.URL = strings.ReplaceAll(e.URL, url.QueryEscape(string(key)), key.String())
+	}
+	return err
+}
 
-	r, err := http.NewRequest(http.MethodGet, "https://api.agify.io/?name="+name, nil)
+func (h handlers) fetch(ctx context.Context, e endpoint, target string) (int, http.Header, []byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, e.config.Timeout)
+	defer cancel()
+
+	r, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
 	if err != nil {
-		return 0, err
+		return 0, nil, nil, redact(err, e.config.Key)
 	}
 
 	resp, err := h.Client.Do(r)
 	if err != nil {
-		return 0, err
+		return 0, nil, nil, redact(err, e.config.Key)
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		if resp.StatusCode == http.StatusNotFound {
-			return 0, ErrNotFound
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, nil, nil, err
+	}
+
+	return resp.StatusCode, resp.Header, body, nil
+}
+
+func (h handlers) get(ctx context.Context, e endpoint, target string) ([]byte, error) {
+	const op = "client.get()"
+
+	provider := e.provider.Name()
+
+	wait := h.quotas[provider].Acquire()
+	if wait > 0 {
+		if wait > h.config.Client.QuotaMaxWait {
+			return nil, &RateLimitError{Provider: provider, RetryAfter: wait}
+		}
+
+		h.log.Debug(
+			"квота провайдера почти исчерпана, запрос ожидает сброса лимита",
+			slog.String("source", source),
+			slog.String("op", op),
+			slog.String("provider", provider),
+			slog.Duration("wait", wait),
+		)
+
+		err := sleep(ctx, wait)
+		if err != nil {
+			return nil, err
 		}
-		return 0, ErrInternal
 	}
 
-	body, err := io.ReadAll(resp.Body)
+	b := h.breakers[provider]
+
+	err := b.Allow()
 	if err != nil {
-		return 0, err
+		return nil, fmt.Errorf("%w: %s", ErrCircuitOpen, provider)
+	}
+
+	body, err := h.retry(ctx, e, target)
+
+	switch {
+	case err == nil, errors.Is(err, ErrNotFound), errors.Is(err, ErrInternal), errors.Is(err, ErrRateLimited), errors.Is(err, ErrUnauthorized), errors.Is(err, ErrPaymentRequired):
+		b.Success()
+
+	case ctx.Err() != nil:
+		b.Release()
+
+	default:
+		b.Failure()
+	}
+
+	return body, err
+}
+
+func (h handlers) retry(ctx context.Context, e endpoint, target string) ([]byte, error) {
+	const op = "client.retry()"
+
+	provider := e.provider.Name()
+
+	for attempt := 0; ; attempt++ {
+		status, header, body, err := h.fetch(ctx, e, target)
+
+		if err == nil {
+			h.quotas[provider].Update(header)
+		}
+
+		var wait time.Duration
+
+		switch {
+		case err != nil:
+			if attempt >= h.config.Client.Retries || !isTransient(ctx, err) {
+				return nil, err
+			}
+			wait = backoff(attempt, h.config.Client.RetryBaseDelay, h.config.Client.RetryMaxDelay)
+
+		case status == http.StatusOK:
+			return body, nil
+
+		case status == http.StatusNotFound:
+			return nil, ErrNotFound
+
+		case status == http.StatusUnauthorized:
+			return nil, fmt.Errorf("%w: %s", ErrUnauthorized, provider)
+
+		case status == http.StatusPaymentRequired:
+			return nil, fmt.Errorf("%w: %s", ErrPaymentRequired, provider)
+
+		case status == http.StatusTooManyRequests:
+			retryAfter, ok := parseRetryAfter(header.Get("Retry-After"))
+			if !ok {
+				q, _ := h.quotas[provider].Quota()
+				retryAfter = max(0, time.Until(q.ResetAt))
+			}
+			h.quotas[provider].Exhaust(retryAfter)
+
+			return nil, &RateLimitError{Provider: provider, RetryAfter: retryAfter}
+
+		case status >= http.StatusInternalServerError:
+			if attempt >= h.config.Client.Retries {
+				return nil, fmt.Errorf("%w: %d", ErrUnavailable, status)
+			}
+			wait = backoff(attempt, h.config.Client.RetryBaseDelay, h.config.Client.RetryMaxDelay)
+
+			retryAfter, ok := parseRetryAfter(header.Get("Retry-After"))
+			if ok {
+				if retryAfter > h.config.Client.RetryMaxDelay {
+					return nil, fmt.Errorf("%w: %d", ErrUnavailable, status)
+				}
+				wait = retryAfter
+			}
+
+		default:
+			return nil, ErrInternal
+		}
+
+		h.log.Debug(
+			"повторная попытка запроса к провайдеру",
+			slog.String("source", source),
+			slog.String("op", op),
+			slog.String("provider", provider),
+			slog.Int("attempt", attempt+1),
+			slog.Int("status", status),
+			slog.Duration("wait", wait),
+			slog.Any("error", err),
+		)
+
+		err = sleep(ctx, wait)
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+type Age struct {
+	Age   int `json:"age"`
+	Count int `json:"count"`
+}
+
+type Gender struct {
+	Gender      string  `json:"gender"`
+	Probability float64 `json:"probability"`
+	Count       int     `json:"count"`
+}
+
+type Nationality struct {
+	Nationality string    `json:"nationality"`
+	Probability float64   `json:"probability"`
+	Count       int       `json:"count"`
+	Candidates  []Country `json:"candidates"`
+}
+
+type Country = provider.Country
+
+func age(e provider.Estimate) Age {
+	return Age{
+		Age:   e.Age,
+		Count: e.Count,
+	}
+}
+
+func gender(e provider.Estimate) Gender {
+	return Gender{
+		Gender:      e.Gender,
+		Probability: e.Probability,
+		Count:       e.Count,
+	}
+}
+
+func rankNationalities(countries []Country, n int) []Country {
+	ranked := slices.Clone(countries)
+
+	slices.SortStableFunc(ranked, func(a Country, b Country) int {
+		return cmp.Compare(b.Probability, a.Probability)
+	})
+
+	if n > 0 && len(ranked) > n {
+		ranked = ranked[:n]
+	}
+
+	return ranked
+}
+
+func nationality(e provider.Estimate, candidates int) Nationality {
+	n := Nationality{
+		Count:      e.Count,
+		Candidates: rankNationalities(e.Countries, candidates),
+	}
+
+	if len(n.Candidates) > 0 {
+		n.Nationality = n.Candidates[0].CountryID
+		n.Probability = n.Candidates[0].Probability
+	}
+
+	return n
+}
+
+func chunk(names []string) [][]string {
+	var (
+		chunks [][]string
+		c      []string
+	)
+
+	seen := map[string]struct{}{}
+
+	for _, n := range names {
+		_, ok := seen[n]
+		if ok {
+			continue
+		}
+		seen[n] = struct{}{}
+
+		c = append(c, n)
+
+		if len(c) == MaxBatchSize {
+			chunks = append(chunks, c)
+			c = nil
+		}
+	}
+
+	if len(c) > 0 {
+		chunks = append(chunks, c)
+	}
+
+	return chunks
+}
+
+func (h handlers) estimate(ctx context.Context, kind string, names []string, countryID string) (map[string]provider.Estimate, error) {
+	estimates := map[string]provider.Estimate{}
+
+	e, ok := h.endpoints[kind]
+	if !ok {
+		return nil, ErrDisabled
+	}
+
+	for _, c := range chunk(names) {
+		body, err := h.get(ctx, e, e.provider.Target(e.config.URL, c, countryID, string(e.config.Key)))
+		if err != nil {
+			return nil, err
+		}
+
+		decoded, err := e.provider.Decode(c, body)
+		if err != nil {
+			return nil, err
+		}
+		maps.Copy(estimates, decoded)
 	}
 
-	var b GetAgeResponse
+	return estimates, nil
+}
+
+func (h handlers) GetAge(ctx context.Context, name string, countryID string) (Age, error) {
+	const op = "client.GetAge()"
+
+	h.log.Debug(
+		"данные получены клиентом",
+		slog.String("source", source),
+		slog.String("op", op),
+	)
 
-	err = json.Unmarshal(body, &b)
+	estimates, err := h.estimate(ctx, provider.KindAge, []string{name}, countryID)
 	if err != nil {
-		return 0, err
+		return Age{}, err
 	}
 
-	if b.Count == 0 {
-		return 0, ErrNotFound
+	e, ok := estimates[name]
+	if !ok {
+		return Age{}, ErrNotFound
 	}
 
 	h.log.Debug(
@@ -121,17 +619,10 @@ func (h handlers) GetAge(name string) (int, error) {
 		slog.String("op", op),
 	)
 
-	return b.Age, nil
+	return age(e), nil
 }
 
-type GetGenderResponse struct {
-	Count       int     `json:"count"`
-	Name        string  `json:"name"`
-	Gender      string  `json:"gender"`
-	Probability float64 `json:"probability"`
-}
-
-func (h handlers) GetGender(name string) (string, error) {
+func (h handlers) GetGender(ctx context.Context, name string, countryID string) (Gender, error) {
 	const op = "client.GetGender()"
 
 	h.log.Debug(
@@ -140,38 +631,42 @@ func (h handlers) GetGender(name string) (string, error) {
 		slog.String("op", op),
 	)
 
-	r, err := http.NewRequest(http.MethodGet, "https://api.genderize.io/?name="+name, nil)
+	estimates, err := h.estimate(ctx, provider.KindGender, []string{name}, countryID)
 	if err != nil {
-		return "", err
+		return Gender{}, err
 	}
 
-	resp, err := h.Client.Do(r)
-	if err != nil {
-		return "", err
+	e, ok := estimates[name]
+	if !ok {
+		return Gender{}, ErrNotFound
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		if resp.StatusCode == http.StatusNotFound {
-			return "", ErrNotFound
-		}
-		return "", ErrInternal
-	}
+	h.log.Debug(
+		"данные обработаны клиентом",
+		slog.String("source", source),
+		slog.String("op", op),
+	)
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return "", err
-	}
+	return gender(e), nil
+}
 
-	var b GetGenderResponse
+func (h handlers) GetNationality(ctx context.Context, name string) (Nationality, error) {
+	const op = "client.GetNationality()"
+
+	h.log.Debug(
+		"данные получены клиентом",
+		slog.String("source", source),
+		slog.String("op", op),
+	)
 
-	err = json.Unmarshal(body, &b)
+	estimates, err := h.estimate(ctx, provider.KindNationality, []string{name}, "")
 	if err != nil {
-		return "", err
+		return Nationality{}, err
 	}
 
-	if b.Count == 0 {
-		return "", ErrNotFound
+	e, ok := estimates[name]
+	if !ok {
+		return Nationality{}, ErrNotFound
 	}
 
 	h.log.Debug(
@@ -180,38 +675,69 @@ func (h handlers) GetGender(name string) (string, error) {
 		slog.String("op", op),
 	)
 
-	return b.Gender, nil
+	return nationality(e, h.config.Client.Candidates), nil
 }
 
-type GetNationalityResponse struct {
-	Count   int       `json:"count"`
-	Name    string    `json:"name"`
-	Country []Country `json:"country"`
-}
+func (h handlers) GetAges(ctx context.Context, names []string, countryID string) (map[string]Age, error) {
+	const op = "client.GetAges()"
 
-type Country struct {
-	CountryID   string  `json:"country_id"`
-	Probability float64 `json:"probability"`
+	h.log.Debug(
+		"данные получены клиентом",
+		slog.String("source", source),
+		slog.String("op", op),
+	)
+
+	estimates, err := h.estimate(ctx, provider.KindAge, names, countryID)
+	if err != nil {
+		return nil, err
+	}
+
+	ages := map[string]Age{}
+
+	for n, e := range estimates {
+		ages[n] = age(e)
+	}
+
+	h.log.Debug(
+		"данные обработаны клиентом",
+		slog.String("source", source),
+		slog.String("op", op),
+	)
+
+	return ages, nil
 }
 
-func findMostProbableNationality(countries []Country) string {
-	var (
-		country string
-		p       float64 = -1
+func (h handlers) GetGenders(ctx context.Context, names []string, countryID string) (map[string]Gender, error) {
+	const op = "client.GetGenders()"
+
+	h.log.Debug(
+		"данные получены клиентом",
+		slog.String("source", source),
+		slog.String("op", op),
 	)
 
-	for _, c := range countries {
-		if c.Probability > p {
-			p = c.Probability
-			country = c.CountryID
-		}
+	estimates, err := h.estimate(ctx, provider.KindGender, names, countryID)
+	if err != nil {
+		return nil, err
 	}
 
-	return country
+	genders := map[string]Gender{}
+
+	for n, e := range estimates {
+		genders[n] = gender(e)
+	}
+
+	h.log.Debug(
+		"данные обработаны клиентом",
+		slog.String("source", source),
+		slog.String("op", op),
+	)
+
+	return genders, nil
 }
 
-func (h handlers) GetNationality(name string) (string, error) {
-	const op = "client.GetNationality()"
+func (h handlers) GetNationalities(ctx context.Context, names []string) (map[string]Nationality, error) {
+	const op = "client.GetNationalities()"
 
 	h.log.Debug(
 		"данные получены клиентом",
@@ -219,61 +745,291 @@ func (h handlers) GetNationality(name string) (string, error) {
 		slog.String("op", op),
 	)
 
-	r, err := http.NewRequest(http.MethodGet, "https://api.nationalize.io/?name="+name, nil)
+	estimates, err := h.estimate(ctx, provider.KindNationality, names, "")
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	resp, err := h.Client.Do(r)
+	nationalities := map[string]Nationality{}
+
+	for n, e := range estimates {
+		nationalities[n] = nationality(e, h.config.Client.Candidates)
+	}
+
+	h.log.Debug(
+		"данные обработаны клиентом",
+		slog.String("source", source),
+		slog.String("op", op),
+	)
+
+	return nationalities, nil
+}
+
+func (h handlers) Stats() Stats {
+	s := Stats{
+		Breakers: map[string]string{},
+		Quota:    map[string]quota.Quota{},
+	}
+
+	for p, b := range h.breakers {
+		s.Breakers[p] = b.State()
+	}
+
+	for p, t := range h.quotas {
+		q, ok := t.Quota()
+		if ok {
+			s.Quota[p] = q
+		}
+	}
+
+	return s
+}
+
+type cachedHandlers struct {
+	Handlerer
+
+	providers map[string]string
+	backend   cache.Backend
+	hits      map[string]*atomic.Int64
+	misses    map[string]*atomic.Int64
+
+	log *slog.Logger
+}
+
+func newCachedHandlers(next Handlerer, endpoints map[string]endpoint, backend cache.Backend, log *slog.Logger) cachedHandlers {
+	h := cachedHandlers{
+		Handlerer: next,
+
+		providers: map[string]string{},
+		backend:   backend,
+		hits:      map[string]*atomic.Int64{},
+		misses:    map[string]*atomic.Int64{},
+
+		log: log,
+	}
+
+	for kind, e := range endpoints {
+		p := e.provider.Name()
+
+		h.providers[kind] = p
+		h.hits[p] = &atomic.Int64{}
+		h.misses[p] = &atomic.Int64{}
+	}
+
+	return h
+}
+
+type bypassKey struct{}
+
+func WithoutCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, bypassKey{}, true)
+}
+
+func bypass(ctx context.Context) bool {
+	b, _ := ctx.Value(bypassKey{}).(bool)
+	return b
+}
+
+func scope(provider string, countryID string) string {
+	if countryID == "" {
+		return provider
+	}
+	return provider + "@" + strings.ToUpper(countryID)
+}
+
+func (h cachedHandlers) lookup(ctx context.Context, provider string, countryID string, name string) (string, bool) {
+	const op = "client.lookup()"
+
+	if bypass(ctx) {
+		return "", false
+	}
+
+	value, ok, err := h.backend.Get(ctx, scope(provider, countryID), name)
 	if err != nil {
-		return "", err
+		h.log.Error(
+			"не удалось прочитать кэш",
+			slog.String("source", source),
+			slog.String("op", op),
+			slog.String("provider", provider),
+			slog.Any("error", err),
+		)
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		if resp.StatusCode == http.StatusNotFound {
-			return "", ErrNotFound
+	if !ok {
+		h.misses[provider].Add(1)
+
+		return "", false
+	}
+	h.hits[provider].Add(1)
+
+	return value, true
+}
+
+func (h cachedHandlers) store(ctx context.Context, provider string, countryID string, name string, value string) {
+	const op = "client.store()"
+
+	err := h.backend.Set(ctx, scope(provider, countryID), name, value)
+	if err != nil {
+		h.log.Error(
+			"не удалось записать кэш",
+			slog.String("source", source),
+			slog.String("op", op),
+			slog.String("provider", provider),
+			slog.Any("error", err),
+		)
+	}
+}
+
+func cachedGet[T any](ctx context.Context, h cachedHandlers, kind string, countryID string, name string, fetch func() (T, error)) (T, error) {
+	provider, ok := h.providers[kind]
+	if !ok {
+		return fetch()
+	}
+
+	value, ok := h.lookup(ctx, provider, countryID, name)
+	if ok {
+		var v T
+
+		err := json.Unmarshal([]byte(value), &v)
+		if err == nil {
+			return v, nil
 		}
-		return "", ErrInternal
 	}
 
-	body, err := io.ReadAll(resp.Body)
+	v, err := fetch()
 	if err != nil {
-		return "", err
+		return v, err
+	}
+
+	b, err := json.Marshal(v)
+	if err == nil {
+		h.store(ctx, provider, countryID, name, string(b))
+	}
+
+	return v, nil
+}
+
+func cachedGetBatch[T any](ctx context.Context, h cachedHandlers, kind string, countryID string, names []string, fetch func(names []string) (map[string]T, error)) (map[string]T, error) {
+	provider, ok := h.providers[kind]
+	if !ok {
+		return fetch(names)
 	}
 
-	var b GetNationalityResponse
+	values := map[string]T{}
+
+	var missing []string
+
+	for _, n := range names {
+		value, ok := h.lookup(ctx, provider, countryID, n)
+		if ok {
+			var v T
 
-	err = json.Unmarshal(body, &b)
+			err := json.Unmarshal([]byte(value), &v)
+			if err == nil {
+				values[n] = v
+
+				continue
+			}
+		}
+		missing = append(missing, n)
+	}
+
+	if len(missing) == 0 {
+		return values, nil
+	}
+
+	fetched, err := fetch(missing)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	if b.Count == 0 {
-		return "", ErrNotFound
+	for n, v := range fetched {
+		b, err := json.Marshal(v)
+		if err == nil {
+			h.store(ctx, provider, countryID, n, string(b))
+		}
+		values[n] = v
 	}
 
-	nationality := findMostProbableNationality(b.Country)
+	return values, nil
+}
 
-	h.log.Debug(
-		"данные обработаны клиентом",
-		slog.String("source", source),
-		slog.String("op", op),
-	)
+func (h cachedHandlers) GetAge(ctx context.Context, name string, countryID string) (Age, error) {
+	return cachedGet(ctx, h, provider.KindAge, countryID, name, func() (Age, error) {
+		return h.Handlerer.GetAge(ctx, name, countryID)
+	})
+}
 
-	return nationality, nil
+func (h cachedHandlers) GetGender(ctx context.Context, name string, countryID string) (Gender, error) {
+	return cachedGet(ctx, h, provider.KindGender, countryID, name, func() (Gender, error) {
+		return h.Handlerer.GetGender(ctx, name, countryID)
+	})
+}
+
+func (h cachedHandlers) GetNationality(ctx context.Context, name string) (Nationality, error) {
+	return cachedGet(ctx, h, provider.KindNationality, "", name, func() (Nationality, error) {
+		return h.Handlerer.GetNationality(ctx, name)
+	})
+}
+
+func (h cachedHandlers) GetAges(ctx context.Context, names []string, countryID string) (map[string]Age, error) {
+	return cachedGetBatch(ctx, h, provider.KindAge, countryID, names, func(names []string) (map[string]Age, error) {
+		return h.Handlerer.GetAges(ctx, names, countryID)
+	})
+}
+
+func (h cachedHandlers) GetGenders(ctx context.Context, names []string, countryID string) (map[string]Gender, error) {
+	return cachedGetBatch(ctx, h, provider.KindGender, countryID, names, func(names []string) (map[string]Gender, error) {
+		return h.Handlerer.GetGenders(ctx, names, countryID)
+	})
+}
+
+func (h cachedHandlers) GetNationalities(ctx context.Context, names []string) (map[string]Nationality, error) {
+	return cachedGetBatch(ctx, h, provider.KindNationality, "", names, func(names []string) (map[string]Nationality, error) {
+		return h.Handlerer.GetNationalities(ctx, names)
+	})
+}
+
+func (h cachedHandlers) Stats() Stats {
+	s := h.Handlerer.Stats()
+	s.Cache = map[string]cache.Stats{}
+
+	for _, p := range h.providers {
+		s.Cache[p] = cache.Stats{
+			Hits:   h.hits[p].Load(),
+			Misses: h.misses[p].Load(),
+		}
+	}
+
+	return s
 }
 
 type UnimplementedHandlers struct{}
 
-func (u UnimplementedHandlers) GetAge(name string) (int, error) {
-	return 0, nil
+func (u UnimplementedHandlers) GetAge(ctx context.Context, name string, countryID string) (Age, error) {
+	return Age{}, nil
+}
+
+func (u UnimplementedHandlers) GetGender(ctx context.Context, name string, countryID string) (Gender, error) {
+	return Gender{}, nil
+}
+
+func (u UnimplementedHandlers) GetNationality(ctx context.Context, name string) (Nationality, error) {
+	return Nationality{}, nil
+}
+
+func (u UnimplementedHandlers) GetAges(ctx context.Context, names []string, countryID string) (map[string]Age, error) {
+	return map[string]Age{}, nil
+}
+
+func (u UnimplementedHandlers) GetGenders(ctx context.Context, names []string, countryID string) (map[string]Gender, error) {
+	return map[string]Gender{}, nil
 }
 
-func (u UnimplementedHandlers) GetGender(name string) (string, error) {
-	return "", nil
+func (u UnimplementedHandlers) GetNationalities(ctx context.Context, names []string) (map[string]Nationality, error) {
+	return map[string]Nationality{}, nil
 }
 
-func (u UnimplementedHandlers) GetNationality(name string) (string, error) {
-	return "", nil
+func (u UnimplementedHandlers) Stats() Stats {
+	return Stats{}
 }
@@ -0,0 +1,347 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/xoticdsign/effectivemobile/internal/client/provider"
+	"github.com/xoticdsign/effectivemobile/internal/client/quota"
+	"github.com/xoticdsign/effectivemobile/internal/utils/config"
+)
+
+func TestParseRetryAfter_Unit(t *testing.T) {
+	cases := []struct {
+		name          string
+		inValue       string
+		expectedMin   time.Duration
+		expectedMax   time.Duration
+		expectedValid bool
+	}{
+		{
+			name:          "empty case",
+			inValue:       "",
+			expectedValid: false,
+		},
+		{
+			name:          "seconds case",
+			inValue:       "5",
+			expectedMin:   5 * time.Second,
+			expectedMax:   5 * time.Second,
+			expectedValid: true,
+		},
+		{
+			name:          "zero seconds case",
+			inValue:       "0",
+			expectedValid: true,
+		},
+		{
+			name:          "negative seconds case",
+			inValue:       "-1",
+			expectedValid: false,
+		},
+		{
+			name:          "garbage case",
+			inValue:       "soon",
+			expectedValid: false,
+		},
+		{
+			name:          "future date case",
+			inValue:       time.Now().Add(30 * time.Second).UTC().Format(http.TimeFormat),
+			expectedMin:   28 * time.Second,
+			expectedMax:   30 * time.Second,
+			expectedValid: true,
+		},
+		{
+			name:          "past date case",
+			inValue:       time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat),
+			expectedValid: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			d, ok := parseRetryAfter(c.inValue)
+
+			assert.Equal(t, c.expectedValid, ok)
+			assert.GreaterOrEqual(t, d, c.expectedMin)
+			assert.LessOrEqual(t, d, c.expectedMax)
+		})
+	}
+}
+
+func TestBackoff_Unit(t *testing.T) {
+	cases := []struct {
+		name        string
+		inAttempt   int
+		inBase      time.Duration
+		inLimit     time.Duration
+		expectedCap time.Duration
+	}{
+		{
+			name:        "first attempt case",
+			inAttempt:   0,
+			inBase:      100 * time.Millisecond,
+			inLimit:     time.Second,
+			expectedCap: 100 * time.Millisecond,
+		},
+		{
+			name:        "growing case",
+			inAttempt:   2,
+			inBase:      100 * time.Millisecond,
+			inLimit:     time.Second,
+			expectedCap: 400 * time.Millisecond,
+		},
+		{
+			name:        "limited case",
+			inAttempt:   5,
+			inBase:      100 * time.Millisecond,
+			inLimit:     time.Second,
+			expectedCap: time.Second,
+		},
+		{
+			name:        "overflow case",
+			inAttempt:   80,
+			inBase:      100 * time.Millisecond,
+			inLimit:     time.Second,
+			expectedCap: time.Second,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			for range 1000 {
+				d := backoff(c.inAttempt, c.inBase, c.inLimit)
+
+				assert.GreaterOrEqual(t, d, c.expectedCap/2)
+				assert.LessOrEqual(t, d, c.expectedCap)
+			}
+		})
+	}
+}
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func TestIsTransient_Unit(t *testing.T) {
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	cases := []struct {
+		name              string
+		inCtx             context.Context
+		inErr             error
+		expectedTransient bool
+	}{
+		{
+			name:              "deadline case",
+			inCtx:             context.Background(),
+			inErr:             fmt.Errorf("get: %w", context.DeadlineExceeded),
+			expectedTransient: true,
+		},
+		{
+			name:              "connection reset case",
+			inCtx:             context.Background(),
+			inErr:             &net.OpError{Op: "read", Err: os.NewSyscallError("read", syscall.ECONNRESET)},
+			expectedTransient: true,
+		},
+		{
+			name:              "connection refused case",
+			inCtx:             context.Background(),
+			inErr:             &net.OpError{Op: "dial", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)},
+			expectedTransient: true,
+		},
+		{
+			name:              "eof case",
+			inCtx:             context.Background(),
+			inErr:             io.ErrUnexpectedEOF,
+			expectedTransient: true,
+		},
+		{
+			name:              "net timeout case",
+			inCtx:             context.Background(),
+			inErr:             timeoutError{},
+			expectedTransient: true,
+		},
+		{
+			name:              "permanent case",
+			inCtx:             context.Background(),
+			inErr:             errors.New("unsupported protocol scheme"),
+			expectedTransient: false,
+		},
+		{
+			name:              "caller canceled case",
+			inCtx:             canceled,
+			inErr:             context.DeadlineExceeded,
+			expectedTransient: false,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			assert.Equal(t, c.expectedTransient, isTransient(c.inCtx, c.inErr))
+		})
+	}
+}
+
+type response struct {
+	status     int
+	retryAfter string
+}
+
+func TestRetry_Unit(t *testing.T) {
+	cases := []struct {
+		name              string
+		inResponses       []response
+		expectedBody      string
+		expectedErr       error
+		expectedRequests  int32
+		expectedRateLimit time.Duration
+	}{
+		{
+			name:             "ok case",
+			inResponses:      []response{{status: http.StatusOK}},
+			expectedBody:     "ok",
+			expectedRequests: 1,
+		},
+		{
+			name:             "not found case",
+			inResponses:      []response{{status: http.StatusNotFound}},
+			expectedErr:      ErrNotFound,
+			expectedRequests: 1,
+		},
+		{
+			name:             "unauthorized case",
+			inResponses:      []response{{status: http.StatusUnauthorized}},
+			expectedErr:      ErrUnauthorized,
+			expectedRequests: 1,
+		},
+		{
+			name:             "payment required case",
+			inResponses:      []response{{status: http.StatusPaymentRequired}},
+			expectedErr:      ErrPaymentRequired,
+			expectedRequests: 1,
+		},
+		{
+			name:             "bad request case",
+			inResponses:      []response{{status: http.StatusBadRequest}},
+			expectedErr:      ErrInternal,
+			expectedRequests: 1,
+		},
+		{
+			name:              "rate limited case",
+			inResponses:       []response{{status: http.StatusTooManyRequests, retryAfter: "7"}},
+			expectedErr:       ErrRateLimited,
+			expectedRequests:  1,
+			expectedRateLimit: 7 * time.Second,
+		},
+		{
+			name:             "recovered case",
+			inResponses:      []response{{status: http.StatusServiceUnavailable}, {status: http.StatusBadGateway}, {status: http.StatusOK}},
+			expectedBody:     "ok",
+			expectedRequests: 3,
+		},
+		{
+			name:             "exhausted case",
+			inResponses:      []response{{status: http.StatusInternalServerError}, {status: http.StatusInternalServerError}, {status: http.StatusInternalServerError}},
+			expectedErr:      ErrUnavailable,
+			expectedRequests: 3,
+		},
+		{
+			name:             "short retry after case",
+			inResponses:      []response{{status: http.StatusServiceUnavailable, retryAfter: "0"}, {status: http.StatusOK}},
+			expectedBody:     "ok",
+			expectedRequests: 2,
+		},
+		{
+			name:             "long retry after case",
+			inResponses:      []response{{status: http.StatusServiceUnavailable, retryAfter: "3600"}},
+			expectedErr:      ErrUnavailable,
+			expectedRequests: 1,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var requests atomic.Int32
+
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				n := requests.Add(1)
+
+				resp := c.inResponses[min(int(n), len(c.inResponses))-1]
+				if resp.retryAfter != "" {
+					w.Header().Set("Retry-After", resp.retryAfter)
+				}
+				w.WriteHeader(resp.status)
+				w.Write([]byte("ok"))
+			}))
+			defer srv.Close()
+
+			h, e := newRetryHandlers(srv.URL)
+
+			body, err := h.retry(context.Background(), e, srv.URL)
+
+			assert.ErrorIs(t, err, c.expectedErr)
+			if c.expectedErr == nil {
+				assert.Equal(t, c.expectedBody, string(body))
+			}
+			assert.Equal(t, c.expectedRequests, requests.Load())
+
+			var rateLimit *RateLimitError
+			if errors.As(err, &rateLimit) {
+				assert.Equal(t, c.expectedRateLimit, rateLimit.RetryAfter)
+			}
+		})
+	}
+
+	t.Run("transport error case", func(t *testing.T) {
+		srv := httptest.NewServer(http.NotFoundHandler())
+		srv.Close()
+
+		h, e := newRetryHandlers(srv.URL)
+
+		_, err := h.retry(context.Background(), e, srv.URL)
+
+		assert.ErrorIs(t, err, syscall.ECONNREFUSED)
+	})
+}
+
+func newRetryHandlers(url string) (handlers, endpoint) {
+	h := handlers{
+		Client: http.Client{},
+		quotas: map[string]*quota.Tracker{provider.NameAgify: quota.New(0)},
+		log:    slog.New(slog.NewTextHandler(io.Discard, nil)),
+		config: config.EffectiveMobileConfig{
+			Client: config.ClientConfig{
+				Retries:        2,
+				RetryBaseDelay: time.Millisecond,
+				RetryMaxDelay:  10 * time.Millisecond,
+			},
+		},
+	}
+
+	e := endpoint{
+		provider: provider.Agify{},
+		config: config.ProviderConfig{
+			URL:     url,
+			Timeout: time.Second,
+		},
+	}
+
+	return h, e
+}
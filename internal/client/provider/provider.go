@@ -0,0 +1,259 @@
+package provider
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+)
+
+var (
+	ErrExists  = fmt.Errorf("провайдер уже зарегистрирован")
+	ErrUnknown = fmt.Errorf("неизвестный провайдер")
+)
+
+const (
+	KindAge         = "age"
+	KindGender      = "gender"
+	KindNationality = "nationality"
+)
+
+const (
+	NameAgify       = "agify"
+	NameGenderize   = "genderize"
+	NameNationalize = "nationalize"
+)
+
+type Country struct {
+	CountryID   string  `json:"country_id"`
+	Probability float64 `json:"probability"`
+}
+
+type Estimate struct {
+	Age         int
+	Gender      string
+	Probability float64
+	Count       int
+	Countries   []Country
+}
+
+type Provider interface {
+	Name() string
+	Kind() string
+	Target(base string, names []string, countryID string, key string) string
+	Decode(names []string, body []byte) (map[string]Estimate, error)
+}
+
+type Registry struct {
+	mu        sync.RWMutex
+	providers map[string]Provider
+}
+
+func NewRegistry(providers ...Provider) *Registry {
+	r := &Registry{
+		providers: map[string]Provider{},
+	}
+
+	for _, p := range providers {
+		r.providers[p.Name()] = p
+	}
+
+	return r
+}
+
+func (r *Registry) Register(p Provider) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	_, ok := r.providers[p.Name()]
+	if ok {
+		return fmt.Errorf("%w: %s", ErrExists, p.Name())
+	}
+	r.providers[p.Name()] = p
+
+	return nil
+}
+
+func (r *Registry) Get(name string) (Provider, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	p, ok := r.providers[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknown, name)
+	}
+
+	return p, nil
+}
+
+func (r *Registry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	names := make([]string, 0, len(r.providers))
+
+	for n := range r.providers {
+		names = append(names, n)
+	}
+	slices.Sort(names)
+
+	return names
+}
+
+var Default = NewRegistry(Agify{}, Genderize{}, Nationalize{})
+
+func Register(p Provider) error {
+	return Default.Register(p)
+}
+
+func target(base string, names []string, countryID string, key string) string {
+	params := make([]string, 0, len(names)+2)
+
+	if len(names) == 1 {
+		params = append(params, "name="+url.QueryEscape(names[0]))
+	} else {
+		for _, n := range names {
+			params = append(params, "name[]="+url.QueryEscape(n))
+		}
+	}
+
+	if countryID != "" {
+		params = append(params, "country_id="+url.QueryEscape(countryID))
+	}
+
+	if key != "" {
+		params = append(params, "apikey="+url.QueryEscape(key))
+	}
+
+	separator := "?"
+	if strings.Contains(base, "?") {
+		separator = "&"
+	}
+
+	return base + separator + strings.Join(params, "&")
+}
+
+func decode[T any](names []string, body []byte, estimate func(r T) (Estimate, bool)) (map[string]Estimate, error) {
+	var responses []T
+
+	if len(names) == 1 {
+		var r T
+
+		err := json.Unmarshal(body, &r)
+		if err != nil {
+			return nil, err
+		}
+		responses = append(responses, r)
+	} else {
+		err := json.Unmarshal(body, &responses)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	estimates := map[string]Estimate{}
+
+	for i, r := range responses {
+		if i >= len(names) {
+			break
+		}
+
+		e, ok := estimate(r)
+		if ok {
+			estimates[names[i]] = e
+		}
+	}
+
+	return estimates, nil
+}
+
+type Agify struct{}
+
+type agifyResponse struct {
+	Count int    `json:"count"`
+	Name  string `json:"name"`
+	Age   int    `json:"age"`
+}
+
+func (Agify) Name() string {
+	return NameAgify
+}
+
+func (Agify) Kind() string {
+	return KindAge
+}
+
+func (Agify) Target(base string, names []string, countryID string, key string) string {
+	return target(base, names, countryID, key)
+}
+
+func (Agify) Decode(names []string, body []byte) (map[string]Estimate, error) {
+	return decode(names, body, func(r agifyResponse) (Estimate, bool) {
+		return Estimate{
+			Age:   r.Age,
+			Count: r.Count,
+		}, r.Count > 0
+	})
+}
+
+type Genderize struct{}
+
+type genderizeResponse struct {
+	Count       int     `json:"count"`
+	Name        string  `json:"name"`
+	Gender      string  `json:"gender"`
+	Probability float64 `json:"probability"`
+}
+
+func (Genderize) Name() string {
+	return NameGenderize
+}
+
+func (Genderize) Kind() string {
+	return KindGender
+}
+
+func (Genderize) Target(base string, names []string, countryID string, key string) string {
+	return target(base, names, countryID, key)
+}
+
+func (Genderize) Decode(names []string, body []byte) (map[string]Estimate, error) {
+	return decode(names, body, func(r genderizeResponse) (Estimate, bool) {
+		return Estimate{
+			Gender:      r.Gender,
+			Probability: r.Probability,
+			Count:       r.Count,
+		}, r.Count > 0
+	})
+}
+
+type Nationalize struct{}
+
+type nationalizeResponse struct {
+	Count   int       `json:"count"`
+	Name    string    `json:"name"`
+	Country []Country `json:"country"`
+}
+
+func (Nationalize) Name() string {
+	return NameNationalize
+}
+
+func (Nationalize) Kind() string {
+	return KindNationality
+}
+
+func (Nationalize) Target(base string, names []string, countryID string, key string) string {
+	return target(base, names, "", key)
+}
+
+func (Nationalize) Decode(names []string, body []byte) (map[string]Estimate, error) {
+	return decode(names, body, func(r nationalizeResponse) (Estimate, bool) {
+		return Estimate{
+			Count:     r.Count,
+			Countries: r.Country,
+		}, r.Count > 0
+	})
+}
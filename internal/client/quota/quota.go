@@ -0,0 +1,102 @@
+package quota
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+var (
+	HeaderLimit     = "X-Rate-Limit-Limit"
+	HeaderRemaining = "X-Rate-Limit-Remaining"
+	HeaderReset     = "X-Rate-Limit-Reset"
+)
+
+type Quota struct {
+	Limit     int       `json:"limit"`
+	Remaining int       `json:"remaining"`
+	ResetAt   time.Time `json:"reset_at"`
+}
+
+type Tracker struct {
+	mu    sync.Mutex
+	quota Quota
+	known bool
+
+	reserve int
+}
+
+func New(reserve int) *Tracker {
+	return &Tracker{
+		reserve: max(0, reserve),
+	}
+}
+
+func (t *Tracker) Update(header http.Header) {
+	limit, err := strconv.Atoi(header.Get(HeaderLimit))
+	if err != nil {
+		return
+	}
+
+	remaining, err := strconv.Atoi(header.Get(HeaderRemaining))
+	if err != nil {
+		return
+	}
+
+	reset, err := strconv.Atoi(header.Get(HeaderReset))
+	if err != nil {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.quota = Quota{
+		Limit:     limit,
+		Remaining: max(0, remaining),
+		ResetAt:   time.Now().Add(time.Duration(max(0, reset)) * time.Second),
+	}
+	t.known = true
+}
+
+func (t *Tracker) Exhaust(retryAfter time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.quota.Remaining = 0
+	if retryAfter > 0 {
+		t.quota.ResetAt = time.Now().Add(retryAfter)
+	}
+	t.known = true
+}
+
+func (t *Tracker) Acquire() time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if !t.known {
+		return 0
+	}
+
+	wait := time.Until(t.quota.ResetAt)
+	if wait <= 0 {
+		t.known = false
+
+		return 0
+	}
+
+	if t.quota.Remaining <= t.reserve {
+		return wait
+	}
+	t.quota.Remaining--
+
+	return 0
+}
+
+func (t *Tracker) Quota() (Quota, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.quota, t.known
+}
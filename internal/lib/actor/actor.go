@@ -0,0 +1,21 @@
+package actor
+
+import (
+	"context"
+)
+
+const Anonymous = "anonymous"
+
+type key struct{}
+
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, key{}, actor)
+}
+
+func FromContext(ctx context.Context) string {
+	actor, ok := ctx.Value(key{}).(string)
+	if !ok || actor == "" {
+		return Anonymous
+	}
+	return actor
+}
@@ -0,0 +1,510 @@
+package queue
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/xoticdsign/effectivemobile/internal/lib/actor"
+	"github.com/xoticdsign/effectivemobile/internal/utils/config"
+)
+
+var (
+	ErrPermanent   = fmt.Errorf("задача не может быть выполнена")
+	ErrUnknownType = fmt.Errorf("неизвестный тип задачи")
+	ErrDuplicate   = fmt.Errorf("задача с таким ключом уже стоит в очереди")
+	ErrLeaseLost   = fmt.Errorf("аренда задачи истекла, задача передана другому воркеру")
+	ErrNotFound    = fmt.Errorf("задача не найдена")
+)
+
+const source = "queue"
+
+var (
+	StatusQueued  = "queued"
+	StatusRunning = "running"
+	StatusDead    = "dead"
+)
+
+type Job struct {
+	ID          int64
+	Type        string
+	Payload     json.RawMessage
+	Attempt     int
+	MaxAttempts int
+	Actor       string
+}
+
+func (j Job) Last() bool {
+	return j.Attempt >= j.MaxAttempts
+}
+
+type State struct {
+	Type      string
+	Status    string
+	Attempt   int
+	LastError string
+}
+
+type Handler func(ctx context.Context, job Job) error
+
+type Task struct {
+	Type    string
+	Key     string
+	Payload interface{}
+}
+
+type record struct {
+	jobType     string
+	key         string
+	payload     []byte
+	maxAttempts int
+	actor       string
+}
+
+type store interface {
+	insert(ctx context.Context, tx *sql.Tx, r record) (int64, error)
+	insertBatch(ctx context.Context, records []record) error
+	claim(ctx context.Context, lease time.Duration) (Job, bool, error)
+	complete(ctx context.Context, id int64, attempt int) error
+	release(ctx context.Context, id int64, attempt int) error
+	bury(ctx context.Context, id int64, attempt int, reason string) error
+	reschedule(ctx context.Context, id int64, attempt int, reason string, wait time.Duration) error
+	state(ctx context.Context, id int64) (State, error)
+}
+
+type Queue struct {
+	store store
+
+	mu       sync.RWMutex
+	handlers map[string]Handler
+
+	wg   sync.WaitGroup
+	once sync.Once
+	stop chan struct{}
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	log     *slog.Logger
+	config  config.QueueConfig
+	timeout time.Duration
+}
+
+func New(db *sql.DB, storage config.PostgreSQLConfig, config config.QueueConfig, log *slog.Logger) *Queue {
+	return newQueue(postgresStore{db: db, table: storage.JobsTable}, storage.Timeout, config, log)
+}
+
+func newQueue(s store, timeout time.Duration, config config.QueueConfig, log *slog.Logger) *Queue {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &Queue{
+		store: s,
+
+		handlers: map[string]Handler{},
+
+		stop: make(chan struct{}),
+
+		ctx:    ctx,
+		cancel: cancel,
+
+		log:     log,
+		config:  config,
+		timeout: timeout,
+	}
+}
+
+func (q *Queue) Register(jobType string, h Handler) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.handlers[jobType] = h
+}
+
+func (q *Queue) handler(jobType string) (Handler, bool) {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+
+	h, ok := q.handlers[jobType]
+	return h, ok
+}
+
+func (q *Queue) Enqueue(ctx context.Context, jobType string, payload interface{}) (int64, error) {
+	return q.EnqueueTx(ctx, nil, jobType, payload)
+}
+
+func (q *Queue) EnqueueTx(ctx context.Context, tx *sql.Tx, jobType string, payload interface{}) (int64, error) {
+	r, err := q.record(ctx, Task{Type: jobType, Payload: payload})
+	if err != nil {
+		return 0, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, q.timeout)
+	defer cancel()
+
+	return q.store.insert(ctx, tx, r)
+}
+
+func (q *Queue) EnqueueBatch(ctx context.Context, tasks ...Task) error {
+	records := make([]record, 0, len(tasks))
+
+	for _, t := range tasks {
+		r, err := q.record(ctx, t)
+		if err != nil {
+			return err
+		}
+		records = append(records, r)
+	}
+
+	if len(records) == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, q.timeout)
+	defer cancel()
+
+	return q.store.insertBatch(ctx, records)
+}
+
+func (q *Queue) record(ctx context.Context, t Task) (record, error) {
+	b, err := json.Marshal(t.Payload)
+	if err != nil {
+		return record{}, err
+	}
+
+	return record{
+		jobType:     t.Type,
+		key:         t.Key,
+		payload:     b,
+		maxAttempts: max(1, q.config.MaxAttempts),
+		actor:       actor.FromContext(ctx),
+	}, nil
+}
+
+func (q *Queue) State(ctx context.Context, id int64) (State, error) {
+	ctx, cancel := context.WithTimeout(ctx, q.timeout)
+	defer cancel()
+
+	return q.store.state(ctx, id)
+}
+
+func (q *Queue) Start() {
+	const op = "queue.Start()"
+
+	workers := max(1, q.config.Workers)
+
+	for range workers {
+		q.wg.Add(1)
+
+		go q.work()
+	}
+
+	q.log.Debug(
+		"воркеры очереди задач запущены",
+		slog.String("source", source),
+		slog.String("op", op),
+		slog.Int("workers", workers),
+	)
+}
+
+func (q *Queue) Shutdown(ctx context.Context) error {
+	q.once.Do(func() {
+		close(q.stop)
+	})
+
+	done := make(chan struct{})
+
+	go func() {
+		q.wg.Wait()
+
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		q.cancel()
+
+		return nil
+
+	case <-ctx.Done():
+		q.cancel()
+
+		<-done
+
+		return ctx.Err()
+	}
+}
+
+func (q *Queue) wait(d time.Duration) bool {
+	timer := time.NewTimer(d)
+
+	select {
+	case <-q.stop:
+		timer.Stop()
+
+		return false
+
+	case <-timer.C:
+		return true
+	}
+}
+
+func (q *Queue) work() {
+	const op = "queue.work()"
+
+	defer q.wg.Done()
+
+	for {
+		select {
+		case <-q.stop:
+			return
+
+		default:
+		}
+
+		job, ok, err := q.claim()
+		if err != nil {
+			q.log.Error(
+				"не удалось получить задачу из очереди",
+				slog.String("source", source),
+				slog.String("op", op),
+				slog.Any("error", err),
+			)
+		}
+
+		if !ok {
+			if !q.wait(q.config.PollInterval) {
+				return
+			}
+
+			continue
+		}
+
+		q.process(job)
+	}
+}
+
+func (q *Queue) lease() time.Duration {
+	return q.config.JobTimeout + q.timeout
+}
+
+func (q *Queue) claim() (Job, bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), q.timeout)
+	defer cancel()
+
+	return q.store.claim(ctx, q.lease())
+}
+
+func (q *Queue) backoff(attempt int) time.Duration {
+	d := q.config.RetryDelay << max(0, attempt-1)
+	if d <= 0 || d > q.config.RetryMaxDelay {
+		d = q.config.RetryMaxDelay
+	}
+	return d
+}
+
+func (q *Queue) process(job Job) {
+	const op = "queue.process()"
+
+	var err error
+
+	h, ok := q.handler(job.Type)
+	if ok {
+		ctx, cancel := context.WithTimeout(actor.WithActor(q.ctx, job.Actor), q.config.JobTimeout)
+
+		err = h(ctx, job)
+
+		cancel()
+	} else {
+		err = fmt.Errorf("%w: %s", ErrUnknownType, job.Type)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), q.timeout)
+	defer cancel()
+
+	switch {
+	case err == nil:
+		err = q.store.complete(ctx, job.ID, job.Attempt)
+
+	case q.ctx.Err() != nil:
+		q.log.Debug(
+			"задача возвращена в очередь из-за остановки",
+			slog.String("source", source),
+			slog.String("op", op),
+			slog.Int64("id", job.ID),
+			slog.String("type", job.Type),
+		)
+
+		err = q.store.release(ctx, job.ID, job.Attempt)
+
+	case errors.Is(err, ErrPermanent), errors.Is(err, ErrUnknownType), job.Last():
+		q.log.Error(
+			"задача перемещена в список неисполнимых",
+			slog.String("source", source),
+			slog.String("op", op),
+			slog.Int64("id", job.ID),
+			slog.String("type", job.Type),
+			slog.Int("attempt", job.Attempt),
+			slog.Any("error", err),
+		)
+
+		err = q.store.bury(ctx, job.ID, job.Attempt, err.Error())
+
+	default:
+		wait := q.backoff(job.Attempt)
+
+		q.log.Debug(
+			"повторная попытка выполнения задачи",
+			slog.String("source", source),
+			slog.String("op", op),
+			slog.Int64("id", job.ID),
+			slog.String("type", job.Type),
+			slog.Int("attempt", job.Attempt),
+			slog.Duration("wait", wait),
+			slog.Any("error", err),
+		)
+
+		err = q.store.reschedule(ctx, job.ID, job.Attempt, err.Error(), wait)
+	}
+
+	switch {
+	case errors.Is(err, ErrLeaseLost):
+		q.log.Error(
+			"аренда задачи утрачена, результат выполнения отброшен",
+			slog.String("source", source),
+			slog.String("op", op),
+			slog.Int64("id", job.ID),
+			slog.String("type", job.Type),
+			slog.Int("attempt", job.Attempt),
+		)
+
+	case err != nil:
+		q.log.Error(
+			"не удалось сохранить результат выполнения задачи",
+			slog.String("source", source),
+			slog.String("op", op),
+			slog.Int64("id", job.ID),
+			slog.Any("error", err),
+		)
+	}
+}
+
+type postgresStore struct {
+	db    *sql.DB
+	table string
+}
+
+func interval(d time.Duration) string {
+	return fmt.Sprintf("%d milliseconds", d.Milliseconds())
+}
+
+func (s postgresStore) insert(ctx context.Context, tx *sql.Tx, r record) (int64, error) {
+	var id int64
+
+	query := fmt.Sprintf("INSERT INTO %s (type, payload, max_attempts, actor, dedupe_key) VALUES($1, $2, $3, $4, $5) ON CONFLICT (type, dedupe_key) WHERE status <> 'dead' DO NOTHING RETURNING id;", s.table)
+
+	row := s.db.QueryRowContext
+	if tx != nil {
+		row = tx.QueryRowContext
+	}
+
+	err := row(ctx, query, r.jobType, string(r.payload), r.maxAttempts, r.actor, sql.NullString{String: r.key, Valid: r.key != ""}).Scan(&id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, fmt.Errorf("%w: %s", ErrDuplicate, r.key)
+		}
+		return 0, err
+	}
+
+	return id, nil
+}
+
+func (s postgresStore) insertBatch(ctx context.Context, records []record) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, r := range records {
+		_, err = s.insert(ctx, tx, r)
+		if err != nil && !errors.Is(err, ErrDuplicate) {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (s postgresStore) claim(ctx context.Context, lease time.Duration) (Job, bool, error) {
+	query := fmt.Sprintf("UPDATE %[1]s SET status=$1, attempts=attempts+1, locked_until=now()+$2::interval, updated_at=now() WHERE id=(SELECT id FROM %[1]s WHERE (status=$3 AND run_at<=now()) OR (status=$1 AND locked_until<now()) ORDER BY run_at, id LIMIT 1 FOR UPDATE SKIP LOCKED) RETURNING id, type, payload, attempts, max_attempts, actor;", s.table)
+
+	var (
+		job     Job
+		payload []byte
+	)
+
+	err := s.db.QueryRowContext(ctx, query, StatusRunning, interval(lease), StatusQueued).Scan(&job.ID, &job.Type, &payload, &job.Attempt, &job.MaxAttempts, &job.Actor)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Job{}, false, nil
+		}
+		return Job{}, false, err
+	}
+	job.Payload = payload
+
+	return job, true, nil
+}
+
+func (s postgresStore) complete(ctx context.Context, id int64, attempt int) error {
+	return s.exec(ctx, "DELETE FROM %s WHERE id=$1 AND status=$2 AND attempts=$3;", id, StatusRunning, attempt)
+}
+
+func (s postgresStore) release(ctx context.Context, id int64, attempt int) error {
+	return s.exec(ctx, "UPDATE %s SET status=$4, attempts=attempts-1, locked_until=NULL, updated_at=now() WHERE id=$1 AND status=$2 AND attempts=$3;", id, StatusRunning, attempt, StatusQueued)
+}
+
+func (s postgresStore) bury(ctx context.Context, id int64, attempt int, reason string) error {
+	return s.exec(ctx, "UPDATE %s SET status=$4, last_error=$5, locked_until=NULL, updated_at=now() WHERE id=$1 AND status=$2 AND attempts=$3;", id, StatusRunning, attempt, StatusDead, reason)
+}
+
+func (s postgresStore) reschedule(ctx context.Context, id int64, attempt int, reason string, wait time.Duration) error {
+	return s.exec(ctx, "UPDATE %s SET status=$4, last_error=$5, run_at=now()+$6::interval, locked_until=NULL, updated_at=now() WHERE id=$1 AND status=$2 AND attempts=$3;", id, StatusRunning, attempt, StatusQueued, reason, interval(wait))
+}
+
+func (s postgresStore) state(ctx context.Context, id int64) (State, error) {
+	query := fmt.Sprintf("SELECT type, status, attempts, COALESCE(last_error, '') FROM %s WHERE id=$1;", s.table)
+
+	var st State
+
+	err := s.db.QueryRowContext(ctx, query, id).Scan(&st.Type, &st.Status, &st.Attempt, &st.LastError)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return State{}, ErrNotFound
+		}
+		return State{}, err
+	}
+
+	return st, nil
+}
+
+func (s postgresStore) exec(ctx context.Context, query string, args ...interface{}) error {
+	result, err := s.db.ExecContext(ctx, fmt.Sprintf(query, s.table), args...)
+	if err != nil {
+		return err
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if n == 0 {
+		return ErrLeaseLost
+	}
+	return nil
+}
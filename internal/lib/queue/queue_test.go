@@ -0,0 +1,566 @@
+package queue
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"io"
+	"log/slog"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/xoticdsign/effectivemobile/internal/lib/actor"
+	"github.com/xoticdsign/effectivemobile/internal/utils/config"
+)
+
+type memoryJob struct {
+	job         Job
+	key         string
+	status      string
+	runAt       time.Time
+	lockedUntil time.Time
+	lastError   string
+}
+
+type memoryStore struct {
+	mu     sync.Mutex
+	jobs   map[int64]*memoryJob
+	nextID int64
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{
+		jobs: map[int64]*memoryJob{},
+	}
+}
+
+func (s *memoryStore) insert(ctx context.Context, tx *sql.Tx, r record) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.add(r)
+}
+
+func (s *memoryStore) insertBatch(ctx context.Context, records []record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, r := range records {
+		_, err := s.add(r)
+		if err != nil && !errors.Is(err, ErrDuplicate) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *memoryStore) add(r record) (int64, error) {
+	if r.key != "" {
+		for _, j := range s.jobs {
+			if j.job.Type == r.jobType && j.key == r.key && j.status != StatusDead {
+				return 0, ErrDuplicate
+			}
+		}
+	}
+
+	s.nextID++
+
+	s.jobs[s.nextID] = &memoryJob{
+		job: Job{
+			ID:          s.nextID,
+			Type:        r.jobType,
+			Payload:     r.payload,
+			MaxAttempts: r.maxAttempts,
+			Actor:       r.actor,
+		},
+		key:    r.key,
+		status: StatusQueued,
+		runAt:  time.Now(),
+	}
+
+	return s.nextID, nil
+}
+
+func (s *memoryStore) claim(ctx context.Context, lease time.Duration) (Job, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+
+	ids := make([]int64, 0, len(s.jobs))
+
+	for id, j := range s.jobs {
+		if (j.status == StatusQueued && !j.runAt.After(now)) || (j.status == StatusRunning && j.lockedUntil.Before(now)) {
+			ids = append(ids, id)
+		}
+	}
+
+	if len(ids) == 0 {
+		return Job{}, false, nil
+	}
+
+	sort.Slice(ids, func(a, b int) bool {
+		return ids[a] < ids[b]
+	})
+
+	j := s.jobs[ids[0]]
+	j.status = StatusRunning
+	j.job.Attempt++
+	j.lockedUntil = now.Add(lease)
+
+	return j.job, true, nil
+}
+
+func (s *memoryStore) owned(id int64, attempt int) (*memoryJob, error) {
+	j, ok := s.jobs[id]
+	if !ok || j.status != StatusRunning || j.job.Attempt != attempt {
+		return nil, ErrLeaseLost
+	}
+	return j, nil
+}
+
+func (s *memoryStore) complete(ctx context.Context, id int64, attempt int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err := s.owned(id, attempt)
+	if err != nil {
+		return err
+	}
+	delete(s.jobs, id)
+
+	return nil
+}
+
+func (s *memoryStore) release(ctx context.Context, id int64, attempt int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	j, err := s.owned(id, attempt)
+	if err != nil {
+		return err
+	}
+	j.status = StatusQueued
+	j.job.Attempt--
+	j.lockedUntil = time.Time{}
+
+	return nil
+}
+
+func (s *memoryStore) bury(ctx context.Context, id int64, attempt int, reason string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	j, err := s.owned(id, attempt)
+	if err != nil {
+		return err
+	}
+	j.status = StatusDead
+	j.lastError = reason
+	j.lockedUntil = time.Time{}
+
+	return nil
+}
+
+func (s *memoryStore) reschedule(ctx context.Context, id int64, attempt int, reason string, wait time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	j, err := s.owned(id, attempt)
+	if err != nil {
+		return err
+	}
+	j.status = StatusQueued
+	j.lastError = reason
+	j.runAt = time.Now().Add(wait)
+	j.lockedUntil = time.Time{}
+
+	return nil
+}
+
+func (s *memoryStore) state(ctx context.Context, id int64) (State, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	j, ok := s.jobs[id]
+	if !ok {
+		return State{}, ErrNotFound
+	}
+
+	return State{
+		Type:      j.job.Type,
+		Status:    j.status,
+		Attempt:   j.job.Attempt,
+		LastError: j.lastError,
+	}, nil
+}
+
+func (s *memoryStore) get(id int64) (memoryJob, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	j, ok := s.jobs[id]
+	if !ok {
+		return memoryJob{}, false
+	}
+	return *j, true
+}
+
+func newTestQueue(s store, c config.QueueConfig) *Queue {
+	return newQueue(s, 50*time.Millisecond, c, slog.New(slog.NewTextHandler(io.Discard, nil)))
+}
+
+var testConfig = config.QueueConfig{
+	Workers:       1,
+	PollInterval:  5 * time.Millisecond,
+	MaxAttempts:   3,
+	RetryDelay:    time.Millisecond,
+	RetryMaxDelay: 5 * time.Millisecond,
+	JobTimeout:    time.Second,
+}
+
+func TestLeaseExpiry_Unit(t *testing.T) {
+	s := newMemoryStore()
+
+	c := testConfig
+	c.JobTimeout = 150 * time.Millisecond
+
+	q := newTestQueue(s, c)
+
+	var attempts atomic.Int32
+
+	q.Register("test", func(ctx context.Context, job Job) error {
+		attempts.Store(int32(job.Attempt))
+
+		return nil
+	})
+
+	id, err := q.Enqueue(context.Background(), "test", struct{}{})
+	assert.NoError(t, err)
+
+	_, ok, err := s.claim(context.Background(), q.lease())
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	q.Start()
+	defer q.Shutdown(context.Background())
+
+	time.Sleep(50 * time.Millisecond)
+
+	assert.Equal(t, int32(0), attempts.Load())
+
+	assert.Eventually(t, func() bool {
+		_, ok := s.get(id)
+		return !ok
+	}, 2*time.Second, 5*time.Millisecond)
+
+	assert.Equal(t, int32(2), attempts.Load())
+}
+
+func TestLeaseLost_Unit(t *testing.T) {
+	errTransient := errors.New("transient")
+
+	cases := []struct {
+		name       string
+		inErr      error
+		inShutdown bool
+	}{
+		{
+			name:  "complete case",
+			inErr: nil,
+		},
+		{
+			name:  "reschedule case",
+			inErr: errTransient,
+		},
+		{
+			name:  "bury case",
+			inErr: ErrPermanent,
+		},
+		{
+			name:       "release case",
+			inErr:      context.Canceled,
+			inShutdown: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			s := newMemoryStore()
+			q := newTestQueue(s, testConfig)
+
+			q.Register("test", func(ctx context.Context, job Job) error {
+				return c.inErr
+			})
+
+			id, err := q.Enqueue(context.Background(), "test", struct{}{})
+			assert.NoError(t, err)
+
+			stale, ok, err := s.claim(context.Background(), time.Millisecond)
+			assert.NoError(t, err)
+			assert.True(t, ok)
+
+			time.Sleep(5 * time.Millisecond)
+
+			current, ok, err := s.claim(context.Background(), time.Minute)
+			assert.NoError(t, err)
+			assert.True(t, ok)
+			assert.Equal(t, id, current.ID)
+
+			if c.inShutdown {
+				q.cancel()
+			}
+
+			q.process(stale)
+
+			j, ok := s.get(id)
+
+			assert.True(t, ok)
+			assert.Equal(t, StatusRunning, j.status)
+			assert.Equal(t, current.Attempt, j.job.Attempt)
+			assert.Empty(t, j.lastError)
+
+			err = s.complete(context.Background(), stale.ID, stale.Attempt)
+			assert.ErrorIs(t, err, ErrLeaseLost)
+
+			err = s.complete(context.Background(), current.ID, current.Attempt)
+			assert.NoError(t, err)
+
+			_, ok = s.get(id)
+			assert.False(t, ok)
+		})
+	}
+}
+
+func TestRetry_Unit(t *testing.T) {
+	errTransient := errors.New("transient")
+
+	cases := []struct {
+		name             string
+		inType           string
+		inErrs           []error
+		expectedCalls    int32
+		expectedStatus   string
+		expectedAttempts int
+		expectedDone     bool
+	}{
+		{
+			name:          "success case",
+			inType:        "test",
+			inErrs:        []error{nil},
+			expectedCalls: 1,
+			expectedDone:  true,
+		},
+		{
+			name:          "recovered case",
+			inType:        "test",
+			inErrs:        []error{errTransient, nil},
+			expectedCalls: 2,
+			expectedDone:  true,
+		},
+		{
+			name:             "max attempts case",
+			inType:           "test",
+			inErrs:           []error{errTransient, errTransient, errTransient, nil},
+			expectedCalls:    3,
+			expectedStatus:   StatusDead,
+			expectedAttempts: 3,
+		},
+		{
+			name:             "permanent case",
+			inType:           "test",
+			inErrs:           []error{ErrPermanent, nil},
+			expectedCalls:    1,
+			expectedStatus:   StatusDead,
+			expectedAttempts: 1,
+		},
+		{
+			name:             "unknown type case",
+			inType:           "unknown",
+			inErrs:           []error{nil},
+			expectedCalls:    0,
+			expectedStatus:   StatusDead,
+			expectedAttempts: 1,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			s := newMemoryStore()
+			q := newTestQueue(s, testConfig)
+
+			var calls atomic.Int32
+
+			q.Register("test", func(ctx context.Context, job Job) error {
+				n := calls.Add(1)
+
+				return c.inErrs[min(int(n), len(c.inErrs))-1]
+			})
+
+			id, err := q.Enqueue(context.Background(), c.inType, struct{}{})
+			assert.NoError(t, err)
+
+			q.Start()
+
+			assert.Eventually(t, func() bool {
+				j, ok := s.get(id)
+				return !ok || j.status == StatusDead
+			}, 2*time.Second, 5*time.Millisecond)
+
+			err = q.Shutdown(context.Background())
+			assert.NoError(t, err)
+
+			j, ok := s.get(id)
+
+			assert.Equal(t, c.expectedDone, !ok)
+			assert.Equal(t, c.expectedCalls, calls.Load())
+			if !c.expectedDone {
+				assert.Equal(t, c.expectedStatus, j.status)
+				assert.Equal(t, c.expectedAttempts, j.job.Attempt)
+				assert.NotEmpty(t, j.lastError)
+			}
+
+			st, err := q.State(context.Background(), id)
+			if c.expectedDone {
+				assert.ErrorIs(t, err, ErrNotFound)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, State{Type: c.inType, Status: c.expectedStatus, Attempt: c.expectedAttempts, LastError: j.lastError}, st)
+			}
+		})
+	}
+}
+
+func TestShutdown_Unit(t *testing.T) {
+	cases := []struct {
+		name             string
+		inWork           time.Duration
+		inDrain          time.Duration
+		expectedErr      error
+		expectedDone     bool
+		expectedAttempts int
+	}{
+		{
+			name:         "drain case",
+			inWork:       50 * time.Millisecond,
+			inDrain:      time.Second,
+			expectedErr:  nil,
+			expectedDone: true,
+		},
+		{
+			name:             "requeue case",
+			inWork:           time.Minute,
+			inDrain:          50 * time.Millisecond,
+			expectedErr:      context.DeadlineExceeded,
+			expectedDone:     false,
+			expectedAttempts: 0,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			s := newMemoryStore()
+
+			cfg := testConfig
+			cfg.JobTimeout = 2 * time.Minute
+
+			q := newTestQueue(s, cfg)
+
+			started := make(chan struct{}, 2)
+
+			q.Register("test", func(ctx context.Context, job Job) error {
+				started <- struct{}{}
+
+				select {
+				case <-ctx.Done():
+					return ctx.Err()
+
+				case <-time.After(c.inWork):
+					return nil
+				}
+			})
+
+			id, err := q.Enqueue(context.Background(), "test", struct{}{})
+			assert.NoError(t, err)
+
+			pending, err := q.Enqueue(context.Background(), "test", struct{}{})
+			assert.NoError(t, err)
+
+			q.Start()
+
+			<-started
+
+			ctx, cancel := context.WithTimeout(context.Background(), c.inDrain)
+			defer cancel()
+
+			err = q.Shutdown(ctx)
+			assert.ErrorIs(t, err, c.expectedErr)
+
+			j, ok := s.get(id)
+
+			assert.Equal(t, c.expectedDone, !ok)
+			if !c.expectedDone {
+				assert.Equal(t, StatusQueued, j.status)
+				assert.Equal(t, c.expectedAttempts, j.job.Attempt)
+				assert.Empty(t, j.lastError)
+			}
+
+			p, ok := s.get(pending)
+
+			assert.True(t, ok)
+			assert.Equal(t, StatusQueued, p.status)
+			assert.Equal(t, 0, p.job.Attempt)
+		})
+	}
+}
+
+func TestEnqueueBatch_Unit(t *testing.T) {
+	s := newMemoryStore()
+	q := newTestQueue(s, testConfig)
+
+	ctx := actor.WithActor(context.Background(), "admin")
+
+	err := q.EnqueueBatch(ctx,
+		Task{Type: "test", Key: "a", Payload: 1},
+		Task{Type: "test", Key: "b", Payload: 2},
+		Task{Type: "other", Key: "a", Payload: 3},
+	)
+	assert.NoError(t, err)
+	assert.Len(t, s.jobs, 3)
+
+	err = q.EnqueueBatch(ctx,
+		Task{Type: "test", Key: "a", Payload: 1},
+		Task{Type: "test", Key: "c", Payload: 4},
+		Task{Type: "test", Payload: 5},
+	)
+	assert.NoError(t, err)
+	assert.Len(t, s.jobs, 5)
+
+	j, ok := s.get(4)
+	assert.True(t, ok)
+	assert.Equal(t, "c", j.key)
+	assert.Equal(t, "admin", j.job.Actor)
+	assert.Equal(t, testConfig.MaxAttempts, j.job.MaxAttempts)
+	assert.JSONEq(t, "4", string(j.job.Payload))
+
+	claimed, ok, err := s.claim(ctx, time.Minute)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	err = s.bury(ctx, claimed.ID, claimed.Attempt, "failed")
+	assert.NoError(t, err)
+
+	err = q.EnqueueBatch(ctx, Task{Type: "test", Key: "a", Payload: 1})
+	assert.NoError(t, err)
+	assert.Len(t, s.jobs, 6)
+
+	err = q.EnqueueBatch(ctx, Task{Type: "test", Key: "bad", Payload: func() {}})
+	assert.Error(t, err)
+	assert.Len(t, s.jobs, 6)
+}
@@ -1,23 +1,45 @@
 package effectivemobile
 
 import (
+	"bufio"
+	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"io/fs"
 	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/xoticdsign/effectivemobile/internal/client"
+	"github.com/xoticdsign/effectivemobile/internal/client/breaker"
+	"github.com/xoticdsign/effectivemobile/internal/client/cache"
+	"github.com/xoticdsign/effectivemobile/internal/client/quota"
+	"github.com/xoticdsign/effectivemobile/internal/lib/queue"
 	storage "github.com/xoticdsign/effectivemobile/internal/storage/postgresql"
 	"github.com/xoticdsign/effectivemobile/internal/utils/config"
 )
 
 var (
-	ErrClientNotFound  = fmt.Errorf("у клиента нет данных")
-	ErrClientInternal  = fmt.Errorf("внутренняя ошибка клиента")
-	ErrStorageNotFound = fmt.Errorf("у хранилища нет данных")
-	ErrStorageConflict = fmt.Errorf("запрос сформирофан некоректно")
-	ErrStorageInternal = fmt.Errorf("внутренняя ошибка хранилища")
+	ErrClientNotFound        = fmt.Errorf("у клиента нет данных")
+	ErrClientInternal        = fmt.Errorf("внутренняя ошибка клиента")
+	ErrClientUnavailable     = fmt.Errorf("провайдер обогащения недоступен")
+	ErrClientRateLimited     = fmt.Errorf("превышен лимит запросов к провайдеру обогащения")
+	ErrClientUnauthorized    = fmt.Errorf("провайдер обогащения отклонил API-ключ")
+	ErrClientPaymentRequired = fmt.Errorf("подписка на провайдера обогащения исчерпана")
+	ErrStorageNotFound       = fmt.Errorf("у хранилища нет данных")
+	ErrStorageConflict       = fmt.Errorf("запрос сформирофан некоректно")
+	ErrStorageInvalid        = fmt.Errorf("хранилище не может обработать параметры запроса")
+	ErrStorageVersion        = fmt.Errorf("версия записи в хранилище не совпадает с ожидаемой")
+	ErrStorageInternal       = fmt.Errorf("внутренняя ошибка хранилища")
+	ErrTimeout               = fmt.Errorf("время ожидания истекло")
 )
 
 const source = "service"
@@ -30,26 +52,59 @@ type Service struct {
 }
 
 type Handlerer interface {
-	DeleteByID(id string) error
-	UpdateByID(id string, data []byte) error
-	Create(name string, surname string, patronymic string) error
-	Select(id string, limit []int, filter string, value string) ([]storage.Row, error)
+	DeleteByID(ctx context.Context, id string, versions []int) error
+	UpdateByID(ctx context.Context, id string, data []byte, versions []int) (storage.Row, error)
+	PatchByID(ctx context.Context, id string, patch storage.Patch, versions []int) (storage.Row, error)
+	RestoreByID(ctx context.Context, id string, versions []int) (storage.Row, error)
+	Purge(ctx context.Context, retention time.Duration) (int64, error)
+	Create(ctx context.Context, name string, surname string, patronymic string, countryID string) (storage.Row, error)
+	CreateAsync(ctx context.Context, name string, surname string, patronymic string, countryID string) (storage.Row, error)
+	CreateBatch(ctx context.Context, people []storage.Person) (CreateBatchResult, error)
+	Reenrich(ctx context.Context, id string) (storage.Row, error)
+	ReenrichBatch(ctx context.Context, f storage.ReenrichFilter) (int64, error)
+	Export(ctx context.Context, filters []storage.Filter) (int64, error)
+	ExportFile(ctx context.Context, id int64) (ExportResult, error)
+	Select(ctx context.Context, q storage.SelectQuery) (storage.SelectResult, error)
+	History(ctx context.Context, id string) ([]storage.HistoryEntry, error)
+	SelectAsOf(ctx context.Context, id string, asOf time.Time) (storage.Row, error)
+	Stats() client.Stats
 }
 
 type S struct {
 	Handlers Handlerer
 }
 
-func New(config config.EffectiveMobileConfig, client *client.Client, storage *storage.Storage, log *slog.Logger) *Service {
+func New(config config.EffectiveMobileConfig, client *client.Client, storage *storage.Storage, q *queue.Queue, log *slog.Logger) *Service {
+	const op = "service.New()"
+
+	switch config.EnrichmentPolicy {
+	case PolicyStrict, PolicyBestEffort:
+
+	default:
+		log.Error(
+			"неизвестная политика обогащения, используется strict",
+			slog.String("source", source),
+			slog.String("op", op),
+			slog.String("policy", config.EnrichmentPolicy),
+		)
+	}
+
+	h := Handlers{
+		Client:  client.C.Handlers,
+		Storage: storage.DB.Handlers,
+		Queue:   q,
+
+		log:    log,
+		config: config,
+	}
+
+	q.Register(JobEnrichment, h.EnrichJob)
+	q.Register(JobReenrichment, h.ReenrichJob)
+	q.Register(JobExport, h.ExportJob)
+
 	return &Service{
 		S: S{
-			Handlers: Handlers{
-				Client:  client.C.Handlers,
-				Storage: storage.DB.Handlers,
-
-				log:    log,
-				config: config,
-			},
+			Handlers: h,
 		},
 
 		log:    log,
@@ -57,17 +112,65 @@ func New(config config.EffectiveMobileConfig, client *client.Client, storage *st
 	}
 }
 
+var (
+	PolicyStrict     = "strict"
+	PolicyBestEffort = "best_effort"
+)
+
+var (
+	JobEnrichment   = "enrichment"
+	JobReenrichment = "reenrichment"
+	JobExport       = "export"
+)
+
+type EnrichmentPayload struct {
+	ID        int    `json:"id"`
+	CountryID string `json:"country_id"`
+	Reenrich  bool   `json:"reenrich,omitempty"`
+}
+
+type ReenrichmentPayload struct {
+	Filter storage.ReenrichFilter `json:"filter"`
+	After  int                    `json:"after"`
+	Origin int64                  `json:"origin,omitempty"`
+}
+
+type ExportPayload struct {
+	Filters []storage.Filter `json:"filters"`
+}
+
+type Enqueuer interface {
+	Enqueue(ctx context.Context, jobType string, payload interface{}) (int64, error)
+	EnqueueTx(ctx context.Context, tx *sql.Tx, jobType string, payload interface{}) (int64, error)
+	EnqueueBatch(ctx context.Context, tasks ...queue.Task) error
+	State(ctx context.Context, id int64) (queue.State, error)
+}
+
 type Clienter interface {
-	GetAge(name string) (int, error)
-	GetGender(name string) (string, error)
-	GetNationality(name string) (string, error)
+	GetAge(ctx context.Context, name string, countryID string) (client.Age, error)
+	GetGender(ctx context.Context, name string, countryID string) (client.Gender, error)
+	GetNationality(ctx context.Context, name string) (client.Nationality, error)
+	GetAges(ctx context.Context, names []string, countryID string) (map[string]client.Age, error)
+	GetGenders(ctx context.Context, names []string, countryID string) (map[string]client.Gender, error)
+	GetNationalities(ctx context.Context, names []string) (map[string]client.Nationality, error)
+	Stats() client.Stats
 }
 
 type Querier interface {
-	DeleteByID(id string) error
-	UpdateByID(id string, data []byte) error
-	Create(name string, surname string, patronymic string, age int, gender string, nationality string) error
-	Select(id string, limit []int, filter string, value string) ([]storage.Row, error)
+	DeleteByID(ctx context.Context, id string, versions []int) error
+	UpdateByID(ctx context.Context, id string, data []byte, versions []int) (storage.Row, error)
+	PatchByID(ctx context.Context, id string, patch storage.Patch, versions []int) (storage.Row, error)
+	RestoreByID(ctx context.Context, id string, versions []int) (storage.Row, error)
+	Purge(ctx context.Context, before time.Time) (int64, error)
+	Create(ctx context.Context, p storage.Person) (storage.Row, error)
+	CreateWith(ctx context.Context, p storage.Person, after storage.AfterCreate) (storage.Row, error)
+	CreateBatch(ctx context.Context, people []storage.Person) ([]storage.Row, error)
+	Enrich(ctx context.Context, id string, p storage.Person) (storage.Row, error)
+	SetEnrichmentStatus(ctx context.Context, id string, status string) (storage.Row, error)
+	SelectReenrich(ctx context.Context, q storage.ReenrichQuery) ([]storage.Row, error)
+	Select(ctx context.Context, q storage.SelectQuery) (storage.SelectResult, error)
+	History(ctx context.Context, id string) ([]storage.HistoryEntry, error)
+	SelectAsOf(ctx context.Context, id string, asOf time.Time) (storage.Row, error)
 }
 
 type Handlers struct {
@@ -75,12 +178,13 @@ type Handlers struct {
 
 	Client  Clienter
 	Storage Querier
+	Queue   Enqueuer
 
 	log    *slog.Logger
 	config config.EffectiveMobileConfig
 }
 
-func (h Handlers) DeleteByID(id string) error {
+func (h Handlers) DeleteByID(ctx context.Context, id string, versions []int) error {
 	const op = "service.DeleteByID()"
 
 	h.log.Debug(
@@ -89,7 +193,7 @@ func (h Handlers) DeleteByID(id string) error {
 		slog.String("op", op),
 	)
 
-	err := h.Storage.DeleteByID(id)
+	err := h.Storage.DeleteByID(ctx, id, versions)
 	if err != nil {
 		switch {
 		case errors.Is(err, sql.ErrNoRows):
@@ -102,6 +206,26 @@ func (h Handlers) DeleteByID(id string) error {
 
 			return fmt.Errorf("%w: %v", ErrStorageNotFound, err)
 
+		case errors.Is(err, storage.ErrVersionMismatch):
+			h.log.Error(
+				"версия записи не совпадает с ожидаемой",
+				slog.String("source", source),
+				slog.String("op", op),
+				slog.Any("error", err),
+			)
+
+			return fmt.Errorf("%w: %v", ErrStorageVersion, err)
+
+		case errors.Is(err, context.DeadlineExceeded), errors.Is(err, context.Canceled):
+			h.log.Error(
+				"хранилище не успело выполнить операцию",
+				slog.String("source", source),
+				slog.String("op", op),
+				slog.Any("error", err),
+			)
+
+			return fmt.Errorf("%w: %v", ErrTimeout, err)
+
 		default:
 			h.log.Error(
 				"внутренняя ошибка хранилища",
@@ -122,7 +246,7 @@ func (h Handlers) DeleteByID(id string) error {
 	return nil
 }
 
-func (h Handlers) UpdateByID(id string, data []byte) error {
+func (h Handlers) UpdateByID(ctx context.Context, id string, data []byte, versions []int) (storage.Row, error) {
 	const op = "service.UpdateByID()"
 
 	h.log.Debug(
@@ -131,7 +255,7 @@ func (h Handlers) UpdateByID(id string, data []byte) error {
 		slog.String("op", op),
 	)
 
-	err := h.Storage.UpdateByID(id, data)
+	r, err := h.Storage.UpdateByID(ctx, id, data, versions)
 	if err != nil {
 		switch {
 		case errors.Is(err, storage.ErrConstraint):
@@ -142,7 +266,7 @@ func (h Handlers) UpdateByID(id string, data []byte) error {
 				slog.Any("error", err),
 			)
 
-			return fmt.Errorf("%w: %v", ErrStorageConflict, err)
+			return storage.Row{}, fmt.Errorf("%w: %v", ErrStorageConflict, err)
 
 		case errors.Is(err, sql.ErrNoRows):
 			h.log.Error(
@@ -152,7 +276,7 @@ func (h Handlers) UpdateByID(id string, data []byte) error {
 				slog.Any("error", err),
 			)
 
-			return fmt.Errorf("%w: %v", ErrStorageNotFound, err)
+			return storage.Row{}, fmt.Errorf("%w: %v", ErrStorageNotFound, err)
 
 		case errors.Is(err, storage.ErrNoNewValues):
 			h.log.Error(
@@ -162,7 +286,37 @@ func (h Handlers) UpdateByID(id string, data []byte) error {
 				slog.Any("error", err),
 			)
 
-			return fmt.Errorf("%w: %v", ErrStorageConflict, err)
+			return storage.Row{}, fmt.Errorf("%w: %v", ErrStorageConflict, err)
+
+		case errors.Is(err, storage.ErrInvalidData), errors.Is(err, storage.ErrNormalization):
+			h.log.Error(
+				"хранилище не смогло разобрать данные из запроса",
+				slog.String("source", source),
+				slog.String("op", op),
+				slog.Any("error", err),
+			)
+
+			return storage.Row{}, fmt.Errorf("%w: %v", ErrStorageInvalid, err)
+
+		case errors.Is(err, storage.ErrVersionMismatch):
+			h.log.Error(
+				"версия записи не совпадает с ожидаемой",
+				slog.String("source", source),
+				slog.String("op", op),
+				slog.Any("error", err),
+			)
+
+			return storage.Row{}, fmt.Errorf("%w: %v", ErrStorageVersion, err)
+
+		case errors.Is(err, context.DeadlineExceeded), errors.Is(err, context.Canceled):
+			h.log.Error(
+				"хранилище не успело выполнить операцию",
+				slog.String("source", source),
+				slog.String("op", op),
+				slog.Any("error", err),
+			)
+
+			return storage.Row{}, fmt.Errorf("%w: %v", ErrTimeout, err)
 
 		default:
 			h.log.Error(
@@ -172,7 +326,7 @@ func (h Handlers) UpdateByID(id string, data []byte) error {
 				slog.Any("error", err),
 			)
 
-			return fmt.Errorf("%w: %v", ErrStorageInternal, err)
+			return storage.Row{}, fmt.Errorf("%w: %v", ErrStorageInternal, err)
 		}
 	}
 	h.log.Debug(
@@ -181,11 +335,11 @@ func (h Handlers) UpdateByID(id string, data []byte) error {
 		slog.String("op", op),
 	)
 
-	return nil
+	return r, nil
 }
 
-func (h Handlers) Create(name string, surname string, patronymic string) error {
-	const op = "service.Create()"
+func (h Handlers) PatchByID(ctx context.Context, id string, patch storage.Patch, versions []int) (storage.Row, error) {
+	const op = "service.PatchByID()"
 
 	h.log.Debug(
 		"данные получены сервисным слоем",
@@ -193,91 +347,130 @@ func (h Handlers) Create(name string, surname string, patronymic string) error {
 		slog.String("op", op),
 	)
 
-	errChan := make(chan error, 3)
+	r, err := h.Storage.PatchByID(ctx, id, patch, versions)
+	if err != nil {
+		switch {
+		case errors.Is(err, storage.ErrConstraint):
+			h.log.Error(
+				"предоставленные данные не соответсвуют ограничениям таблицы",
+				slog.String("source", source),
+				slog.String("op", op),
+				slog.Any("error", err),
+			)
 
-	var age int
-	var gender string
-	var nationality string
+			return storage.Row{}, fmt.Errorf("%w: %v", ErrStorageConflict, err)
 
-	wg := sync.WaitGroup{}
+		case errors.Is(err, sql.ErrNoRows):
+			h.log.Error(
+				"в хранилище нет соответсвующих данных",
+				slog.String("source", source),
+				slog.String("op", op),
+				slog.Any("error", err),
+			)
 
-	wg.Add(1)
+			return storage.Row{}, fmt.Errorf("%w: %v", ErrStorageNotFound, err)
 
-	go func() {
-		var err error
+		case errors.Is(err, storage.ErrInvalidPatch), errors.Is(err, storage.ErrNormalization):
+			h.log.Error(
+				"хранилище не смогло применить патч",
+				slog.String("source", source),
+				slog.String("op", op),
+				slog.Any("error", err),
+			)
 
-		age, err = h.Client.GetAge(name)
-		if err != nil {
-			errChan <- err
-		}
+			return storage.Row{}, fmt.Errorf("%w: %v", ErrStorageInvalid, err)
 
-		wg.Done()
-	}()
+		case errors.Is(err, storage.ErrVersionMismatch):
+			h.log.Error(
+				"версия записи не совпадает с ожидаемой",
+				slog.String("source", source),
+				slog.String("op", op),
+				slog.Any("error", err),
+			)
 
-	wg.Add(1)
+			return storage.Row{}, fmt.Errorf("%w: %v", ErrStorageVersion, err)
 
-	go func() {
-		var err error
+		case errors.Is(err, context.DeadlineExceeded), errors.Is(err, context.Canceled):
+			h.log.Error(
+				"хранилище не успело выполнить операцию",
+				slog.String("source", source),
+				slog.String("op", op),
+				slog.Any("error", err),
+			)
 
-		gender, err = h.Client.GetGender(name)
-		if err != nil {
-			errChan <- err
-		}
+			return storage.Row{}, fmt.Errorf("%w: %v", ErrTimeout, err)
 
-		wg.Done()
-	}()
+		default:
+			h.log.Error(
+				"внутренняя ошибка хранилища",
+				slog.String("source", source),
+				slog.String("op", op),
+				slog.Any("error", err),
+			)
 
-	wg.Add(1)
+			return storage.Row{}, fmt.Errorf("%w: %v", ErrStorageInternal, err)
+		}
+	}
+	h.log.Debug(
+		"данные обработаны сервисным слоем",
+		slog.String("source", source),
+		slog.String("op", op),
+	)
 
-	go func() {
-		var err error
+	return r, nil
+}
 
-		nationality, err = h.Client.GetNationality(name)
-		if err != nil {
-			errChan <- err
-		}
+func (h Handlers) RestoreByID(ctx context.Context, id string, versions []int) (storage.Row, error) {
+	const op = "service.RestoreByID()"
 
-		wg.Done()
-	}()
+	h.log.Debug(
+		"данные получены сервисным слоем",
+		slog.String("source", source),
+		slog.String("op", op),
+	)
 
-	wg.Wait()
+	r, err := h.Storage.RestoreByID(ctx, id, versions)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			h.log.Error(
+				"в хранилище нет соответсвующих данных",
+				slog.String("source", source),
+				slog.String("op", op),
+				slog.Any("error", err),
+			)
 
-	close(errChan)
+			return storage.Row{}, fmt.Errorf("%w: %v", ErrStorageNotFound, err)
 
-	for e := range errChan {
-		if errors.Is(e, client.ErrNotFound) {
+		case errors.Is(err, storage.ErrNotDeleted):
 			h.log.Error(
-				"клиент не ничего нашел",
+				"запись не была удалена",
 				slog.String("source", source),
 				slog.String("op", op),
-				slog.Any("error", e),
+				slog.Any("error", err),
 			)
 
-			return fmt.Errorf("%w: %v", ErrClientNotFound, e)
-		} else {
+			return storage.Row{}, fmt.Errorf("%w: %v", ErrStorageConflict, err)
+
+		case errors.Is(err, storage.ErrVersionMismatch):
 			h.log.Error(
-				"внутренняя ошибка клиента",
+				"версия записи не совпадает с ожидаемой",
 				slog.String("source", source),
 				slog.String("op", op),
-				slog.Any("error", e),
+				slog.Any("error", err),
 			)
 
-			return fmt.Errorf("%w: %v", ErrClientInternal, e)
-		}
-	}
+			return storage.Row{}, fmt.Errorf("%w: %v", ErrStorageVersion, err)
 
-	err := h.Storage.Create(name, surname, patronymic, age, gender, nationality)
-	if err != nil {
-		switch {
-		case errors.Is(err, sql.ErrNoRows):
+		case errors.Is(err, context.DeadlineExceeded), errors.Is(err, context.Canceled):
 			h.log.Error(
-				"в хранилище нет соответсвующих данных",
+				"хранилище не успело выполнить операцию",
 				slog.String("source", source),
 				slog.String("op", op),
 				slog.Any("error", err),
 			)
 
-			return fmt.Errorf("%w: %v", ErrStorageNotFound, err)
+			return storage.Row{}, fmt.Errorf("%w: %v", ErrTimeout, err)
 
 		default:
 			h.log.Error(
@@ -287,7 +480,7 @@ func (h Handlers) Create(name string, surname string, patronymic string) error {
 				slog.Any("error", err),
 			)
 
-			return fmt.Errorf("%w: %v", ErrStorageInternal, err)
+			return storage.Row{}, fmt.Errorf("%w: %v", ErrStorageInternal, err)
 		}
 	}
 	h.log.Debug(
@@ -296,11 +489,11 @@ func (h Handlers) Create(name string, surname string, patronymic string) error {
 		slog.String("op", op),
 	)
 
-	return nil
+	return r, nil
 }
 
-func (h Handlers) Select(id string, limit []int, filter string, value string) ([]storage.Row, error) {
-	const op = "service.Select()"
+func (h Handlers) Purge(ctx context.Context, retention time.Duration) (int64, error) {
+	const op = "service.Purge()"
 
 	h.log.Debug(
 		"данные получены сервисным слоем",
@@ -308,18 +501,18 @@ func (h Handlers) Select(id string, limit []int, filter string, value string) ([
 		slog.String("op", op),
 	)
 
-	r, err := h.Storage.Select(id, limit, filter, value)
+	purged, err := h.Storage.Purge(ctx, time.Now().Add(-retention))
 	if err != nil {
 		switch {
-		case errors.Is(err, sql.ErrNoRows):
+		case errors.Is(err, context.DeadlineExceeded), errors.Is(err, context.Canceled):
 			h.log.Error(
-				"в хранилище нет соответсвующих данных",
+				"хранилище не успело выполнить операцию",
 				slog.String("source", source),
 				slog.String("op", op),
 				slog.Any("error", err),
 			)
 
-			return nil, fmt.Errorf("%w: %v", ErrStorageNotFound, err)
+			return 0, fmt.Errorf("%w: %v", ErrTimeout, err)
 
 		default:
 			h.log.Error(
@@ -329,68 +522,1638 @@ func (h Handlers) Select(id string, limit []int, filter string, value string) ([
 				slog.Any("error", err),
 			)
 
-			return nil, fmt.Errorf("%w: %v", ErrStorageInternal, err)
+			return 0, fmt.Errorf("%w: %v", ErrStorageInternal, err)
 		}
 	}
-	h.log.Debug(
-		"данные обработаны сервисным слоем",
+	h.log.Info(
+		"удаленные записи очищены",
 		slog.String("source", source),
 		slog.String("op", op),
+		slog.Int64("purged", purged),
+		slog.Duration("retention", retention),
 	)
 
-	return r, nil
+	return purged, nil
 }
 
-// МОКИ
+func (h Handlers) clientError(op string, err error) error {
+	switch {
+	case errors.Is(err, client.ErrNotFound):
+		h.log.Error(
+			"клиент не ничего нашел",
+			slog.String("source", source),
+			slog.String("op", op),
+			slog.Any("error", err),
+		)
+
+		return fmt.Errorf("%w: %v", ErrClientNotFound, err)
+
+	case errors.Is(err, client.ErrRateLimited):
+		h.log.Error(
+			"превышен лимит запросов к провайдеру обогащения",
+			slog.String("source", source),
+			slog.String("op", op),
+			slog.Any("error", err),
+		)
+
+		return fmt.Errorf("%w: %w", ErrClientRateLimited, err)
+
+	case errors.Is(err, client.ErrUnauthorized):
+		h.log.Error(
+			"провайдер обогащения отклонил API-ключ, проверьте настройки ключа",
+			slog.String("source", source),
+			slog.String("op", op),
+			slog.Any("error", err),
+		)
+
+		return fmt.Errorf("%w: %v", ErrClientUnauthorized, err)
+
+	case errors.Is(err, client.ErrPaymentRequired):
+		h.log.Error(
+			"подписка на провайдера обогащения исчерпана, требуется продление",
+			slog.String("source", source),
+			slog.String("op", op),
+			slog.Any("error", err),
+		)
+
+		return fmt.Errorf("%w: %v", ErrClientPaymentRequired, err)
+
+	case errors.Is(err, client.ErrCircuitOpen), errors.Is(err, client.ErrUnavailable):
+		h.log.Error(
+			"провайдер обогащения недоступен",
+			slog.String("source", source),
+			slog.String("op", op),
+			slog.Any("error", err),
+		)
+
+		return fmt.Errorf("%w: %v", ErrClientUnavailable, err)
+
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(err, context.Canceled):
+		h.log.Error(
+			"клиент не успел получить данные",
+			slog.String("source", source),
+			slog.String("op", op),
+			slog.Any("error", err),
+		)
+
+		return fmt.Errorf("%w: %v", ErrTimeout, err)
+
+	default:
+		h.log.Error(
+			"внутренняя ошибка клиента",
+			slog.String("source", source),
+			slog.String("op", op),
+			slog.Any("error", err),
+		)
+
+		return fmt.Errorf("%w: %v", ErrClientInternal, err)
+	}
+}
 
-type UnimplementedHandlers struct{}
+func (h Handlers) confident(nationality client.Nationality) bool {
+	t := h.config.Thresholds
 
-func (u UnimplementedHandlers) DeleteByID(id string) error {
-	switch id {
-	case "s404":
-		return ErrStorageNotFound
+	return nationality.Probability >= t.NationalityProbability && nationality.Count >= t.NationalityCount
+}
 
-	case "s500":
-		return ErrStorageInternal
-	}
-	return nil
+func (h Handlers) strict() bool {
+	return h.config.EnrichmentPolicy != PolicyBestEffort
 }
 
-func (u UnimplementedHandlers) UpdateByID(id string, data []byte) error {
-	switch id {
-	case "s404":
-		return ErrStorageNotFound
+func (h Handlers) enrich(p storage.Person, age client.Age, gender client.Gender, nationality client.Nationality, statuses map[string]string) storage.Person {
+	const op = "service.enrich()"
 
-	case "s409":
-		return ErrStorageConflict
+	t := h.config.Thresholds
 
-	case "s500":
-		return ErrStorageInternal
+	unset := []string{}
+
+	p.EnrichmentFields = map[string]string{}
+
+	switch {
+	case statuses["age"] != "":
+		p.EnrichmentFields["age"] = statuses["age"]
+
+	case age.Count >= t.AgeCount:
+		p.Age = age.Age
+		p.EnrichmentFields["age"] = storage.FieldEnriched
+
+	default:
+		unset = append(unset, "age")
+		p.EnrichmentFields["age"] = storage.FieldLowConfidence
 	}
-	return nil
-}
+	p.AgeCount = age.Count
 
-func (u UnimplementedHandlers) Create(name string, surname string, patronymic string) error {
-	switch name {
-	case "s404":
-		return ErrStorageNotFound
+	switch {
+	case statuses["gender"] != "":
+		p.EnrichmentFields["gender"] = statuses["gender"]
 
-	case "c404":
-		return ErrClientNotFound
+	case gender.Probability >= t.GenderProbability && gender.Count >= t.GenderCount:
+		p.Gender = gender.Gender
+		p.EnrichmentFields["gender"] = storage.FieldEnriched
 
-	case "500":
-		return ErrStorageInternal
+	default:
+		unset = append(unset, "gender")
+		p.EnrichmentFields["gender"] = storage.FieldLowConfidence
 	}
-	return nil
+	p.GenderProbability = gender.Probability
+	p.GenderCount = gender.Count
+
+	switch {
+	case statuses["nationality"] != "":
+		p.EnrichmentFields["nationality"] = statuses["nationality"]
+
+	case h.confident(nationality):
+		p.Nationality = nationality.Nationality
+		p.EnrichmentFields["nationality"] = storage.FieldEnriched
+
+	default:
+		unset = append(unset, "nationality")
+		p.EnrichmentFields["nationality"] = storage.FieldLowConfidence
+	}
+	p.NationalityProbability = nationality.Probability
+	p.NationalityCount = nationality.Count
+	p.NationalityCandidates = []storage.Candidate{}
+
+	for _, c := range nationality.Candidates {
+		p.NationalityCandidates = append(p.NationalityCandidates, storage.Candidate{
+			CountryID:   c.CountryID,
+			Probability: c.Probability,
+		})
+	}
+
+	if len(unset) > 0 {
+		h.log.Debug(
+			"уверенность ниже порога, поля оставлены пустыми и требуют проверки",
+			slog.String("source", source),
+			slog.String("op", op),
+			slog.Any("fields", unset),
+		)
+	}
+
+	return p
 }
 
-func (u UnimplementedHandlers) Select(id string, limit []int, filter string, value string) ([]storage.Row, error) {
-	switch id {
-	case "s404":
-		return []storage.Row{}, ErrStorageNotFound
+type fieldError struct {
+	field string
+	err   error
+}
 
-	case "s500":
-		return []storage.Row{}, ErrStorageInternal
+func (h Handlers) lookup(ctx context.Context, p storage.Person) (storage.Person, error) {
+	const op = "service.lookup()"
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	errChan := make(chan fieldError, 3)
+
+	fail := func(field string, err error) {
+		errChan <- fieldError{field: field, err: err}
+
+		if h.strict() && !errors.Is(err, client.ErrDisabled) {
+			cancel()
+		}
+	}
+
+	var age client.Age
+	var gender client.Gender
+	var nationality client.Nationality
+
+	hintFailed := false
+
+	if p.Localization == "" && h.config.NationalityHint {
+		var err error
+
+		nationality, err = h.Client.GetNationality(ctx, p.Name)
+		if err != nil {
+			if h.strict() && !errors.Is(err, client.ErrDisabled) {
+				return storage.Person{}, err
+			}
+			fail("nationality", err)
+		}
+		hintFailed = err != nil
+
+		if h.confident(nationality) {
+			p.Localization = nationality.Nationality
+		}
+	}
+
+	wg := sync.WaitGroup{}
+
+	wg.Add(1)
+
+	go func() {
+		var err error
+
+		age, err = h.Client.GetAge(ctx, p.Name, p.Localization)
+		if err != nil {
+			fail("age", err)
+		}
+
+		wg.Done()
+	}()
+
+	wg.Add(1)
+
+	go func() {
+		var err error
+
+		gender, err = h.Client.GetGender(ctx, p.Name, p.Localization)
+		if err != nil {
+			fail("gender", err)
+		}
+
+		wg.Done()
+	}()
+
+	if nationality.Nationality == "" && !hintFailed {
+		wg.Add(1)
+
+		go func() {
+			var err error
+
+			nationality, err = h.Client.GetNationality(ctx, p.Name)
+			if err != nil {
+				fail("nationality", err)
+			}
+
+			wg.Done()
+		}()
+	}
+
+	wg.Wait()
+
+	close(errChan)
+
+	var first error
+
+	skipped := 0
+	failed := map[string]bool{}
+	fields := []string{}
+	statuses := map[string]string{}
+
+	for e := range errChan {
+		if errors.Is(e.err, client.ErrDisabled) {
+			statuses[e.field] = storage.FieldSkipped
+			skipped++
+
+			continue
+		}
+
+		if first == nil {
+			first = e.err
+		}
+		failed[e.field] = true
+		fields = append(fields, e.field)
+		statuses[e.field] = storage.FieldFailed
+	}
+
+	if first != nil && (h.strict() || len(failed) == 3-skipped) {
+		return storage.Person{}, first
+	}
+
+	if first != nil {
+		h.log.Error(
+			"часть провайдеров обогащения не ответила, поля оставлены пустыми",
+			slog.String("source", source),
+			slog.String("op", op),
+			slog.Any("fields", fields),
+			slog.Any("error", first),
+		)
+	}
+
+	return h.enrich(p, age, gender, nationality, statuses), nil
+}
+
+func (h Handlers) createError(op string, err error) error {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		h.log.Error(
+			"в хранилище нет соответсвующих данных",
+			slog.String("source", source),
+			slog.String("op", op),
+			slog.Any("error", err),
+		)
+
+		return fmt.Errorf("%w: %v", ErrStorageNotFound, err)
+
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(err, context.Canceled):
+		h.log.Error(
+			"хранилище не успело выполнить операцию",
+			slog.String("source", source),
+			slog.String("op", op),
+			slog.Any("error", err),
+		)
+
+		return fmt.Errorf("%w: %v", ErrTimeout, err)
+
+	default:
+		h.log.Error(
+			"внутренняя ошибка хранилища",
+			slog.String("source", source),
+			slog.String("op", op),
+			slog.Any("error", err),
+		)
+
+		return fmt.Errorf("%w: %v", ErrStorageInternal, err)
+	}
+}
+
+func (h Handlers) Create(ctx context.Context, name string, surname string, patronymic string, countryID string) (storage.Row, error) {
+	const op = "service.Create()"
+
+	h.log.Debug(
+		"данные получены сервисным слоем",
+		slog.String("source", source),
+		slog.String("op", op),
+	)
+
+	p, err := h.lookup(ctx, storage.Person{
+		Name:         name,
+		Surname:      surname,
+		Patronymic:   patronymic,
+		Localization: countryID,
+	})
+	if err != nil {
+		return storage.Row{}, h.clientError(op, err)
+	}
+
+	r, err := h.Storage.Create(ctx, p)
+	if err != nil {
+		return storage.Row{}, h.createError(op, err)
+	}
+	h.log.Debug(
+		"данные обработаны сервисным слоем",
+		slog.String("source", source),
+		slog.String("op", op),
+	)
+
+	return r, nil
+}
+
+func (h Handlers) CreateAsync(ctx context.Context, name string, surname string, patronymic string, countryID string) (storage.Row, error) {
+	const op = "service.CreateAsync()"
+
+	h.log.Debug(
+		"данные получены сервисным слоем",
+		slog.String("source", source),
+		slog.String("op", op),
+	)
+
+	r, err := h.Storage.CreateWith(ctx, storage.Person{
+		Name:             name,
+		Surname:          surname,
+		Patronymic:       patronymic,
+		Localization:     countryID,
+		EnrichmentStatus: storage.StatusPending,
+	}, func(ctx context.Context, tx *sql.Tx, r storage.Row) error {
+		_, err := h.Queue.EnqueueTx(ctx, tx, JobEnrichment, EnrichmentPayload{ID: r.ID, CountryID: countryID})
+		if err != nil {
+			h.log.Error(
+				"не удалось поставить запись в очередь обогащения",
+				slog.String("source", source),
+				slog.String("op", op),
+				slog.Int("id", r.ID),
+				slog.Any("error", err),
+			)
+		}
+		return err
+	})
+	if err != nil {
+		return storage.Row{}, h.createError(op, err)
+	}
+
+	h.log.Debug(
+		"данные обработаны сервисным слоем",
+		slog.String("source", source),
+		slog.String("op", op),
+	)
+
+	return r, nil
+}
+
+func retryable(err error) bool {
+	switch {
+	case errors.Is(err, client.ErrNotFound), errors.Is(err, client.ErrUnauthorized), errors.Is(err, client.ErrPaymentRequired):
+		return false
+
+	case errors.Is(err, sql.ErrNoRows), errors.Is(err, storage.ErrNormalization), errors.Is(err, storage.ErrConstraint):
+		return false
+	}
+	return true
+}
+
+func (h Handlers) EnrichJob(ctx context.Context, job queue.Job) error {
+	const op = "service.EnrichJob()"
+
+	var payload EnrichmentPayload
+
+	err := json.Unmarshal(job.Payload, &payload)
+	if err != nil {
+		return fmt.Errorf("%w: %v", queue.ErrPermanent, err)
+	}
+
+	id := strconv.Itoa(payload.ID)
+
+	lookupCtx := ctx
+	if payload.Reenrich {
+		lookupCtx = client.WithoutCache(ctx)
+	}
+
+	r, err := h.Storage.Select(ctx, storage.SelectQuery{ID: id, Limit: []int{0, 1}})
+	if err == nil {
+		var enriched storage.Person
+
+		enriched, err = h.lookup(lookupCtx, storage.Person{
+			Name:         r.Rows[0].Name,
+			Localization: payload.CountryID,
+		})
+		if err == nil {
+			enriched = retain(enriched, r.Rows[0])
+			enriched.EnrichmentStatus = storage.StatusEnriched
+
+			_, err = h.Storage.Enrich(ctx, id, enriched)
+		}
+	}
+
+	switch {
+	case err == nil:
+		h.log.Debug(
+			"запись обогащена",
+			slog.String("source", source),
+			slog.String("op", op),
+			slog.Int("id", payload.ID),
+			slog.Int("attempt", job.Attempt),
+		)
+
+		return nil
+
+	case errors.Is(err, sql.ErrNoRows):
+		h.log.Debug(
+			"запись удалена до завершения обогащения",
+			slog.String("source", source),
+			slog.String("op", op),
+			slog.Int("id", payload.ID),
+		)
+
+		return nil
+
+	case ctx.Err() != nil && !errors.Is(ctx.Err(), context.DeadlineExceeded):
+		return err
+
+	case job.Last() || !retryable(err):
+		h.log.Error(
+			"не удалось обогатить запись",
+			slog.String("source", source),
+			slog.String("op", op),
+			slog.Int("id", payload.ID),
+			slog.Int("attempt", job.Attempt),
+			slog.Any("error", err),
+		)
+
+		if payload.Reenrich {
+			return fmt.Errorf("%w: %v", queue.ErrPermanent, err)
+		}
+
+		_, serr := h.Storage.SetEnrichmentStatus(context.WithoutCancel(ctx), id, storage.StatusFailed)
+		if serr != nil {
+			return serr
+		}
+
+		return fmt.Errorf("%w: %v", queue.ErrPermanent, err)
+
+	default:
+		return err
+	}
+}
+
+func retain(p storage.Person, r storage.Row) storage.Person {
+	unresolved := func(field string) bool {
+		s := p.EnrichmentFields[field]
+
+		return s == storage.FieldFailed || s == storage.FieldSkipped
+	}
+
+	if unresolved("age") && r.Age != nil {
+		p.Age = *r.Age
+		p.AgeCount = value(r.AgeCount)
+		p.EnrichmentFields["age"] = storage.FieldEnriched
+	}
+
+	if unresolved("gender") && r.Gender != nil {
+		p.Gender = *r.Gender
+		p.GenderProbability = value(r.GenderProbability)
+		p.GenderCount = value(r.GenderCount)
+		p.EnrichmentFields["gender"] = storage.FieldEnriched
+	}
+
+	if unresolved("nationality") && r.Nationality != nil {
+		p.Nationality = *r.Nationality
+		p.NationalityProbability = value(r.NationalityProbability)
+		p.NationalityCount = value(r.NationalityCount)
+		p.NationalityCandidates = r.NationalityCandidates
+		p.EnrichmentFields["nationality"] = storage.FieldEnriched
+	}
+
+	return p
+}
+
+func value[T any](v *T) T {
+	var zero T
+
+	if v == nil {
+		return zero
+	}
+	return *v
+}
+
+func localization(r storage.Row) string {
+	if r.Localization == nil {
+		return ""
+	}
+	return *r.Localization
+}
+
+func (h Handlers) Reenrich(ctx context.Context, id string) (storage.Row, error) {
+	const op = "service.Reenrich()"
+
+	h.log.Debug(
+		"данные получены сервисным слоем",
+		slog.String("source", source),
+		slog.String("op", op),
+	)
+
+	r, err := h.Storage.Select(ctx, storage.SelectQuery{ID: id, Limit: []int{0, 1}})
+	if err != nil {
+		return storage.Row{}, h.createError(op, err)
+	}
+
+	p, err := h.lookup(client.WithoutCache(ctx), storage.Person{
+		Name:         r.Rows[0].Name,
+		Localization: localization(r.Rows[0]),
+	})
+	if err != nil {
+		return storage.Row{}, h.clientError(op, err)
+	}
+	p = retain(p, r.Rows[0])
+	p.EnrichmentStatus = storage.StatusEnriched
+
+	row, err := h.Storage.Enrich(ctx, id, p)
+	if err != nil {
+		return storage.Row{}, h.createError(op, err)
+	}
+	h.log.Debug(
+		"данные обработаны сервисным слоем",
+		slog.String("source", source),
+		slog.String("op", op),
+	)
+
+	return row, nil
+}
+
+func (h Handlers) ReenrichBatch(ctx context.Context, f storage.ReenrichFilter) (int64, error) {
+	const op = "service.ReenrichBatch()"
+
+	h.log.Debug(
+		"данные получены сервисным слоем",
+		slog.String("source", source),
+		slog.String("op", op),
+	)
+
+	id, err := h.Queue.Enqueue(ctx, JobReenrichment, ReenrichmentPayload{Filter: f})
+	if err != nil {
+		return 0, h.createError(op, err)
+	}
+	h.log.Debug(
+		"данные обработаны сервисным слоем",
+		slog.String("source", source),
+		slog.String("op", op),
+	)
+
+	return id, nil
+}
+
+func (h Handlers) ReenrichJob(ctx context.Context, job queue.Job) error {
+	const op = "service.ReenrichJob()"
+
+	var payload ReenrichmentPayload
+
+	err := json.Unmarshal(job.Payload, &payload)
+	if err != nil {
+		return fmt.Errorf("%w: %v", queue.ErrPermanent, err)
+	}
+
+	limit := max(1, h.config.SelectLimit)
+
+	rows, err := h.Storage.SelectReenrich(ctx, storage.ReenrichQuery{
+		Filter:     payload.Filter,
+		Thresholds: h.config.Thresholds,
+		After:      payload.After,
+		Limit:      limit,
+	})
+	if err != nil {
+		return err
+	}
+
+	origin := payload.Origin
+	if origin == 0 {
+		origin = job.ID
+	}
+
+	tasks := make([]queue.Task, 0, len(rows)+1)
+
+	for _, r := range rows {
+		tasks = append(tasks, queue.Task{
+			Type:    JobEnrichment,
+			Key:     fmt.Sprintf("reenrich:%d", r.ID),
+			Payload: EnrichmentPayload{ID: r.ID, CountryID: localization(r), Reenrich: true},
+		})
+	}
+
+	if len(rows) == limit {
+		after := rows[len(rows)-1].ID
+
+		tasks = append(tasks, queue.Task{
+			Type:    JobReenrichment,
+			Key:     fmt.Sprintf("%d:%d", origin, after),
+			Payload: ReenrichmentPayload{Filter: payload.Filter, After: after, Origin: origin},
+		})
+	}
+
+	err = h.Queue.EnqueueBatch(ctx, tasks...)
+	if err != nil {
+		return err
+	}
+
+	h.log.Debug(
+		"записи поставлены в очередь повторного обогащения",
+		slog.String("source", source),
+		slog.String("op", op),
+		slog.Int("after", payload.After),
+		slog.Int("queued", len(rows)),
+	)
+
+	return nil
+}
+
+func (h Handlers) Export(ctx context.Context, filters []storage.Filter) (int64, error) {
+	const op = "service.Export()"
+
+	h.log.Debug(
+		"данные получены сервисным слоем",
+		slog.String("source", source),
+		slog.String("op", op),
+	)
+
+	id, err := h.Queue.Enqueue(ctx, JobExport, ExportPayload{Filters: filters})
+	if err != nil {
+		return 0, h.createError(op, err)
+	}
+	h.log.Debug(
+		"данные обработаны сервисным слоем",
+		slog.String("source", source),
+		slog.String("op", op),
+	)
+
+	return id, nil
+}
+
+func (h Handlers) exportPath(id int64) string {
+	return filepath.Join(h.config.ExportDir, fmt.Sprintf("%d.ndjson", id))
+}
+
+type ExportResult struct {
+	File      io.ReadCloser
+	Status    string
+	LastError string
+}
+
+func (h Handlers) openExport(op string, id int64) (io.ReadCloser, bool, error) {
+	f, err := os.Open(h.exportPath(id))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, false, nil
+		}
+
+		h.log.Error(
+			"не удалось открыть выгрузку",
+			slog.String("source", source),
+			slog.String("op", op),
+			slog.Any("error", err),
+		)
+
+		return nil, false, fmt.Errorf("%w: %v", ErrStorageInternal, err)
+	}
+
+	return f, true, nil
+}
+
+func (h Handlers) ExportFile(ctx context.Context, id int64) (ExportResult, error) {
+	const op = "service.ExportFile()"
+
+	h.log.Debug(
+		"данные получены сервисным слоем",
+		slog.String("source", source),
+		slog.String("op", op),
+	)
+
+	f, ok, err := h.openExport(op, id)
+	if err != nil {
+		return ExportResult{}, err
+	}
+
+	if ok {
+		h.log.Debug(
+			"данные обработаны сервисным слоем",
+			slog.String("source", source),
+			slog.String("op", op),
+		)
+
+		return ExportResult{File: f}, nil
+	}
+
+	state, err := h.Queue.State(ctx, id)
+	if err != nil && !errors.Is(err, queue.ErrNotFound) {
+		return ExportResult{}, h.createError(op, err)
+	}
+
+	if errors.Is(err, queue.ErrNotFound) {
+		f, ok, err = h.openExport(op, id)
+		if err != nil {
+			return ExportResult{}, err
+		}
+
+		if ok {
+			return ExportResult{File: f}, nil
+		}
+	}
+
+	if state.Type != JobExport {
+		h.log.Debug(
+			"выгрузка не найдена",
+			slog.String("source", source),
+			slog.String("op", op),
+			slog.Int64("id", id),
+		)
+
+		return ExportResult{}, fmt.Errorf("%w: export %d", ErrStorageNotFound, id)
+	}
+
+	h.log.Debug(
+		"выгрузка еще не готова",
+		slog.String("source", source),
+		slog.String("op", op),
+		slog.Int64("id", id),
+		slog.String("status", state.Status),
+	)
+
+	return ExportResult{Status: state.Status, LastError: state.LastError}, nil
+}
+
+func (h Handlers) ExportJob(ctx context.Context, job queue.Job) error {
+	const op = "service.ExportJob()"
+
+	var payload ExportPayload
+
+	err := json.Unmarshal(job.Payload, &payload)
+	if err != nil {
+		return fmt.Errorf("%w: %v", queue.ErrPermanent, err)
+	}
+
+	err = os.MkdirAll(h.config.ExportDir, 0o755)
+	if err != nil {
+		return err
+	}
+
+	path := h.exportPath(job.ID)
+	tmp := path + ".tmp"
+
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp)
+	defer f.Close()
+
+	w := bufio.NewWriter(f)
+	e := json.NewEncoder(w)
+
+	limit := max(1, h.config.SelectLimit)
+	cursor := ""
+	exported := 0
+
+	for {
+		r, err := h.Storage.Select(ctx, storage.SelectQuery{
+			Limit:   []int{0, limit},
+			Filters: payload.Filters,
+			Cursor:  cursor,
+		})
+		if err != nil {
+			if errors.Is(err, storage.ErrInvalidFilter) || errors.Is(err, storage.ErrInvalidCursor) {
+				return fmt.Errorf("%w: %v", queue.ErrPermanent, err)
+			}
+			return err
+		}
+
+		for _, row := range r.Rows {
+			err = e.Encode(row)
+			if err != nil {
+				return err
+			}
+		}
+		exported += len(r.Rows)
+
+		if !r.HasMore {
+			break
+		}
+		cursor = r.NextCursor
+	}
+
+	err = w.Flush()
+	if err != nil {
+		return err
+	}
+
+	err = f.Sync()
+	if err != nil {
+		return err
+	}
+
+	err = f.Close()
+	if err != nil {
+		return err
+	}
+
+	err = os.Rename(tmp, path)
+	if err != nil {
+		return err
+	}
+
+	h.log.Debug(
+		"выгрузка сохранена",
+		slog.String("source", source),
+		slog.String("op", op),
+		slog.Int64("id", job.ID),
+		slog.Int("rows", exported),
+	)
+
+	return nil
+}
+
+type CreateBatchResult struct {
+	Created []storage.Row `json:"created"`
+	Skipped []int         `json:"skipped"`
+}
+
+func (h Handlers) CreateBatch(ctx context.Context, people []storage.Person) (CreateBatchResult, error) {
+	const op = "service.CreateBatch()"
+
+	h.log.Debug(
+		"данные получены сервисным слоем",
+		slog.String("source", source),
+		slog.String("op", op),
+	)
+
+	names := make([]string, 0, len(people))
+
+	for _, p := range people {
+		names = append(names, p.Name)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	errChan := make(chan fieldError, 3)
+
+	fail := func(field string, err error) {
+		errChan <- fieldError{field: field, err: err}
+
+		if h.strict() && !errors.Is(err, client.ErrDisabled) {
+			cancel()
+		}
+	}
+
+	ages := map[string]map[string]client.Age{}
+	genders := map[string]map[string]client.Gender{}
+
+	var nationalities map[string]client.Nationality
+
+	hintFailed := false
+
+	if h.config.NationalityHint {
+		var err error
+
+		nationalities, err = h.Client.GetNationalities(ctx, names)
+		if err != nil {
+			if h.strict() && !errors.Is(err, client.ErrDisabled) {
+				return CreateBatchResult{}, h.clientError(op, err)
+			}
+			fail("nationality", err)
+		}
+		hintFailed = err != nil
+	}
+
+	localizations := make([]string, len(people))
+	groups := map[string][]string{}
+
+	for i, p := range people {
+		localization := p.CountryID
+		if localization == "" && h.confident(nationalities[p.Name]) {
+			localization = nationalities[p.Name].Nationality
+		}
+
+		localizations[i] = localization
+		groups[localization] = append(groups[localization], p.Name)
+	}
+
+	wg := sync.WaitGroup{}
+
+	wg.Add(1)
+
+	go func() {
+		for localization, names := range groups {
+			a, err := h.Client.GetAges(ctx, names, localization)
+			if err != nil {
+				fail("age", err)
+
+				break
+			}
+			ages[localization] = a
+		}
+
+		wg.Done()
+	}()
+
+	wg.Add(1)
+
+	go func() {
+		for localization, names := range groups {
+			g, err := h.Client.GetGenders(ctx, names, localization)
+			if err != nil {
+				fail("gender", err)
+
+				break
+			}
+			genders[localization] = g
+		}
+
+		wg.Done()
+	}()
+
+	if nationalities == nil && !hintFailed {
+		wg.Add(1)
+
+		go func() {
+			var err error
+
+			nationalities, err = h.Client.GetNationalities(ctx, names)
+			if err != nil {
+				fail("nationality", err)
+			}
+
+			wg.Done()
+		}()
+	}
+
+	wg.Wait()
+
+	close(errChan)
+
+	var first error
+
+	skipped := 0
+	failed := map[string]bool{}
+	fields := []string{}
+	statuses := map[string]string{}
+
+	for e := range errChan {
+		if errors.Is(e.err, client.ErrDisabled) {
+			statuses[e.field] = storage.FieldSkipped
+			skipped++
+
+			continue
+		}
+
+		if first == nil {
+			first = e.err
+		}
+		failed[e.field] = true
+		fields = append(fields, e.field)
+		statuses[e.field] = storage.FieldFailed
+	}
+
+	if first != nil && (h.strict() || len(failed) == 3-skipped) {
+		return CreateBatchResult{}, h.clientError(op, first)
+	}
+
+	if first != nil {
+		h.log.Error(
+			"часть провайдеров обогащения не ответила, поля оставлены пустыми",
+			slog.String("source", source),
+			slog.String("op", op),
+			slog.Any("fields", fields),
+			slog.Any("error", first),
+		)
+	}
+
+	result := CreateBatchResult{
+		Created: []storage.Row{},
+		Skipped: []int{},
+	}
+
+	enriched := make([]storage.Person, 0, len(people))
+
+	for i, p := range people {
+		age, okAge := ages[localizations[i]][p.Name]
+		gender, okGender := genders[localizations[i]][p.Name]
+		nationality, okNationality := nationalities[p.Name]
+
+		unresolved := map[string]string{}
+
+		if !okAge {
+			unresolved["age"] = statuses["age"]
+		}
+		if !okGender {
+			unresolved["gender"] = statuses["gender"]
+		}
+		if !okNationality {
+			unresolved["nationality"] = statuses["nationality"]
+		}
+
+		missing := false
+		failures := 0
+
+		for field, status := range unresolved {
+			switch status {
+			case "":
+				missing = true
+				failures++
+				unresolved[field] = storage.FieldFailed
+
+			case storage.FieldFailed:
+				failures++
+			}
+		}
+
+		if missing && (h.strict() || failures == 3-skipped) {
+			result.Skipped = append(result.Skipped, i)
+
+			continue
+		}
+
+		p.Localization = localizations[i]
+
+		enriched = append(enriched, h.enrich(p, age, gender, nationality, unresolved))
+	}
+
+	if len(enriched) == 0 {
+		return result, nil
+	}
+
+	rows, err := h.Storage.CreateBatch(ctx, enriched)
+	if err != nil {
+		switch {
+		case errors.Is(err, context.DeadlineExceeded), errors.Is(err, context.Canceled):
+			h.log.Error(
+				"хранилище не успело выполнить операцию",
+				slog.String("source", source),
+				slog.String("op", op),
+				slog.Any("error", err),
+			)
+
+			return CreateBatchResult{}, fmt.Errorf("%w: %v", ErrTimeout, err)
+
+		default:
+			h.log.Error(
+				"внутренняя ошибка хранилища",
+				slog.String("source", source),
+				slog.String("op", op),
+				slog.Any("error", err),
+			)
+
+			return CreateBatchResult{}, fmt.Errorf("%w: %v", ErrStorageInternal, err)
+		}
+	}
+	result.Created = rows
+
+	h.log.Debug(
+		"данные обработаны сервисным слоем",
+		slog.String("source", source),
+		slog.String("op", op),
+	)
+
+	return result, nil
+}
+
+func (h Handlers) Select(ctx context.Context, q storage.SelectQuery) (storage.SelectResult, error) {
+	const op = "service.Select()"
+
+	h.log.Debug(
+		"данные получены сервисным слоем",
+		slog.String("source", source),
+		slog.String("op", op),
+	)
+
+	r, err := h.Storage.Select(ctx, q)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows) && q.ID != "":
+			h.log.Error(
+				"в хранилище нет записи с таким идентификатором",
+				slog.String("source", source),
+				slog.String("op", op),
+				slog.Any("error", err),
+			)
+
+			return storage.SelectResult{}, fmt.Errorf("%w: %v", ErrStorageNotFound, err)
+
+		case errors.Is(err, storage.ErrInvalidFilter), errors.Is(err, storage.ErrInvalidSort), errors.Is(err, storage.ErrInvalidCursor), errors.Is(err, storage.ErrNormalization):
+			h.log.Error(
+				"хранилище не смогло обработать фильтры, сортировку или курсор",
+				slog.String("source", source),
+				slog.String("op", op),
+				slog.Any("error", err),
+			)
+
+			return storage.SelectResult{}, fmt.Errorf("%w: %v", ErrStorageInvalid, err)
+
+		case errors.Is(err, context.DeadlineExceeded), errors.Is(err, context.Canceled):
+			h.log.Error(
+				"хранилище не успело выполнить операцию",
+				slog.String("source", source),
+				slog.String("op", op),
+				slog.Any("error", err),
+			)
+
+			return storage.SelectResult{}, fmt.Errorf("%w: %v", ErrTimeout, err)
+
+		default:
+			h.log.Error(
+				"внутренняя ошибка хранилища",
+				slog.String("source", source),
+				slog.String("op", op),
+				slog.Any("error", err),
+			)
+
+			return storage.SelectResult{}, fmt.Errorf("%w: %v", ErrStorageInternal, err)
+		}
+	}
+	h.log.Debug(
+		"данные обработаны сервисным слоем",
+		slog.String("source", source),
+		slog.String("op", op),
+	)
+
+	return r, nil
+}
+
+func (h Handlers) History(ctx context.Context, id string) ([]storage.HistoryEntry, error) {
+	const op = "service.History()"
+
+	h.log.Debug(
+		"данные получены сервисным слоем",
+		slog.String("source", source),
+		slog.String("op", op),
+	)
+
+	r, err := h.Storage.History(ctx, id)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			h.log.Error(
+				"в хранилище нет соответсвующих данных",
+				slog.String("source", source),
+				slog.String("op", op),
+				slog.Any("error", err),
+			)
+
+			return nil, fmt.Errorf("%w: %v", ErrStorageNotFound, err)
+
+		case errors.Is(err, context.DeadlineExceeded), errors.Is(err, context.Canceled):
+			h.log.Error(
+				"хранилище не успело выполнить операцию",
+				slog.String("source", source),
+				slog.String("op", op),
+				slog.Any("error", err),
+			)
+
+			return nil, fmt.Errorf("%w: %v", ErrTimeout, err)
+
+		default:
+			h.log.Error(
+				"внутренняя ошибка хранилища",
+				slog.String("source", source),
+				slog.String("op", op),
+				slog.Any("error", err),
+			)
+
+			return nil, fmt.Errorf("%w: %v", ErrStorageInternal, err)
+		}
+	}
+	h.log.Debug(
+		"данные обработаны сервисным слоем",
+		slog.String("source", source),
+		slog.String("op", op),
+	)
+
+	return r, nil
+}
+
+func (h Handlers) SelectAsOf(ctx context.Context, id string, asOf time.Time) (storage.Row, error) {
+	const op = "service.SelectAsOf()"
+
+	h.log.Debug(
+		"данные получены сервисным слоем",
+		slog.String("source", source),
+		slog.String("op", op),
+	)
+
+	r, err := h.Storage.SelectAsOf(ctx, id, asOf)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			h.log.Error(
+				"в хранилище нет соответсвующих данных",
+				slog.String("source", source),
+				slog.String("op", op),
+				slog.Any("error", err),
+			)
+
+			return storage.Row{}, fmt.Errorf("%w: %v", ErrStorageNotFound, err)
+
+		case errors.Is(err, context.DeadlineExceeded), errors.Is(err, context.Canceled):
+			h.log.Error(
+				"хранилище не успело выполнить операцию",
+				slog.String("source", source),
+				slog.String("op", op),
+				slog.Any("error", err),
+			)
+
+			return storage.Row{}, fmt.Errorf("%w: %v", ErrTimeout, err)
+
+		default:
+			h.log.Error(
+				"внутренняя ошибка хранилища",
+				slog.String("source", source),
+				slog.String("op", op),
+				slog.Any("error", err),
+			)
+
+			return storage.Row{}, fmt.Errorf("%w: %v", ErrStorageInternal, err)
+		}
+	}
+	h.log.Debug(
+		"данные обработаны сервисным слоем",
+		slog.String("source", source),
+		slog.String("op", op),
+	)
+
+	return r, nil
+}
+
+func (h Handlers) Stats() client.Stats {
+	return h.Client.Stats()
+}
+
+// МОКИ
+
+type UnimplementedHandlers struct{}
+
+func nextVersion(versions []int) int {
+	return slices.Max(append([]int{0}, versions...)) + 1
+}
+
+func (u UnimplementedHandlers) DeleteByID(ctx context.Context, id string, versions []int) error {
+	switch id {
+	case "s412":
+		return ErrStorageVersion
+
+	case "s404":
+		return ErrStorageNotFound
+
+	case "s504":
+		return ErrTimeout
+
+	case "s500":
+		return ErrStorageInternal
+	}
+	return nil
+}
+
+func (u UnimplementedHandlers) UpdateByID(ctx context.Context, id string, data []byte, versions []int) (storage.Row, error) {
+	switch id {
+	case "s404":
+		return storage.Row{}, ErrStorageNotFound
+
+	case "s504":
+		return storage.Row{}, ErrTimeout
+
+	case "s409":
+		return storage.Row{}, ErrStorageConflict
+
+	case "s412":
+		return storage.Row{}, ErrStorageVersion
+
+	case "s500":
+		return storage.Row{}, ErrStorageInternal
+	}
+	return storage.Row{ID: 1, Version: nextVersion(versions)}, nil
+}
+
+func (u UnimplementedHandlers) PatchByID(ctx context.Context, id string, patch storage.Patch, versions []int) (storage.Row, error) {
+	switch id {
+	case "s412":
+		return storage.Row{}, ErrStorageVersion
+
+	case "s400":
+		return storage.Row{}, ErrStorageInvalid
+
+	case "s404":
+		return storage.Row{}, ErrStorageNotFound
+
+	case "s409":
+		return storage.Row{}, ErrStorageConflict
+
+	case "s500":
+		return storage.Row{}, ErrStorageInternal
+
+	case "s504":
+		return storage.Row{}, ErrTimeout
+	}
+	return storage.Row{ID: 1, Version: nextVersion(versions)}, nil
+}
+
+func (u UnimplementedHandlers) RestoreByID(ctx context.Context, id string, versions []int) (storage.Row, error) {
+	switch id {
+	case "s404":
+		return storage.Row{}, ErrStorageNotFound
+
+	case "s409":
+		return storage.Row{}, ErrStorageConflict
+
+	case "s412":
+		return storage.Row{}, ErrStorageVersion
+
+	case "s500":
+		return storage.Row{}, ErrStorageInternal
+
+	case "s504":
+		return storage.Row{}, ErrTimeout
+	}
+	return storage.Row{ID: 1, Version: nextVersion(versions)}, nil
+}
+
+func (u UnimplementedHandlers) Purge(ctx context.Context, retention time.Duration) (int64, error) {
+	switch retention {
+	case 500 * time.Hour:
+		return 0, ErrStorageInternal
+
+	case 504 * time.Hour:
+		return 0, ErrTimeout
+	}
+	return 1, nil
+}
+
+func (u UnimplementedHandlers) Create(ctx context.Context, name string, surname string, patronymic string, countryID string) (storage.Row, error) {
+	switch name {
+	case "s404":
+		return storage.Row{}, ErrStorageNotFound
+
+	case "504":
+		return storage.Row{}, ErrTimeout
+
+	case "c404":
+		return storage.Row{}, ErrClientNotFound
+
+	case "c503":
+		return storage.Row{}, ErrClientUnavailable
+
+	case "c401":
+		return storage.Row{}, ErrClientUnauthorized
+
+	case "c402":
+		return storage.Row{}, ErrClientPaymentRequired
+
+	case "c429":
+		return storage.Row{}, fmt.Errorf("%w: %w", ErrClientRateLimited, &client.RateLimitError{Provider: client.ProviderAgify, RetryAfter: 1500 * time.Millisecond})
+
+	case "500":
+		return storage.Row{}, ErrStorageInternal
+
+	case "partial":
+		age := 21
+
+		return storage.Row{
+			ID:               1,
+			Name:             name,
+			Surname:          surname,
+			Age:              &age,
+			NeedsReview:      true,
+			EnrichmentStatus: storage.StatusEnriched,
+			EnrichmentFields: map[string]string{
+				"age":         storage.FieldEnriched,
+				"gender":      storage.FieldFailed,
+				"nationality": storage.FieldFailed,
+			},
+		}, nil
+	}
+	r := storage.Row{ID: 1, Name: name, Surname: surname}
+	if patronymic != "" {
+		r.Patronymic = &patronymic
+	}
+	if countryID != "" {
+		r.Localization = &countryID
+	}
+	return r, nil
+}
+
+func (u UnimplementedHandlers) CreateAsync(ctx context.Context, name string, surname string, patronymic string, countryID string) (storage.Row, error) {
+	switch name {
+	case "504":
+		return storage.Row{}, ErrTimeout
+
+	case "500":
+		return storage.Row{}, ErrStorageInternal
+	}
+	r := storage.Row{ID: 1, Name: name, Surname: surname, EnrichmentStatus: storage.StatusPending}
+	if patronymic != "" {
+		r.Patronymic = &patronymic
+	}
+	if countryID != "" {
+		r.Localization = &countryID
+	}
+	return r, nil
+}
+
+func (u UnimplementedHandlers) CreateBatch(ctx context.Context, people []storage.Person) (CreateBatchResult, error) {
+	result := CreateBatchResult{
+		Created: []storage.Row{},
+		Skipped: []int{},
+	}
+
+	for i, p := range people {
+		switch p.Name {
+		case "504":
+			return CreateBatchResult{}, ErrTimeout
+
+		case "c503":
+			return CreateBatchResult{}, ErrClientUnavailable
+
+		case "c429":
+			return CreateBatchResult{}, fmt.Errorf("%w: %w", ErrClientRateLimited, &client.RateLimitError{Provider: client.ProviderAgify, RetryAfter: 1500 * time.Millisecond})
+
+		case "500":
+			return CreateBatchResult{}, ErrStorageInternal
+
+		case "c404":
+			result.Skipped = append(result.Skipped, i)
+
+			continue
+		}
+		r := storage.Row{ID: len(result.Created) + 1, Name: p.Name, Surname: p.Surname}
+		if p.CountryID != "" {
+			r.Localization = &p.CountryID
+		}
+		result.Created = append(result.Created, r)
+	}
+	return result, nil
+}
+
+func (u UnimplementedHandlers) Reenrich(ctx context.Context, id string) (storage.Row, error) {
+	switch id {
+	case "s404":
+		return storage.Row{}, ErrStorageNotFound
+
+	case "c404":
+		return storage.Row{}, ErrClientNotFound
+
+	case "c503":
+		return storage.Row{}, ErrClientUnavailable
+
+	case "c429":
+		return storage.Row{}, fmt.Errorf("%w: %w", ErrClientRateLimited, &client.RateLimitError{Provider: client.ProviderAgify, RetryAfter: 1500 * time.Millisecond})
+
+	case "s500":
+		return storage.Row{}, ErrStorageInternal
+
+	case "s504":
+		return storage.Row{}, ErrTimeout
+	}
+	return storage.Row{ID: 1, Version: 2, EnrichmentStatus: storage.StatusEnriched}, nil
+}
+
+func (u UnimplementedHandlers) ReenrichBatch(ctx context.Context, f storage.ReenrichFilter) (int64, error) {
+	if f.CreatedBefore != nil {
+		switch f.CreatedBefore.Year() {
+		case 500:
+			return 0, ErrStorageInternal
+
+		case 504:
+			return 0, ErrTimeout
+		}
+	}
+	return 1, nil
+}
+
+func (u UnimplementedHandlers) Export(ctx context.Context, filters []storage.Filter) (int64, error) {
+	for _, f := range filters {
+		switch f.Values[0] {
+		case "s500":
+			return 0, ErrStorageInternal
+
+		case "s504":
+			return 0, ErrTimeout
+		}
+	}
+	return 1, nil
+}
+
+func (u UnimplementedHandlers) ExportFile(ctx context.Context, id int64) (ExportResult, error) {
+	switch id {
+	case 202:
+		return ExportResult{Status: queue.StatusQueued}, nil
+
+	case 404:
+		return ExportResult{}, ErrStorageNotFound
+
+	case 410:
+		return ExportResult{Status: queue.StatusDead, LastError: "задача не может быть выполнена"}, nil
+
+	case 500:
+		return ExportResult{}, ErrStorageInternal
+
+	case 504:
+		return ExportResult{}, ErrTimeout
+	}
+	return ExportResult{File: io.NopCloser(strings.NewReader("{\"id\":1}\n"))}, nil
+}
+
+func (u UnimplementedHandlers) Select(ctx context.Context, q storage.SelectQuery) (storage.SelectResult, error) {
+	switch q.ID {
+	case "s404":
+		return storage.SelectResult{Rows: []storage.Row{}}, ErrStorageNotFound
+
+	case "s504":
+		return storage.SelectResult{Rows: []storage.Row{}}, ErrTimeout
+
+	case "s500":
+		return storage.SelectResult{Rows: []storage.Row{}}, ErrStorageInternal
+
+	case "etag":
+		return storage.SelectResult{Rows: []storage.Row{{ID: 1, Version: 3}}}, nil
+	}
+
+	switch q.Cursor {
+	case "s400":
+		return storage.SelectResult{Rows: []storage.Row{}}, ErrStorageInvalid
+	}
+
+	for _, f := range q.Filters {
+		if f.Values[0] == "more" {
+			return storage.SelectResult{Rows: []storage.Row{}, NextCursor: "next", HasMore: true, Total: 1}, nil
+		}
+	}
+	return storage.SelectResult{Rows: []storage.Row{}}, nil
+}
+
+func (u UnimplementedHandlers) History(ctx context.Context, id string) ([]storage.HistoryEntry, error) {
+	switch id {
+	case "s404":
+		return nil, ErrStorageNotFound
+
+	case "s500":
+		return nil, ErrStorageInternal
+
+	case "s504":
+		return nil, ErrTimeout
+	}
+	return []storage.HistoryEntry{{ID: 1, PersonID: 1, Operation: storage.OperationCreate, Actor: "anonymous", After: &storage.Row{ID: 1, Version: 1}}}, nil
+}
+
+func (u UnimplementedHandlers) SelectAsOf(ctx context.Context, id string, asOf time.Time) (storage.Row, error) {
+	switch id {
+	case "s404":
+		return storage.Row{}, ErrStorageNotFound
+
+	case "s500":
+		return storage.Row{}, ErrStorageInternal
+
+	case "s504":
+		return storage.Row{}, ErrTimeout
+	}
+	return storage.Row{ID: 1, Version: 1}, nil
+}
+
+func (u UnimplementedHandlers) Stats() client.Stats {
+	return client.Stats{
+		Cache: map[string]cache.Stats{
+			client.ProviderAgify: {Hits: 1, Misses: 1},
+		},
+		Breakers: map[string]string{
+			client.ProviderAgify:     breaker.StateClosed,
+			client.ProviderGenderize: breaker.StateOpen,
+		},
+		Quota: map[string]quota.Quota{
+			client.ProviderAgify: {Limit: 1000, Remaining: 999, ResetAt: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)},
+		},
 	}
-	return []storage.Row{}, nil
 }
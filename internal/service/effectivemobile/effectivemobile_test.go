@@ -0,0 +1,782 @@
+package effectivemobile
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/xoticdsign/effectivemobile/internal/client"
+	"github.com/xoticdsign/effectivemobile/internal/lib/queue"
+	storage "github.com/xoticdsign/effectivemobile/internal/storage/postgresql"
+	"github.com/xoticdsign/effectivemobile/internal/utils/config"
+)
+
+type fakeStorage struct {
+	storage.UnimplementedHandlers
+
+	selectResult storage.SelectResult
+	selectErr    error
+
+	pages   []storage.SelectResult
+	pageErr error
+	queries []storage.SelectQuery
+
+	reenrich []storage.Row
+	enriched storage.Person
+	created  []storage.Person
+
+	committed bool
+}
+
+func (f *fakeStorage) CreateWith(ctx context.Context, p storage.Person, after storage.AfterCreate) (storage.Row, error) {
+	r := storage.Row{ID: 7, Name: p.Name, EnrichmentStatus: p.EnrichmentStatus}
+
+	err := after(ctx, nil, r)
+	if err != nil {
+		return storage.Row{}, err
+	}
+	f.committed = true
+
+	return r, nil
+}
+
+func (f *fakeStorage) CreateBatch(ctx context.Context, people []storage.Person) ([]storage.Row, error) {
+	f.created = people
+
+	rows := make([]storage.Row, 0, len(people))
+
+	for i, p := range people {
+		rows = append(rows, storage.Row{ID: i + 1, Name: p.Name})
+	}
+
+	return rows, nil
+}
+
+func (f *fakeStorage) SelectReenrich(ctx context.Context, q storage.ReenrichQuery) ([]storage.Row, error) {
+	return f.reenrich, nil
+}
+
+func (f *fakeStorage) Enrich(ctx context.Context, id string, p storage.Person) (storage.Row, error) {
+	f.enriched = p
+
+	return storage.Row{ID: 1}, nil
+}
+
+type fakeQueue struct {
+	payloads []interface{}
+	tasks    []queue.Task
+	states   map[int64]queue.State
+	err      error
+}
+
+func (f *fakeQueue) State(ctx context.Context, id int64) (queue.State, error) {
+	st, ok := f.states[id]
+	if !ok {
+		return queue.State{}, queue.ErrNotFound
+	}
+	return st, nil
+}
+
+func (f *fakeQueue) EnqueueBatch(ctx context.Context, tasks ...queue.Task) error {
+	if f.err != nil {
+		return f.err
+	}
+	f.tasks = append(f.tasks, tasks...)
+
+	return nil
+}
+
+func (f *fakeQueue) Enqueue(ctx context.Context, jobType string, payload interface{}) (int64, error) {
+	return f.EnqueueTx(ctx, nil, jobType, payload)
+}
+
+func (f *fakeQueue) EnqueueTx(ctx context.Context, tx *sql.Tx, jobType string, payload interface{}) (int64, error) {
+	if f.err != nil {
+		return 0, f.err
+	}
+	f.payloads = append(f.payloads, payload)
+
+	return int64(len(f.payloads)), nil
+}
+
+func (f *fakeStorage) Select(ctx context.Context, q storage.SelectQuery) (storage.SelectResult, error) {
+	f.queries = append(f.queries, q)
+
+	if f.pages != nil {
+		if len(f.pages) == 0 {
+			return storage.SelectResult{}, f.pageErr
+		}
+		r := f.pages[0]
+		f.pages = f.pages[1:]
+
+		return r, nil
+	}
+	return f.selectResult, f.selectErr
+}
+
+type fakeClient struct {
+	client.UnimplementedHandlers
+
+	age         client.Age
+	ageErr      error
+	gender      client.Gender
+	genderErr   error
+	nationality client.Nationality
+	nationErr   error
+
+	ages          map[string]client.Age
+	genders       map[string]client.Gender
+	nationalities map[string]client.Nationality
+}
+
+func (f *fakeClient) GetAge(ctx context.Context, name string, countryID string) (client.Age, error) {
+	return f.age, f.ageErr
+}
+
+func (f *fakeClient) GetGender(ctx context.Context, name string, countryID string) (client.Gender, error) {
+	return f.gender, f.genderErr
+}
+
+func (f *fakeClient) GetNationality(ctx context.Context, name string) (client.Nationality, error) {
+	return f.nationality, f.nationErr
+}
+
+func (f *fakeClient) GetAges(ctx context.Context, names []string, countryID string) (map[string]client.Age, error) {
+	return f.ages, f.ageErr
+}
+
+func (f *fakeClient) GetGenders(ctx context.Context, names []string, countryID string) (map[string]client.Gender, error) {
+	return f.genders, f.genderErr
+}
+
+func (f *fakeClient) GetNationalities(ctx context.Context, names []string) (map[string]client.Nationality, error) {
+	return f.nationalities, f.nationErr
+}
+
+func newHandlers(s Querier, c Clienter, q Enqueuer, cfg config.EffectiveMobileConfig) Handlers {
+	return Handlers{
+		Client:  c,
+		Storage: s,
+		Queue:   q,
+
+		log:    slog.New(slog.NewTextHandler(io.Discard, nil)),
+		config: cfg,
+	}
+}
+
+func TestSelect_Unit(t *testing.T) {
+	cases := []struct {
+		name         string
+		inQuery      storage.SelectQuery
+		inResult     storage.SelectResult
+		inErr        error
+		expectedRows []storage.Row
+		expectedErr  error
+	}{
+		{
+			name:         "no matches case",
+			inQuery:      storage.SelectQuery{Filters: []storage.Filter{{Field: "name", Operator: storage.OperatorIn, Values: []string{"Nobody"}}}, Limit: []int{0, 10}},
+			inResult:     storage.SelectResult{Rows: []storage.Row{}},
+			expectedRows: []storage.Row{},
+			expectedErr:  nil,
+		},
+		{
+			name:         "missing id case",
+			inQuery:      storage.SelectQuery{ID: "42", Limit: []int{0, 1}},
+			inErr:        sql.ErrNoRows,
+			expectedRows: nil,
+			expectedErr:  ErrStorageNotFound,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			h := newHandlers(&fakeStorage{selectResult: c.inResult, selectErr: c.inErr}, nil, nil, config.EffectiveMobileConfig{})
+
+			r, err := h.Select(context.Background(), c.inQuery)
+
+			assert.ErrorIs(t, err, c.expectedErr)
+			assert.Equal(t, c.expectedRows, r.Rows)
+		})
+	}
+}
+
+func TestLookup_Unit(t *testing.T) {
+	thresholds := config.ThresholdsConfig{AgeCount: 1, GenderProbability: 0.5, GenderCount: 1, NationalityProbability: 0.1, NationalityCount: 1}
+
+	cases := []struct {
+		name           string
+		inPolicy       string
+		inClient       *fakeClient
+		expectedPerson storage.Person
+		expectedErr    error
+	}{
+		{
+			name:     "disabled provider strict case",
+			inPolicy: PolicyStrict,
+			inClient: &fakeClient{
+				ageErr:      client.ErrDisabled,
+				gender:      client.Gender{Gender: "male", Probability: 0.9, Count: 10},
+				nationality: client.Nationality{Nationality: "RU", Probability: 0.5, Count: 10},
+			},
+			expectedPerson: storage.Person{
+				Name:                   "Dmitriy",
+				Gender:                 "male",
+				GenderProbability:      0.9,
+				GenderCount:            10,
+				Nationality:            "RU",
+				NationalityProbability: 0.5,
+				NationalityCount:       10,
+				NationalityCandidates:  []storage.Candidate{},
+				EnrichmentFields: map[string]string{
+					"age":         storage.FieldSkipped,
+					"gender":      storage.FieldEnriched,
+					"nationality": storage.FieldEnriched,
+				},
+			},
+			expectedErr: nil,
+		},
+		{
+			name:     "failed provider best effort case",
+			inPolicy: PolicyBestEffort,
+			inClient: &fakeClient{
+				ageErr:      client.ErrUnavailable,
+				gender:      client.Gender{Gender: "male", Probability: 0.9, Count: 10},
+				nationErr:   client.ErrDisabled,
+				nationality: client.Nationality{},
+			},
+			expectedPerson: storage.Person{
+				Name:                  "Dmitriy",
+				Gender:                "male",
+				GenderProbability:     0.9,
+				GenderCount:           10,
+				NationalityCandidates: []storage.Candidate{},
+				EnrichmentFields: map[string]string{
+					"age":         storage.FieldFailed,
+					"gender":      storage.FieldEnriched,
+					"nationality": storage.FieldSkipped,
+				},
+			},
+			expectedErr: nil,
+		},
+		{
+			name:     "failed provider strict case",
+			inPolicy: PolicyStrict,
+			inClient: &fakeClient{
+				ageErr: client.ErrUnavailable,
+				gender: client.Gender{Gender: "male", Probability: 0.9, Count: 10},
+			},
+			expectedPerson: storage.Person{},
+			expectedErr:    client.ErrUnavailable,
+		},
+		{
+			name:     "all enabled providers failed best effort case",
+			inPolicy: PolicyBestEffort,
+			inClient: &fakeClient{
+				ageErr:    client.ErrDisabled,
+				genderErr: client.ErrUnavailable,
+				nationErr: client.ErrUnavailable,
+			},
+			expectedPerson: storage.Person{},
+			expectedErr:    client.ErrUnavailable,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			h := newHandlers(nil, c.inClient, nil, config.EffectiveMobileConfig{EnrichmentPolicy: c.inPolicy, Thresholds: thresholds})
+
+			p, err := h.lookup(context.Background(), storage.Person{Name: "Dmitriy"})
+
+			assert.ErrorIs(t, err, c.expectedErr)
+			assert.Equal(t, c.expectedPerson, p)
+		})
+	}
+}
+
+func TestCreateBatch_Unit(t *testing.T) {
+	thresholds := config.ThresholdsConfig{AgeCount: 1, GenderProbability: 0.5, GenderCount: 1, NationalityProbability: 0.1, NationalityCount: 1}
+
+	ages := map[string]client.Age{"Dmitriy": {Age: 42, Count: 10}}
+	genders := map[string]client.Gender{"Dmitriy": {Gender: "male", Probability: 0.9, Count: 10}, "Anna": {Gender: "female", Probability: 0.9, Count: 10}}
+	nationalities := map[string]client.Nationality{"Dmitriy": {Nationality: "RU", Probability: 0.5, Count: 10}, "Anna": {Nationality: "RU", Probability: 0.5, Count: 10}}
+
+	cases := []struct {
+		name            string
+		inPolicy        string
+		inClient        *fakeClient
+		expectedSkipped []int
+		expectedFields  []map[string]string
+		expectedErr     error
+	}{
+		{
+			name:            "missing name best effort case",
+			inPolicy:        PolicyBestEffort,
+			inClient:        &fakeClient{ages: ages, genders: genders, nationalities: nationalities},
+			expectedSkipped: []int{},
+			expectedFields: []map[string]string{
+				{"age": storage.FieldEnriched, "gender": storage.FieldEnriched, "nationality": storage.FieldEnriched},
+				{"age": storage.FieldFailed, "gender": storage.FieldEnriched, "nationality": storage.FieldEnriched},
+			},
+			expectedErr: nil,
+		},
+		{
+			name:            "missing name strict case",
+			inPolicy:        PolicyStrict,
+			inClient:        &fakeClient{ages: ages, genders: genders, nationalities: nationalities},
+			expectedSkipped: []int{1},
+			expectedFields: []map[string]string{
+				{"age": storage.FieldEnriched, "gender": storage.FieldEnriched, "nationality": storage.FieldEnriched},
+			},
+			expectedErr: nil,
+		},
+		{
+			name:            "missing name in every enabled provider best effort case",
+			inPolicy:        PolicyBestEffort,
+			inClient:        &fakeClient{ageErr: client.ErrDisabled, genders: map[string]client.Gender{"Dmitriy": genders["Dmitriy"]}, nationalities: map[string]client.Nationality{"Dmitriy": nationalities["Dmitriy"]}},
+			expectedSkipped: []int{1},
+			expectedFields: []map[string]string{
+				{"age": storage.FieldSkipped, "gender": storage.FieldEnriched, "nationality": storage.FieldEnriched},
+			},
+			expectedErr: nil,
+		},
+		{
+			name:            "all enabled providers failed best effort case",
+			inPolicy:        PolicyBestEffort,
+			inClient:        &fakeClient{ageErr: client.ErrDisabled, genderErr: client.ErrUnavailable, nationErr: client.ErrUnavailable},
+			expectedSkipped: nil,
+			expectedFields:  nil,
+			expectedErr:     ErrClientUnavailable,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			s := &fakeStorage{}
+
+			h := newHandlers(s, c.inClient, nil, config.EffectiveMobileConfig{EnrichmentPolicy: c.inPolicy, Thresholds: thresholds})
+
+			result, err := h.CreateBatch(context.Background(), []storage.Person{{Name: "Dmitriy"}, {Name: "Anna"}})
+
+			assert.ErrorIs(t, err, c.expectedErr)
+			assert.Equal(t, c.expectedSkipped, result.Skipped)
+
+			var fields []map[string]string
+
+			for _, p := range s.created {
+				fields = append(fields, p.EnrichmentFields)
+			}
+			assert.Equal(t, c.expectedFields, fields)
+		})
+	}
+}
+
+func TestCreateAsync_Unit(t *testing.T) {
+	cases := []struct {
+		name              string
+		inQueueErr        error
+		expectedRow       storage.Row
+		expectedCommitted bool
+		expectedPayloads  []interface{}
+		expectedErr       error
+	}{
+		{
+			name:              "happy case",
+			expectedRow:       storage.Row{ID: 7, Name: "Dmitriy", EnrichmentStatus: storage.StatusPending},
+			expectedCommitted: true,
+			expectedPayloads:  []interface{}{EnrichmentPayload{ID: 7, CountryID: "RU"}},
+			expectedErr:       nil,
+		},
+		{
+			name:              "enqueue failure case",
+			inQueueErr:        errors.New("queue is down"),
+			expectedRow:       storage.Row{},
+			expectedCommitted: false,
+			expectedPayloads:  nil,
+			expectedErr:       ErrStorageInternal,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			s := &fakeStorage{}
+			q := &fakeQueue{err: c.inQueueErr}
+
+			h := newHandlers(s, nil, q, config.EffectiveMobileConfig{})
+
+			r, err := h.CreateAsync(context.Background(), "Dmitriy", "Ushakov", "", "RU")
+
+			assert.ErrorIs(t, err, c.expectedErr)
+			assert.Equal(t, c.expectedRow, r)
+			assert.Equal(t, c.expectedCommitted, s.committed)
+			assert.Equal(t, c.expectedPayloads, q.payloads)
+		})
+	}
+}
+
+func TestExportJob_Unit(t *testing.T) {
+	filters := []storage.Filter{{Field: "nationality", Operator: storage.OperatorIn, Values: []string{"RU"}}}
+
+	cases := []struct {
+		name            string
+		inPages         []storage.SelectResult
+		inErr           error
+		expectedFile    string
+		expectedCursors []string
+		expectedErr     error
+	}{
+		{
+			name: "happy case",
+			inPages: []storage.SelectResult{
+				{Rows: []storage.Row{{ID: 1, Name: "Dmitriy"}, {ID: 2, Name: "Anna"}}, NextCursor: "next", HasMore: true},
+				{Rows: []storage.Row{{ID: 3, Name: "Ivan"}}},
+			},
+			expectedFile:    "1\n2\n3\n",
+			expectedCursors: []string{"", "next"},
+			expectedErr:     nil,
+		},
+		{
+			name:            "empty case",
+			inPages:         []storage.SelectResult{{Rows: []storage.Row{}}},
+			expectedFile:    "",
+			expectedCursors: []string{""},
+			expectedErr:     nil,
+		},
+		{
+			name: "storage failure case",
+			inPages: []storage.SelectResult{
+				{Rows: []storage.Row{{ID: 1, Name: "Dmitriy"}}, NextCursor: "next", HasMore: true},
+			},
+			inErr:           context.DeadlineExceeded,
+			expectedCursors: []string{"", "next"},
+			expectedErr:     context.DeadlineExceeded,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			dir := t.TempDir()
+
+			s := &fakeStorage{pages: c.inPages, pageErr: c.inErr}
+			h := newHandlers(s, nil, nil, config.EffectiveMobileConfig{ExportDir: dir, SelectLimit: 2})
+
+			payload, _ := json.Marshal(ExportPayload{Filters: filters})
+
+			err := h.ExportJob(context.Background(), queue.Job{ID: 42, Type: JobExport, Payload: payload})
+
+			assert.ErrorIs(t, err, c.expectedErr)
+
+			cursors := []string{}
+			for _, q := range s.queries {
+				assert.Equal(t, filters, q.Filters)
+				assert.Equal(t, []int{0, 2}, q.Limit)
+				cursors = append(cursors, q.Cursor)
+			}
+			assert.Equal(t, c.expectedCursors, cursors)
+
+			_, err = os.Stat(filepath.Join(dir, "42.ndjson.tmp"))
+			assert.ErrorIs(t, err, os.ErrNotExist)
+
+			b, err := os.ReadFile(filepath.Join(dir, "42.ndjson"))
+			if c.expectedErr != nil {
+				assert.ErrorIs(t, err, os.ErrNotExist)
+
+				return
+			}
+			assert.NoError(t, err)
+
+			ids := ""
+			for _, line := range strings.SplitAfter(string(b), "\n") {
+				if line == "" {
+					continue
+				}
+
+				var r storage.Row
+
+				assert.NoError(t, json.Unmarshal([]byte(line), &r))
+				ids += fmt.Sprintf("%d\n", r.ID)
+			}
+			assert.Equal(t, c.expectedFile, ids)
+		})
+	}
+}
+
+func TestExportFile_Unit(t *testing.T) {
+	dir := t.TempDir()
+
+	err := os.WriteFile(filepath.Join(dir, "1.ndjson"), []byte("{\"id\":1}\n"), 0o644)
+	assert.NoError(t, err)
+
+	q := &fakeQueue{
+		states: map[int64]queue.State{
+			2: {Type: JobExport, Status: queue.StatusRunning, Attempt: 1},
+			3: {Type: JobExport, Status: queue.StatusDead, Attempt: 3, LastError: "время ожидания истекло"},
+			4: {Type: JobEnrichment, Status: queue.StatusQueued},
+		},
+	}
+
+	h := newHandlers(nil, nil, q, config.EffectiveMobileConfig{ExportDir: dir})
+
+	cases := []struct {
+		name           string
+		inID           int64
+		expectedFile   string
+		expectedResult ExportResult
+		expectedErr    error
+	}{
+		{
+			name:         "ready case",
+			inID:         1,
+			expectedFile: "{\"id\":1}\n",
+			expectedErr:  nil,
+		},
+		{
+			name:           "running case",
+			inID:           2,
+			expectedResult: ExportResult{Status: queue.StatusRunning},
+			expectedErr:    nil,
+		},
+		{
+			name:           "dead case",
+			inID:           3,
+			expectedResult: ExportResult{Status: queue.StatusDead, LastError: "время ожидания истекло"},
+			expectedErr:    nil,
+		},
+		{
+			name:        "other job type case",
+			inID:        4,
+			expectedErr: ErrStorageNotFound,
+		},
+		{
+			name:        "unknown case",
+			inID:        5,
+			expectedErr: ErrStorageNotFound,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			r, err := h.ExportFile(context.Background(), c.inID)
+
+			assert.ErrorIs(t, err, c.expectedErr)
+
+			if c.expectedFile != "" {
+				b, err := io.ReadAll(r.File)
+				assert.NoError(t, err)
+				assert.NoError(t, r.File.Close())
+				assert.Equal(t, c.expectedFile, string(b))
+
+				return
+			}
+			assert.Equal(t, c.expectedResult, r)
+		})
+	}
+}
+
+func TestReenrichJob_Unit(t *testing.T) {
+	filter := storage.ReenrichFilter{LowConfidence: true}
+	ru := "RU"
+
+	cases := []struct {
+		name          string
+		inPayload     ReenrichmentPayload
+		inRows        []storage.Row
+		inQueueErr    error
+		expectedTasks []queue.Task
+		expectedErr   error
+	}{
+		{
+			name:      "full page case",
+			inPayload: ReenrichmentPayload{Filter: filter},
+			inRows:    []storage.Row{{ID: 3, Localization: &ru}, {ID: 5}},
+			expectedTasks: []queue.Task{
+				{Type: JobEnrichment, Key: "reenrich:3", Payload: EnrichmentPayload{ID: 3, CountryID: "RU", Reenrich: true}},
+				{Type: JobEnrichment, Key: "reenrich:5", Payload: EnrichmentPayload{ID: 5, Reenrich: true}},
+				{Type: JobReenrichment, Key: "9:5", Payload: ReenrichmentPayload{Filter: filter, After: 5, Origin: 9}},
+			},
+			expectedErr: nil,
+		},
+		{
+			name:      "continuation case",
+			inPayload: ReenrichmentPayload{Filter: filter, After: 5, Origin: 2},
+			inRows:    []storage.Row{{ID: 7}},
+			expectedTasks: []queue.Task{
+				{Type: JobEnrichment, Key: "reenrich:7", Payload: EnrichmentPayload{ID: 7, Reenrich: true}},
+			},
+			expectedErr: nil,
+		},
+		{
+			name:          "queue failure case",
+			inPayload:     ReenrichmentPayload{Filter: filter},
+			inRows:        []storage.Row{{ID: 3}},
+			inQueueErr:    context.DeadlineExceeded,
+			expectedTasks: nil,
+			expectedErr:   context.DeadlineExceeded,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			q := &fakeQueue{err: c.inQueueErr}
+			h := newHandlers(&fakeStorage{reenrich: c.inRows}, nil, q, config.EffectiveMobileConfig{SelectLimit: 2})
+
+			payload, _ := json.Marshal(c.inPayload)
+
+			err := h.ReenrichJob(context.Background(), queue.Job{ID: 9, Type: JobReenrichment, Payload: payload})
+
+			assert.ErrorIs(t, err, c.expectedErr)
+			assert.Equal(t, c.expectedTasks, q.tasks)
+		})
+	}
+}
+
+func TestReenrich_Unit(t *testing.T) {
+	thresholds := config.ThresholdsConfig{AgeCount: 1, GenderProbability: 0.5, GenderCount: 1, NationalityProbability: 0.5, NationalityCount: 1}
+
+	age, ageCount := 40, 900
+	gender, genderProbability, genderCount := "female", 0.97, 800
+	nationality, nationalityProbability, nationalityCount := "KZ", 0.7, 700
+
+	stored := storage.Row{
+		ID:                     1,
+		Name:                   "Dmitriy",
+		Age:                    &age,
+		AgeCount:               &ageCount,
+		Gender:                 &gender,
+		GenderProbability:      &genderProbability,
+		GenderCount:            &genderCount,
+		Nationality:            &nationality,
+		NationalityProbability: &nationalityProbability,
+		NationalityCount:       &nationalityCount,
+		NationalityCandidates:  []storage.Candidate{{CountryID: "KZ", Probability: 0.7}},
+		EnrichmentStatus:       storage.StatusEnriched,
+		EnrichmentFields: map[string]string{
+			"age":         storage.FieldEnriched,
+			"gender":      storage.FieldEnriched,
+			"nationality": storage.FieldEnriched,
+		},
+	}
+
+	raised := thresholds
+	raised.AgeCount = 1000
+
+	cases := []struct {
+		name           string
+		inStored       storage.Row
+		inClient       *fakeClient
+		inThresholds   config.ThresholdsConfig
+		expectedPerson storage.Person
+	}{
+		{
+			name:         "failed provider keeps stored value case",
+			inStored:     stored,
+			inThresholds: thresholds,
+			inClient: &fakeClient{
+				ageErr:      client.ErrUnavailable,
+				gender:      client.Gender{Gender: "male", Probability: 0.9, Count: 10},
+				nationality: client.Nationality{Nationality: "RU", Probability: 0.1, Count: 10},
+			},
+			expectedPerson: storage.Person{
+				Name:                   "Dmitriy",
+				Age:                    40,
+				AgeCount:               900,
+				Gender:                 "male",
+				GenderProbability:      0.9,
+				GenderCount:            10,
+				NationalityProbability: 0.1,
+				NationalityCount:       10,
+				NationalityCandidates:  []storage.Candidate{},
+				EnrichmentStatus:       storage.StatusEnriched,
+				EnrichmentFields: map[string]string{
+					"age":         storage.FieldEnriched,
+					"gender":      storage.FieldEnriched,
+					"nationality": storage.FieldLowConfidence,
+				},
+			},
+		},
+		{
+			name:         "empty stored value case",
+			inStored:     storage.Row{ID: 1, Name: "Dmitriy", EnrichmentStatus: storage.StatusEnriched},
+			inThresholds: thresholds,
+			inClient: &fakeClient{
+				ageErr:      client.ErrUnavailable,
+				gender:      client.Gender{Gender: "male", Probability: 0.9, Count: 10},
+				nationality: client.Nationality{Nationality: "RU", Probability: 0.9, Count: 10},
+			},
+			expectedPerson: storage.Person{
+				Name:                   "Dmitriy",
+				Gender:                 "male",
+				GenderProbability:      0.9,
+				GenderCount:            10,
+				Nationality:            "RU",
+				NationalityProbability: 0.9,
+				NationalityCount:       10,
+				NationalityCandidates:  []storage.Candidate{},
+				EnrichmentStatus:       storage.StatusEnriched,
+				EnrichmentFields: map[string]string{
+					"age":         storage.FieldFailed,
+					"gender":      storage.FieldEnriched,
+					"nationality": storage.FieldEnriched,
+				},
+			},
+		},
+		{
+			name:         "raised threshold case",
+			inStored:     stored,
+			inThresholds: raised,
+			inClient: &fakeClient{
+				age:         client.Age{Age: 40, Count: 900},
+				gender:      client.Gender{Gender: "female", Probability: 0.97, Count: 800},
+				nationality: client.Nationality{Nationality: "KZ", Probability: 0.7, Count: 700},
+			},
+			expectedPerson: storage.Person{
+				Name:                   "Dmitriy",
+				AgeCount:               900,
+				Gender:                 "female",
+				GenderProbability:      0.97,
+				GenderCount:            800,
+				Nationality:            "KZ",
+				NationalityProbability: 0.7,
+				NationalityCount:       700,
+				NationalityCandidates:  []storage.Candidate{},
+				EnrichmentStatus:       storage.StatusEnriched,
+				EnrichmentFields: map[string]string{
+					"age":         storage.FieldLowConfidence,
+					"gender":      storage.FieldEnriched,
+					"nationality": storage.FieldEnriched,
+				},
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			s := &fakeStorage{selectResult: storage.SelectResult{Rows: []storage.Row{c.inStored}}}
+			h := newHandlers(s, c.inClient, nil, config.EffectiveMobileConfig{EnrichmentPolicy: PolicyBestEffort, Thresholds: c.inThresholds})
+
+			_, err := h.Reenrich(context.Background(), "1")
+
+			assert.NoError(t, err)
+			assert.Equal(t, c.expectedPerson, s.enriched)
+
+			s.enriched = storage.Person{}
+
+			payload, _ := json.Marshal(EnrichmentPayload{ID: 1, Reenrich: true})
+
+			err = h.EnrichJob(context.Background(), queue.Job{ID: 1, Type: JobEnrichment, Payload: payload, Attempt: 1, MaxAttempts: 3})
+
+			assert.NoError(t, err)
+			assert.Equal(t, c.expectedPerson, s.enriched)
+		})
+	}
+}
@@ -1,13 +1,23 @@
 package postgresql
 
 import (
+	"bytes"
+	"context"
 	"database/sql"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 
+	"github.com/xoticdsign/effectivemobile/internal/lib/actor"
 	"github.com/xoticdsign/effectivemobile/internal/utils"
 	"github.com/xoticdsign/effectivemobile/internal/utils/config"
 )
@@ -17,10 +27,19 @@ var (
 	ErrConstraint               = fmt.Errorf("был нарушен constraint")
 	ErrNoNewValues              = fmt.Errorf("данные из запроса не отличаются от уже существующих в хранилище")
 	ErrOperationDidNotSuccessed = fmt.Errorf("операция не была выполнена")
+	ErrInvalidFilter            = fmt.Errorf("фильтр сформирован некорректно")
+	ErrInvalidSort              = fmt.Errorf("сортировка сформирована некорректно")
+	ErrInvalidCursor            = fmt.Errorf("курсор сформирован некорректно")
+	ErrInvalidPatch             = fmt.Errorf("патч сформирован некорректно")
+	ErrInvalidData              = fmt.Errorf("данные из запроса не могут быть разобраны")
+	ErrVersionMismatch          = fmt.Errorf("версия записи не совпадает с ожидаемой")
+	ErrNotDeleted               = fmt.Errorf("запись не была удалена")
 )
 
 const source = "postgresql"
 
+const columns = "id, name, surname, patronymic, age, gender, nationality, localization, age_count, gender_probability, gender_count, nationality_probability, nationality_count, nationality_candidates, needs_review, enrichment_status, enrichment_fields, version, deleted_at"
+
 type Storage struct {
 	DB *DB
 
@@ -29,10 +48,20 @@ type Storage struct {
 }
 
 type Handlerer interface {
-	DeleteByID(id string) error
-	UpdateByID(id string, data []byte) error
-	Create(name string, surname string, patronymic string, age int, gender string, nationality string) error
-	Select(id string, limit []int, filter string, value string) ([]Row, error)
+	DeleteByID(ctx context.Context, id string, versions []int) error
+	UpdateByID(ctx context.Context, id string, data []byte, versions []int) (Row, error)
+	PatchByID(ctx context.Context, id string, patch Patch, versions []int) (Row, error)
+	RestoreByID(ctx context.Context, id string, versions []int) (Row, error)
+	Purge(ctx context.Context, before time.Time) (int64, error)
+	Create(ctx context.Context, p Person) (Row, error)
+	CreateWith(ctx context.Context, p Person, after AfterCreate) (Row, error)
+	CreateBatch(ctx context.Context, people []Person) ([]Row, error)
+	Enrich(ctx context.Context, id string, p Person) (Row, error)
+	SetEnrichmentStatus(ctx context.Context, id string, status string) (Row, error)
+	SelectReenrich(ctx context.Context, q ReenrichQuery) ([]Row, error)
+	Select(ctx context.Context, q SelectQuery) (SelectResult, error)
+	History(ctx context.Context, id string) ([]HistoryEntry, error)
+	SelectAsOf(ctx context.Context, id string, asOf time.Time) (Row, error)
 }
 
 type DB struct {
@@ -64,7 +93,10 @@ func New(config config.PostgreSQLConfig, log *slog.Logger) (*Storage, error) {
 		return nil, err
 	}
 
-	err = db.Ping()
+	ctx, cancel := context.WithTimeout(context.Background(), config.Timeout)
+	defer cancel()
+
+	err = db.PingContext(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -96,13 +128,184 @@ func (s *Storage) Shutdown() error {
 }
 
 type Row struct {
-	ID          int    `json:"id" example:"1"`
-	Name        string `json:"name" example:"Ivan"`
-	Surname     string `json:"surname" example:"Petrov"`
-	Patronymic  string `json:"patronymic" example:"Ivanovich"`
-	Age         int    `json:"age" example:"21"`
-	Gender      string `json:"gender" example:"male"`
-	Nationality string `json:"nationality" example:"RU"`
+	ID           int     `json:"id" example:"1"`
+	Name         string  `json:"name" example:"Ivan"`
+	Surname      string  `json:"surname" example:"Petrov"`
+	Patronymic   *string `json:"patronymic" example:"Ivanovich"`
+	Age          *int    `json:"age" example:"21"`
+	Gender       *string `json:"gender" example:"male"`
+	Nationality  *string `json:"nationality" example:"RU"`
+	Localization *string `json:"localization" example:"RU"`
+
+	AgeCount               *int              `json:"age_count" example:"1200"`
+	GenderProbability      *float64          `json:"gender_probability" example:"0.98"`
+	GenderCount            *int              `json:"gender_count" example:"1200"`
+	NationalityProbability *float64          `json:"nationality_probability" example:"0.65"`
+	NationalityCount       *int              `json:"nationality_count" example:"1200"`
+	NationalityCandidates  []Candidate       `json:"nationality_candidates"`
+	NeedsReview            bool              `json:"needs_review" example:"false"`
+	EnrichmentStatus       string            `json:"enrichment_status" example:"enriched"`
+	EnrichmentFields       map[string]string `json:"enrichment_fields" example:"age:enriched,gender:failed,nationality:low_confidence"`
+
+	Version   int        `json:"version" example:"1"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty" example:"2024-01-01T00:00:00Z"`
+}
+
+type Candidate struct {
+	CountryID   string  `json:"country_id" example:"RU"`
+	Probability float64 `json:"probability" example:"0.65"`
+}
+
+type Person struct {
+	Name         string `json:"name" example:"Ivan"`
+	Surname      string `json:"surname" example:"Petrov"`
+	Patronymic   string `json:"patronymic" example:"Ivanovich"`
+	Age          int    `json:"-"`
+	Gender       string `json:"-"`
+	Nationality  string `json:"-"`
+	CountryID    string `json:"country_id" example:"RU"`
+	Localization string `json:"-"`
+
+	AgeCount               int               `json:"-"`
+	GenderProbability      float64           `json:"-"`
+	GenderCount            int               `json:"-"`
+	NationalityProbability float64           `json:"-"`
+	NationalityCount       int               `json:"-"`
+	NationalityCandidates  []Candidate       `json:"-"`
+	EnrichmentStatus       string            `json:"-"`
+	EnrichmentFields       map[string]string `json:"-"`
+}
+
+var (
+	StatusPending  = "pending_enrichment"
+	StatusEnriched = "enriched"
+	StatusFailed   = "enrichment_failed"
+)
+
+var (
+	FieldEnriched      = "enriched"
+	FieldLowConfidence = "low_confidence"
+	FieldFailed        = "failed"
+	FieldSkipped       = "skipped"
+)
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanRow(s scanner) (Row, error) {
+	var (
+		row        Row
+		candidates []byte
+		fields     []byte
+	)
+
+	err := s.Scan(&row.ID, &row.Name, &row.Surname, &row.Patronymic, &row.Age, &row.Gender, &row.Nationality, &row.Localization, &row.AgeCount, &row.GenderProbability, &row.GenderCount, &row.NationalityProbability, &row.NationalityCount, &candidates, &row.NeedsReview, &row.EnrichmentStatus, &fields, &row.Version, &row.DeletedAt)
+	if err != nil {
+		return Row{}, err
+	}
+
+	if candidates != nil {
+		err = json.Unmarshal(candidates, &row.NationalityCandidates)
+		if err != nil {
+			return Row{}, err
+		}
+	}
+
+	if fields != nil {
+		err = json.Unmarshal(fields, &row.EnrichmentFields)
+		if err != nil {
+			return Row{}, err
+		}
+	}
+	return row, nil
+}
+
+func lockRow(ctx context.Context, tx *sql.Tx, id string, deleted bool, config config.PostgreSQLConfig) (Row, error) {
+	condition := "deleted_at IS NULL"
+
+	if deleted {
+		condition = "deleted_at IS NOT NULL"
+	}
+
+	return scanRow(tx.QueryRowContext(ctx, fmt.Sprintf("SELECT %s FROM %s WHERE id=$1 AND %s FOR UPDATE;", columns, config.Table, condition), id))
+}
+
+var (
+	OperationCreate  = "create"
+	OperationUpdate  = "update"
+	OperationPatch   = "patch"
+	OperationDelete  = "delete"
+	OperationRestore = "restore"
+	OperationPurge   = "purge"
+	OperationEnrich  = "enrich"
+)
+
+type HistoryEntry struct {
+	ID        int       `json:"id" example:"1"`
+	PersonID  int       `json:"person_id" example:"1"`
+	Operation string    `json:"operation" example:"update"`
+	Actor     string    `json:"actor" example:"operator"`
+	ChangedAt time.Time `json:"changed_at" example:"2024-01-01T00:00:00Z"`
+	Before    *Row      `json:"before"`
+	After     *Row      `json:"after"`
+}
+
+func snapshot(row *Row) (interface{}, error) {
+	if row == nil {
+		return nil, nil
+	}
+
+	b, err := json.Marshal(row)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+func unmarshalSnapshot(b []byte) (*Row, error) {
+	if b == nil {
+		return nil, nil
+	}
+
+	var row Row
+
+	err := json.Unmarshal(b, &row)
+	if err != nil {
+		return nil, err
+	}
+	return &row, nil
+}
+
+func recordHistory(ctx context.Context, tx *sql.Tx, operation string, before *Row, after *Row, config config.PostgreSQLConfig) error {
+	var id int
+
+	switch {
+	case after != nil:
+		id = after.ID
+
+	case before != nil:
+		id = before.ID
+	}
+
+	b, err := snapshot(before)
+	if err != nil {
+		return err
+	}
+
+	a, err := snapshot(after)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, fmt.Sprintf("INSERT INTO %s (person_id, operation, actor, before, after) VALUES($1, $2, $3, $4, $5);", config.HistoryTable), id, operation, actor.FromContext(ctx), b, a)
+	return err
+}
+
+func isConstraintViolation(err error) bool {
+	var e *pq.Error
+
+	return errors.As(err, &e) && e.Code.Class() == "23"
 }
 
 type Handlers struct {
@@ -114,7 +317,11 @@ type Handlers struct {
 	config config.PostgreSQLConfig
 }
 
-func (h Handlers) DeleteByID(id string) error {
+func matchVersion(versions []int, version int) bool {
+	return len(versions) == 0 || slices.Contains(versions, version)
+}
+
+func (h Handlers) DeleteByID(ctx context.Context, id string, versions []int) error {
 	const op = "postgresql.DeleteByID()"
 
 	h.log.Debug(
@@ -124,26 +331,34 @@ func (h Handlers) DeleteByID(id string) error {
 		slog.Any("data", []string{id}),
 	)
 
-	tx, err := h.DB.Begin()
+	ctx, cancel := context.WithTimeout(ctx, h.config.Timeout)
+	defer cancel()
+
+	tx, err := h.DB.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
 	defer tx.Rollback()
 
-	query := fmt.Sprintf("DELETE FROM %s WHERE id=$1;", h.config.Table)
-
-	result, err := tx.Exec(query, id)
+	original, err := lockRow(ctx, tx, id, false, h.config)
 	if err != nil {
 		return err
 	}
 
-	rowsAffected, err := result.RowsAffected()
+	if !matchVersion(versions, original.Version) {
+		return ErrVersionMismatch
+	}
+
+	query := fmt.Sprintf("UPDATE %s SET deleted_at=now(), version=version+1 WHERE id=$1 RETURNING %s;", h.config.Table, columns)
+
+	row, err := scanRow(tx.QueryRowContext(ctx, query, id))
 	if err != nil {
 		return err
 	}
 
-	if rowsAffected == 0 {
-		return sql.ErrNoRows
+	err = recordHistory(ctx, tx, OperationDelete, &original, &row, h.config)
+	if err != nil {
+		return err
 	}
 
 	h.log.Debug(
@@ -161,15 +376,45 @@ func buildUpdateByIDQuery(id string, original []byte, update []byte) ([]interfac
 	var o Row
 	var u Row
 
-	json.Unmarshal(original, &o)
-	json.Unmarshal(update, &u)
+	err := json.Unmarshal(original, &o)
+	if err != nil {
+		return nil, ErrInvalidData
+	}
+
+	err = json.Unmarshal(update, &u)
+	if err != nil {
+		return nil, ErrInvalidData
+	}
+
+	var (
+		patronymic  string
+		age         int
+		gender      string
+		nationality string
+	)
+
+	if u.Patronymic != nil {
+		patronymic = *u.Patronymic
+	}
+
+	if u.Age != nil {
+		age = *u.Age
+	}
+
+	if u.Gender != nil {
+		gender = *u.Gender
+	}
+
+	if u.Nationality != nil {
+		nationality = *u.Nationality
+	}
 
 	n, err := utils.NormalizeInput(map[string]map[string]string{
 		"name":        {u.Name: "title"},
 		"surname":     {u.Surname: "title"},
-		"patronymic":  {u.Patronymic: "title"},
-		"gender":      {u.Gender: "lowercase"},
-		"nationality": {u.Nationality: "uppercase"},
+		"patronymic":  {patronymic: "title"},
+		"gender":      {gender: "lowercase"},
+		"nationality": {nationality: "uppercase"},
 	})
 	if err != nil {
 		return nil, ErrNormalization
@@ -177,9 +422,9 @@ func buildUpdateByIDQuery(id string, original []byte, update []byte) ([]interfac
 
 	name := n["name"]
 	surname := n["surname"]
-	patronymic := n["patronymic"]
-	gender := n["gender"]
-	nationality := n["nationality"]
+	patronymic = n["patronymic"]
+	gender = n["gender"]
+	nationality = n["nationality"]
 
 	changes := 0
 	args := []interface{}{}
@@ -198,28 +443,28 @@ func buildUpdateByIDQuery(id string, original []byte, update []byte) ([]interfac
 		args = append(args, o.Surname)
 	}
 
-	if o.Patronymic != patronymic && patronymic != "" {
+	if patronymic != "" && (o.Patronymic == nil || *o.Patronymic != patronymic) {
 		args = append(args, patronymic)
 		changes++
 	} else {
 		args = append(args, o.Patronymic)
 	}
 
-	if o.Age != u.Age && u.Age != 0 {
-		args = append(args, u.Age)
+	if age != 0 && (o.Age == nil || *o.Age != age) {
+		args = append(args, age)
 		changes++
 	} else {
 		args = append(args, o.Age)
 	}
 
-	if o.Gender != gender && gender != "" {
+	if gender != "" && (o.Gender == nil || *o.Gender != gender) {
 		args = append(args, gender)
 		changes++
 	} else {
 		args = append(args, o.Gender)
 	}
 
-	if o.Nationality != nationality && nationality != "" {
+	if nationality != "" && (o.Nationality == nil || *o.Nationality != nationality) {
 		args = append(args, nationality)
 		changes++
 	} else {
@@ -233,7 +478,7 @@ func buildUpdateByIDQuery(id string, original []byte, update []byte) ([]interfac
 	return append(args, id), nil
 }
 
-func (h Handlers) UpdateByID(id string, data []byte) error {
+func (h Handlers) UpdateByID(ctx context.Context, id string, data []byte, versions []int) (Row, error) {
 	const op = "postgresql.UpdateByID()"
 
 	h.log.Debug(
@@ -242,50 +487,59 @@ func (h Handlers) UpdateByID(id string, data []byte) error {
 		slog.String("op", op),
 	)
 
-	tx, err := h.DB.Begin()
+	ctx, cancel := context.WithTimeout(ctx, h.config.Timeout)
+	defer cancel()
+
+	tx, err := h.DB.BeginTx(ctx, nil)
 	if err != nil {
-		return err
+		return Row{}, err
 	}
 	defer tx.Rollback()
 
-	querySelect := fmt.Sprintf("SELECT name, surname, patronymic, age, gender, nationality FROM %s WHERE id=$1;", h.config.Table)
-
-	r := tx.QueryRow(querySelect, id)
-	if r.Err() != nil {
-		return r.Err()
+	original, err := lockRow(ctx, tx, id, false, h.config)
+	if err != nil {
+		return Row{}, err
 	}
 
-	var original Row
-
-	err = r.Scan(&original.Name, &original.Surname, &original.Patronymic, &original.Age, &original.Gender, &original.Nationality)
-	if err != nil {
-		return err
+	if !matchVersion(versions, original.Version) {
+		return Row{}, ErrVersionMismatch
 	}
 
 	originalByte, err := json.Marshal(original)
 	if err != nil {
-		return err
+		return Row{}, err
 	}
 
 	args, err := buildUpdateByIDQuery(id, originalByte, data)
 	if err != nil {
-		return err
+		return Row{}, err
 	}
 
-	queryUpdate := fmt.Sprintf("UPDATE %s SET name=$1, surname=$2, patronymic=$3, age=$4, gender=$5, nationality=$6 WHERE id=$7;", h.config.Table)
+	details := []string{}
 
-	result, err := tx.Exec(queryUpdate, args...)
-	if err != nil {
-		return fmt.Errorf("%w:%v", ErrConstraint, err)
+	for i, f := range []string{"age", "gender", "nationality"} {
+		details = append(details, clearDetails(f, i+4)...)
 	}
 
-	rowsAffected, err := result.RowsAffected()
+	queryUpdate := fmt.Sprintf("UPDATE %s SET name=$1, surname=$2, patronymic=$3, age=$4, gender=$5, nationality=$6, %s, version=version+1 WHERE id=$7 AND version=$8 RETURNING %s;", h.config.Table, strings.Join(details, ", "), columns)
+
+	row, err := scanRow(tx.QueryRowContext(ctx, queryUpdate, append(args, original.Version)...))
 	if err != nil {
-		return err
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return Row{}, ErrVersionMismatch
+
+		case isConstraintViolation(err):
+			return Row{}, fmt.Errorf("%w:%v", ErrConstraint, err)
+
+		default:
+			return Row{}, err
+		}
 	}
 
-	if rowsAffected == 0 {
-		return ErrOperationDidNotSuccessed
+	err = recordHistory(ctx, tx, OperationUpdate, &original, &row, h.config)
+	if err != nil {
+		return Row{}, err
 	}
 
 	h.log.Debug(
@@ -294,11 +548,113 @@ func (h Handlers) UpdateByID(id string, data []byte) error {
 		slog.String("op", op),
 	)
 
-	return tx.Commit()
+	err = tx.Commit()
+	if err != nil {
+		return Row{}, err
+	}
+
+	return row, nil
+}
+
+type Patch map[string]interface{}
+
+var enrichmentDetails = map[string][]string{
+	"age":         {"age_count"},
+	"gender":      {"gender_probability", "gender_count"},
+	"nationality": {"nationality_probability", "nationality_count", "nationality_candidates"},
+}
+
+func clearDetails(field string, placeholder int) []string {
+	sets := []string{}
+
+	for _, d := range enrichmentDetails[field] {
+		sets = append(sets, fmt.Sprintf("%s=CASE WHEN %s IS NOT DISTINCT FROM $%d THEN %s END", d, field, placeholder, d))
+	}
+
+	return sets
+}
+
+var patchNullable = map[string]bool{
+	"patronymic":  true,
+	"age":         true,
+	"gender":      true,
+	"nationality": true,
+}
+
+func buildPatchByIDQuery(id string, patch Patch, versions []int, config config.PostgreSQLConfig) (string, []interface{}, error) {
+	fields := []string{}
+
+	for f := range patch {
+		fields = append(fields, f)
+	}
+	slices.Sort(fields)
+
+	sets := []string{}
+	args := []interface{}{}
+
+	for _, f := range fields {
+		c, ok := filterCases[f]
+		if !ok {
+			return "", nil, ErrInvalidPatch
+		}
+
+		switch v := patch[f].(type) {
+		case nil:
+			if !patchNullable[f] {
+				return "", nil, ErrInvalidPatch
+			}
+			args = append(args, nil)
+
+		case string:
+			if f == "age" {
+				return "", nil, ErrInvalidPatch
+			}
+
+			n, err := utils.NormalizeInput(map[string]map[string]string{
+				f: {v: c},
+			})
+			if err != nil {
+				return "", nil, ErrNormalization
+			}
+
+			if n[f] == "" {
+				return "", nil, ErrInvalidPatch
+			}
+			args = append(args, n[f])
+
+		case int:
+			if f != "age" {
+				return "", nil, ErrInvalidPatch
+			}
+			args = append(args, v)
+
+		default:
+			return "", nil, ErrInvalidPatch
+		}
+
+		sets = append(sets, fmt.Sprintf("%s=$%d", f, len(args)))
+		sets = append(sets, clearDetails(f, len(args))...)
+	}
+
+	args = append(args, id)
+	where := fmt.Sprintf("id=$%d AND deleted_at IS NULL", len(args))
+
+	if len(versions) > 0 {
+		args = append(args, pq.Array(versions))
+		where += fmt.Sprintf(" AND version=ANY($%d)", len(args))
+	}
+
+	if len(sets) == 0 {
+		return fmt.Sprintf("SELECT %s FROM %s WHERE %s;", columns, config.Table, where), args, nil
+	}
+
+	sets = append(sets, "version=version+1")
+
+	return fmt.Sprintf("UPDATE %s SET %s WHERE %s RETURNING %s;", config.Table, strings.Join(sets, ", "), where, columns), args, nil
 }
 
-func (h Handlers) Create(name string, surname string, patronymic string, age int, gender string, nationality string) error {
-	const op = "postgresql.Create()"
+func (h Handlers) PatchByID(ctx context.Context, id string, patch Patch, versions []int) (Row, error) {
+	const op = "postgresql.PatchByID()"
 
 	h.log.Debug(
 		"старт транзакции",
@@ -306,43 +662,50 @@ func (h Handlers) Create(name string, surname string, patronymic string, age int
 		slog.String("op", op),
 	)
 
-	tx, err := h.DB.Begin()
+	ctx, cancel := context.WithTimeout(ctx, h.config.Timeout)
+	defer cancel()
+
+	tx, err := h.DB.BeginTx(ctx, nil)
 	if err != nil {
-		return err
+		return Row{}, err
 	}
 	defer tx.Rollback()
 
-	n, err := utils.NormalizeInput(map[string]map[string]string{
-		"name":        {name: "title"},
-		"surname":     {surname: "title"},
-		"patronymic":  {patronymic: "title"},
-		"gender":      {gender: "lowercase"},
-		"nationality": {nationality: "uppercase"},
-	})
+	query, args, err := buildPatchByIDQuery(id, patch, versions, h.config)
 	if err != nil {
-		return ErrNormalization
+		return Row{}, err
 	}
 
-	name = n["name"]
-	surname = n["surname"]
-	patronymic = n["patronymic"]
-	gender = n["gender"]
-	nationality = n["nationality"]
+	original, err := lockRow(ctx, tx, id, false, h.config)
+	if err != nil {
+		return Row{}, err
+	}
 
-	query := fmt.Sprintf(fmt.Sprintf("INSERT INTO %s (name, surname, patronymic, age, gender, nationality) VALUES($1, $2, $3, $4, $5, $6);", h.config.Table))
+	if !matchVersion(versions, original.Version) {
+		return Row{}, ErrVersionMismatch
+	}
 
-	result, err := tx.Exec(query, name, surname, patronymic, age, gender, nationality)
-	if err != nil {
-		return err
+	if len(patch) == 0 {
+		return original, nil
 	}
 
-	rowsAffected, err := result.RowsAffected()
+	row, err := scanRow(tx.QueryRowContext(ctx, query, args...))
 	if err != nil {
-		return err
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return Row{}, ErrVersionMismatch
+
+		case isConstraintViolation(err):
+			return Row{}, fmt.Errorf("%w:%v", ErrConstraint, err)
+
+		default:
+			return Row{}, err
+		}
 	}
 
-	if rowsAffected == 0 {
-		return ErrOperationDidNotSuccessed
+	err = recordHistory(ctx, tx, OperationPatch, &original, &row, h.config)
+	if err != nil {
+		return Row{}, err
 	}
 
 	h.log.Debug(
@@ -351,114 +714,60 @@ func (h Handlers) Create(name string, surname string, patronymic string, age int
 		slog.String("op", op),
 	)
 
-	return tx.Commit()
-}
-
-func buildSelectQuery(id string, limit []int, filter string, value string, config config.PostgreSQLConfig) (string, []interface{}, error) {
-	args := []interface{}{}
-
-	if id != "" {
-		return fmt.Sprintf("SELECT * FROM %s WHERE id=$1;", config.Table), append(args, id), nil
-	} else {
-		if filter == "" {
-			return fmt.Sprintf("SELECT * FROM %s ORDER BY id LIMIT $1 OFFSET $2;", config.Table), append(args, limit[1], limit[0]), nil
-		} else {
-			switch {
-			case filter == "name":
-				n, err := utils.NormalizeInput(map[string]map[string]string{
-					"name": {value: "title"},
-				})
-				if err != nil {
-					return "", nil, ErrNormalization
-				}
-				value = n["name"]
-
-			case filter == "surname":
-				n, err := utils.NormalizeInput(map[string]map[string]string{
-					"surname": {value: "title"},
-				})
-				if err != nil {
-					return "", nil, ErrNormalization
-				}
-				value = n["surname"]
-
-			case filter == "patronymic":
-				n, err := utils.NormalizeInput(map[string]map[string]string{
-					"patronymic": {value: "title"},
-				})
-				if err != nil {
-					return "", nil, ErrNormalization
-				}
-				value = n["patronymic"]
-
-			case filter == "gender":
-				n, err := utils.NormalizeInput(map[string]map[string]string{
-					"gender": {value: "lowercase"},
-				})
-				if err != nil {
-					return "", nil, ErrNormalization
-				}
-				value = n["gender"]
-
-			case filter == "nationality":
-				n, err := utils.NormalizeInput(map[string]map[string]string{
-					"nationality": {value: "uppercase"},
-				})
-				if err != nil {
-					return "", nil, ErrNormalization
-				}
-				value = n["nationality"]
-			}
-
-			return fmt.Sprintf("SELECT * FROM %s WHERE %s=$1 ORDER BY id LIMIT $2 OFFSET $3;", config.Table, filter), append(args, value, limit[1], limit[0]), nil
-		}
+	err = tx.Commit()
+	if err != nil {
+		return Row{}, err
 	}
+
+	return row, nil
 }
 
-func (h Handlers) Select(id string, limit []int, filter string, value string) ([]Row, error) {
-	const op = "postgresql.Select()"
+func (h Handlers) RestoreByID(ctx context.Context, id string, versions []int) (Row, error) {
+	const op = "postgresql.RestoreByID()"
 
 	h.log.Debug(
 		"старт транзакции",
 		slog.String("source", source),
 		slog.String("op", op),
+		slog.Any("data", []string{id}),
 	)
 
-	tx, err := h.DB.Begin()
-	if err != nil {
-		return nil, err
-	}
-	defer tx.Rollback()
+	ctx, cancel := context.WithTimeout(ctx, h.config.Timeout)
+	defer cancel()
 
-	query, args, err := buildSelectQuery(id, limit, filter, value, h.config)
+	tx, err := h.DB.BeginTx(ctx, nil)
 	if err != nil {
-		return nil, err
+		return Row{}, err
 	}
+	defer tx.Rollback()
 
-	r, err := tx.Query(query, args...)
+	original, err := lockRow(ctx, tx, id, true, h.config)
 	if err != nil {
-		return nil, err
-	}
-	defer r.Close()
-
-	var rows []Row
-
-	for r.Next() {
-		var row Row
+		if !errors.Is(err, sql.ErrNoRows) {
+			return Row{}, err
+		}
 
-		err := r.Scan(&row.ID, &row.Name, &row.Surname, &row.Patronymic, &row.Age, &row.Gender, &row.Nationality)
+		_, err = lockRow(ctx, tx, id, false, h.config)
 		if err != nil {
-			return nil, err
+			return Row{}, err
 		}
-		rows = append(rows, row)
+		return Row{}, ErrNotDeleted
 	}
 
-	if r.Err() != nil {
-		return nil, r.Err()
+	if !matchVersion(versions, original.Version) {
+		return Row{}, ErrVersionMismatch
+	}
+
+	query := fmt.Sprintf("UPDATE %s SET deleted_at=NULL, version=version+1 WHERE id=$1 RETURNING %s;", h.config.Table, columns)
+
+	row, err := scanRow(tx.QueryRowContext(ctx, query, id))
+	if err != nil {
+		return Row{}, err
 	}
 
-	if len(rows) == 0 {
-		return nil, sql.ErrNoRows
+	err = recordHistory(ctx, tx, OperationRestore, &original, &row, h.config)
+	if err != nil {
+		return Row{}, err
 	}
 
 	h.log.Debug(
@@ -467,25 +776,1103 @@ func (h Handlers) Select(id string, limit []int, filter string, value string) ([
 		slog.String("op", op),
 	)
 
-	return rows, nil
+	err = tx.Commit()
+	if err != nil {
+		return Row{}, err
+	}
+
+	return row, nil
 }
 
-// МОКИ
+func (h Handlers) Purge(ctx context.Context, before time.Time) (int64, error) {
+	const op = "postgresql.Purge()"
 
-type UnimplementedHandlers struct{}
+	h.log.Debug(
+		"старт транзакции",
+		slog.String("source", source),
+		slog.String("op", op),
+		slog.Time("before", before),
+	)
 
-func (u UnimplementedHandlers) DeleteByID(id string) error {
-	return nil
-}
+	ctx, cancel := context.WithTimeout(ctx, h.config.Timeout)
+	defer cancel()
 
-func (u UnimplementedHandlers) UpdateByID(id string, data []byte) error {
-	return nil
-}
+	tx, err := h.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
 
-func (u UnimplementedHandlers) Create(name string, surname string, patronymic string, age int, gender string, nationality string) error {
-	return nil
-}
+	query := fmt.Sprintf("WITH purged AS (DELETE FROM %s WHERE deleted_at IS NOT NULL AND deleted_at < $1 RETURNING *), recorded AS (INSERT INTO %s (person_id, operation, actor, before) SELECT id, $2::text, $3::text, to_jsonb(purged) FROM purged RETURNING 1) SELECT COUNT(*) FROM recorded;", h.config.Table, h.config.HistoryTable)
 
-func (u UnimplementedHandlers) Select(id string, limit []int, filter string, value string) ([]Row, error) {
-	return []Row{}, nil
+	var rowsAffected int64
+
+	err = tx.QueryRowContext(ctx, query, before, OperationPurge, actor.FromContext(ctx)).Scan(&rowsAffected)
+	if err != nil {
+		return 0, err
+	}
+
+	h.log.Debug(
+		"транзакция завершена",
+		slog.String("source", source),
+		slog.String("op", op),
+		slog.Int64("purged", rowsAffected),
+	)
+
+	err = tx.Commit()
+	if err != nil {
+		return 0, err
+	}
+
+	return rowsAffected, nil
+}
+
+type AfterCreate func(ctx context.Context, tx *sql.Tx, r Row) error
+
+func (h Handlers) Create(ctx context.Context, p Person) (Row, error) {
+	return h.CreateWith(ctx, p, nil)
+}
+
+func (h Handlers) CreateWith(ctx context.Context, p Person, after AfterCreate) (Row, error) {
+	const op = "postgresql.CreateWith()"
+
+	h.log.Debug(
+		"старт транзакции",
+		slog.String("source", source),
+		slog.String("op", op),
+	)
+
+	ctx, cancel := context.WithTimeout(ctx, h.config.Timeout)
+	defer cancel()
+
+	tx, err := h.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return Row{}, err
+	}
+	defer tx.Rollback()
+
+	row, err := insert(ctx, tx, p, h.config)
+	if err != nil {
+		return Row{}, err
+	}
+
+	if after != nil {
+		err = after(ctx, tx, row)
+		if err != nil {
+			return Row{}, err
+		}
+	}
+
+	h.log.Debug(
+		"транзакция завершена",
+		slog.String("source", source),
+		slog.String("op", op),
+	)
+
+	err = tx.Commit()
+	if err != nil {
+		return Row{}, err
+	}
+
+	return row, nil
+}
+
+func (h Handlers) CreateBatch(ctx context.Context, people []Person) ([]Row, error) {
+	const op = "postgresql.CreateBatch()"
+
+	h.log.Debug(
+		"старт транзакции",
+		slog.String("source", source),
+		slog.String("op", op),
+	)
+
+	ctx, cancel := context.WithTimeout(ctx, h.config.Timeout)
+	defer cancel()
+
+	tx, err := h.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	rows := make([]Row, 0, len(people))
+
+	for _, p := range people {
+		row, err := insert(ctx, tx, p, h.config)
+		if err != nil {
+			return nil, err
+		}
+		rows = append(rows, row)
+	}
+
+	h.log.Debug(
+		"транзакция завершена",
+		slog.String("source", source),
+		slog.String("op", op),
+	)
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	return rows, nil
+}
+
+func enrichment(p Person) ([]interface{}, error) {
+	n, err := utils.NormalizeInput(map[string]map[string]string{
+		"gender":       {p.Gender: "lowercase"},
+		"nationality":  {p.Nationality: "uppercase"},
+		"localization": {p.Localization: "uppercase"},
+	})
+	if err != nil {
+		return nil, ErrNormalization
+	}
+
+	candidates, err := json.Marshal(p.NationalityCandidates)
+	if err != nil {
+		return nil, err
+	}
+
+	fields, err := json.Marshal(p.EnrichmentFields)
+	if err != nil {
+		return nil, err
+	}
+
+	status := p.EnrichmentStatus
+	if status == "" {
+		status = StatusEnriched
+	}
+
+	resolved := func(field string) bool {
+		return p.EnrichmentFields[field] == FieldEnriched
+	}
+
+	estimated := func(field string) bool {
+		s := p.EnrichmentFields[field]
+
+		return s == FieldEnriched || s == FieldLowConfidence
+	}
+
+	return []interface{}{sql.NullInt64{Int64: int64(p.Age), Valid: resolved("age")}, sql.NullString{String: n["gender"], Valid: resolved("gender")}, sql.NullString{String: n["nationality"], Valid: resolved("nationality")}, sql.NullString{String: n["localization"], Valid: n["localization"] != ""}, sql.NullInt64{Int64: int64(p.AgeCount), Valid: estimated("age")}, sql.NullFloat64{Float64: p.GenderProbability, Valid: estimated("gender")}, sql.NullInt64{Int64: int64(p.GenderCount), Valid: estimated("gender")}, sql.NullFloat64{Float64: p.NationalityProbability, Valid: estimated("nationality")}, sql.NullInt64{Int64: int64(p.NationalityCount), Valid: estimated("nationality")}, sql.NullString{String: string(candidates), Valid: estimated("nationality") && p.NationalityCandidates != nil}, status, sql.NullString{String: string(fields), Valid: p.EnrichmentFields != nil}}, nil
+}
+
+func insert(ctx context.Context, tx *sql.Tx, p Person, config config.PostgreSQLConfig) (Row, error) {
+	n, err := utils.NormalizeInput(map[string]map[string]string{
+		"name":       {p.Name: "title"},
+		"surname":    {p.Surname: "title"},
+		"patronymic": {p.Patronymic: "title"},
+	})
+	if err != nil {
+		return Row{}, ErrNormalization
+	}
+
+	e, err := enrichment(p)
+	if err != nil {
+		return Row{}, err
+	}
+
+	query := fmt.Sprintf("INSERT INTO %s (name, surname, patronymic, age, gender, nationality, localization, age_count, gender_probability, gender_count, nationality_probability, nationality_count, nationality_candidates, enrichment_status, enrichment_fields) VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15) RETURNING %s;", config.Table, columns)
+
+	args := append([]interface{}{n["name"], n["surname"], sql.NullString{String: n["patronymic"], Valid: n["patronymic"] != ""}}, e...)
+
+	row, err := scanRow(tx.QueryRowContext(ctx, query, args...))
+	if err != nil {
+		return Row{}, err
+	}
+
+	err = recordHistory(ctx, tx, OperationCreate, nil, &row, config)
+	if err != nil {
+		return Row{}, err
+	}
+
+	return row, nil
+}
+
+func (h Handlers) Enrich(ctx context.Context, id string, p Person) (Row, error) {
+	const op = "postgresql.Enrich()"
+
+	h.log.Debug(
+		"старт транзакции",
+		slog.String("source", source),
+		slog.String("op", op),
+		slog.Any("data", []string{id}),
+	)
+
+	ctx, cancel := context.WithTimeout(ctx, h.config.Timeout)
+	defer cancel()
+
+	tx, err := h.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return Row{}, err
+	}
+	defer tx.Rollback()
+
+	original, err := lockRow(ctx, tx, id, false, h.config)
+	if err != nil {
+		return Row{}, err
+	}
+
+	args, err := enrichment(p)
+	if err != nil {
+		return Row{}, err
+	}
+
+	query := fmt.Sprintf("UPDATE %s SET age=$1, gender=$2, nationality=$3, localization=$4, age_count=$5, gender_probability=$6, gender_count=$7, nationality_probability=$8, nationality_count=$9, nationality_candidates=$10, enrichment_status=$11, enrichment_fields=$12, version=version+1 WHERE id=$13 RETURNING %s;", h.config.Table, columns)
+
+	row, err := scanRow(tx.QueryRowContext(ctx, query, append(args, id)...))
+	if err != nil {
+		return Row{}, err
+	}
+
+	unchanged := row
+	unchanged.Version = original.Version
+
+	if reflect.DeepEqual(original, unchanged) {
+		h.log.Debug(
+			"обогащение не изменило запись",
+			slog.String("source", source),
+			slog.String("op", op),
+		)
+
+		return original, nil
+	}
+
+	err = recordHistory(ctx, tx, OperationEnrich, &original, &row, h.config)
+	if err != nil {
+		return Row{}, err
+	}
+
+	h.log.Debug(
+		"транзакция завершена",
+		slog.String("source", source),
+		slog.String("op", op),
+	)
+
+	err = tx.Commit()
+	if err != nil {
+		return Row{}, err
+	}
+
+	return row, nil
+}
+
+func (h Handlers) SetEnrichmentStatus(ctx context.Context, id string, status string) (Row, error) {
+	const op = "postgresql.SetEnrichmentStatus()"
+
+	h.log.Debug(
+		"старт транзакции",
+		slog.String("source", source),
+		slog.String("op", op),
+		slog.Any("data", []string{id, status}),
+	)
+
+	ctx, cancel := context.WithTimeout(ctx, h.config.Timeout)
+	defer cancel()
+
+	tx, err := h.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return Row{}, err
+	}
+	defer tx.Rollback()
+
+	original, err := lockRow(ctx, tx, id, false, h.config)
+	if err != nil {
+		return Row{}, err
+	}
+
+	if original.EnrichmentStatus == status {
+		return original, nil
+	}
+
+	query := fmt.Sprintf("UPDATE %s SET enrichment_status=$1, version=version+1 WHERE id=$2 RETURNING %s;", h.config.Table, columns)
+
+	row, err := scanRow(tx.QueryRowContext(ctx, query, status, id))
+	if err != nil {
+		if isConstraintViolation(err) {
+			return Row{}, fmt.Errorf("%w:%v", ErrConstraint, err)
+		}
+		return Row{}, err
+	}
+
+	err = recordHistory(ctx, tx, OperationEnrich, &original, &row, h.config)
+	if err != nil {
+		return Row{}, err
+	}
+
+	h.log.Debug(
+		"транзакция завершена",
+		slog.String("source", source),
+		slog.String("op", op),
+	)
+
+	err = tx.Commit()
+	if err != nil {
+		return Row{}, err
+	}
+
+	return row, nil
+}
+
+type ReenrichFilter struct {
+	Nationality   []string   `json:"nationality" example:"RU,KZ"`
+	CreatedBefore *time.Time `json:"created_before" example:"2024-01-01T00:00:00Z"`
+	LowConfidence bool       `json:"low_confidence" example:"true"`
+}
+
+type ReenrichQuery struct {
+	Filter     ReenrichFilter
+	Thresholds config.ThresholdsConfig
+	After      int
+	Limit      int
+}
+
+func buildReenrichQuery(q ReenrichQuery, config config.PostgreSQLConfig) (string, []interface{}) {
+	args := []interface{}{StatusPending, q.After}
+	conditions := []string{"deleted_at IS NULL", "enrichment_status<>$1", "id>$2"}
+
+	if len(q.Filter.Nationality) != 0 {
+		args = append(args, pq.Array(q.Filter.Nationality))
+		conditions = append(conditions, fmt.Sprintf("nationality=ANY($%d)", len(args)))
+	}
+
+	if q.Filter.CreatedBefore != nil {
+		args = append(args, *q.Filter.CreatedBefore)
+		conditions = append(conditions, fmt.Sprintf("created_at<$%d", len(args)))
+	}
+
+	if q.Filter.LowConfidence {
+		t := q.Thresholds
+
+		args = append(args, t.AgeCount, t.GenderProbability, t.GenderCount, t.NationalityProbability, t.NationalityCount)
+		n := len(args)
+
+		conditions = append(conditions, fmt.Sprintf("(needs_review OR COALESCE(age_count, 0)<$%d OR COALESCE(gender_probability, 0)<$%d OR COALESCE(gender_count, 0)<$%d OR COALESCE(nationality_probability, 0)<$%d OR COALESCE(nationality_count, 0)<$%d)", n-4, n-3, n-2, n-1, n))
+	}
+
+	args = append(args, q.Limit)
+
+	return fmt.Sprintf("SELECT %s FROM %s WHERE %s ORDER BY id LIMIT $%d;", columns, config.Table, strings.Join(conditions, " AND "), len(args)), args
+}
+
+func (h Handlers) SelectReenrich(ctx context.Context, q ReenrichQuery) ([]Row, error) {
+	const op = "postgresql.SelectReenrich()"
+
+	h.log.Debug(
+		"старт транзакции",
+		slog.String("source", source),
+		slog.String("op", op),
+		slog.Any("data", q.Filter),
+	)
+
+	ctx, cancel := context.WithTimeout(ctx, h.config.Timeout)
+	defer cancel()
+
+	query, args := buildReenrichQuery(q, h.config)
+
+	r, err := h.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	rows := []Row{}
+
+	for r.Next() {
+		row, err := scanRow(r)
+		if err != nil {
+			return nil, err
+		}
+		rows = append(rows, row)
+	}
+
+	if r.Err() != nil {
+		return nil, r.Err()
+	}
+
+	h.log.Debug(
+		"транзакция завершена",
+		slog.String("source", source),
+		slog.String("op", op),
+	)
+
+	return rows, nil
+}
+
+type Filter struct {
+	Field    string
+	Operator string
+	Values   []string
+}
+
+var (
+	OperatorIn     = "in"
+	OperatorPrefix = "prefix"
+	OperatorGt     = "gt"
+	OperatorGte    = "gte"
+	OperatorLt     = "lt"
+	OperatorLte    = "lte"
+)
+
+var comparisons = map[string]string{
+	OperatorGt:  ">",
+	OperatorGte: ">=",
+	OperatorLt:  "<",
+	OperatorLte: "<=",
+}
+
+var filterCases = map[string]string{
+	"name":        "title",
+	"surname":     "title",
+	"patronymic":  "title",
+	"gender":      "lowercase",
+	"nationality": "uppercase",
+	"age":         "",
+}
+
+var (
+	filterInteger = "integer"
+	filterFloat   = "float"
+	filterBoolean = "boolean"
+	filterText    = "text"
+)
+
+var filterTypes = map[string]string{
+	"age":                     filterInteger,
+	"age_count":               filterInteger,
+	"gender_count":            filterInteger,
+	"nationality_count":       filterInteger,
+	"gender_probability":      filterFloat,
+	"nationality_probability": filterFloat,
+	"needs_review":            filterBoolean,
+	"enrichment_status":       filterText,
+}
+
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(value)
+}
+
+func buildConditions(filters []Filter, args []interface{}) ([]string, []interface{}, error) {
+	conditions := []string{}
+
+	for _, f := range filters {
+		c, ok := filterCases[f.Field]
+		kind := filterTypes[f.Field]
+
+		if (!ok && kind == "") || len(f.Values) == 0 {
+			return nil, nil, ErrInvalidFilter
+		}
+
+		values := []interface{}{}
+
+		for _, v := range f.Values {
+			switch kind {
+			case filterInteger:
+				n, err := strconv.Atoi(v)
+				if err != nil {
+					return nil, nil, ErrInvalidFilter
+				}
+				values = append(values, n)
+
+				continue
+
+			case filterFloat:
+				n, err := strconv.ParseFloat(v, 64)
+				if err != nil {
+					return nil, nil, ErrInvalidFilter
+				}
+				values = append(values, n)
+
+				continue
+
+			case filterBoolean:
+				b, err := strconv.ParseBool(v)
+				if err != nil {
+					return nil, nil, ErrInvalidFilter
+				}
+				values = append(values, b)
+
+				continue
+
+			case filterText:
+				values = append(values, v)
+
+				continue
+			}
+
+			n, err := utils.NormalizeInput(map[string]map[string]string{
+				f.Field: {v: c},
+			})
+			if err != nil {
+				return nil, nil, ErrNormalization
+			}
+			values = append(values, n[f.Field])
+		}
+
+		switch {
+		case f.Operator == OperatorIn && kind != filterFloat:
+			placeholders := []string{}
+
+			for _, v := range values {
+				args = append(args, v)
+				placeholders = append(placeholders, fmt.Sprintf("$%d", len(args)))
+			}
+			conditions = append(conditions, fmt.Sprintf("%s IN (%s)", f.Field, strings.Join(placeholders, ", ")))
+
+		case f.Operator == OperatorPrefix && kind == "" && len(values) == 1:
+			args = append(args, escapeLike(values[0].(string))+"%")
+			conditions = append(conditions, fmt.Sprintf("%s LIKE $%d", f.Field, len(args)))
+
+		case comparisons[f.Operator] != "" && (kind == filterInteger || kind == filterFloat) && len(values) == 1:
+			args = append(args, values[0])
+			conditions = append(conditions, fmt.Sprintf("%s %s $%d", f.Field, comparisons[f.Operator], len(args)))
+
+		default:
+			return nil, nil, ErrInvalidFilter
+		}
+	}
+
+	return conditions, args, nil
+}
+
+type Sort struct {
+	Field string
+	Desc  bool
+}
+
+var sortExpressions = map[string]string{
+	"id":          "id",
+	"name":        "name",
+	"surname":     "surname",
+	"patronymic":  "COALESCE(patronymic, '')",
+	"age":         "COALESCE(age, -1)",
+	"gender":      "COALESCE(gender, '')",
+	"nationality": "COALESCE(nationality, '')",
+}
+
+func buildSortKeys(sort []Sort) ([]Sort, error) {
+	keys := []Sort{}
+	seen := map[string]bool{}
+
+	for _, s := range sort {
+		if sortExpressions[s.Field] == "" || seen[s.Field] {
+			return nil, ErrInvalidSort
+		}
+		seen[s.Field] = true
+
+		keys = append(keys, s)
+	}
+
+	if !seen["id"] {
+		keys = append(keys, Sort{Field: "id"})
+	}
+
+	return keys, nil
+}
+
+func buildOrderBy(keys []Sort) string {
+	order := []string{}
+
+	for _, k := range keys {
+		if k.Desc {
+			order = append(order, sortExpressions[k.Field]+" DESC")
+		} else {
+			order = append(order, sortExpressions[k.Field]+" ASC")
+		}
+	}
+
+	return " ORDER BY " + strings.Join(order, ", ")
+}
+
+type cursor struct {
+	Sort   string        `json:"s"`
+	Values []interface{} `json:"v"`
+}
+
+func sortSignature(keys []Sort) string {
+	signature := []string{}
+
+	for _, k := range keys {
+		if k.Desc {
+			signature = append(signature, "-"+k.Field)
+		} else {
+			signature = append(signature, k.Field)
+		}
+	}
+
+	return strings.Join(signature, ",")
+}
+
+func cursorValue(row Row, field string) interface{} {
+	switch field {
+	case "id":
+		return row.ID
+	case "name":
+		return row.Name
+	case "surname":
+		return row.Surname
+	case "patronymic":
+		if row.Patronymic == nil {
+			return ""
+		}
+		return *row.Patronymic
+	case "age":
+		if row.Age == nil {
+			return -1
+		}
+		return *row.Age
+	case "gender":
+		if row.Gender == nil {
+			return ""
+		}
+		return *row.Gender
+	case "nationality":
+		if row.Nationality == nil {
+			return ""
+		}
+		return *row.Nationality
+	}
+	return nil
+}
+
+func encodeCursor(keys []Sort, row Row) (string, error) {
+	c := cursor{
+		Sort: sortSignature(keys),
+	}
+
+	for _, k := range keys {
+		c.Values = append(c.Values, cursorValue(row, k.Field))
+	}
+
+	b, err := json.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func decodeCursor(value string, keys []Sort) ([]interface{}, error) {
+	b, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var c cursor
+
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.UseNumber()
+
+	err = d.Decode(&c)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	if c.Sort != sortSignature(keys) || len(c.Values) != len(keys) {
+		return nil, ErrInvalidCursor
+	}
+
+	values := []interface{}{}
+
+	for i, k := range keys {
+		switch v := c.Values[i].(type) {
+		case json.Number:
+			if k.Field != "id" && k.Field != "age" {
+				return nil, ErrInvalidCursor
+			}
+
+			n, err := v.Int64()
+			if err != nil {
+				return nil, ErrInvalidCursor
+			}
+			values = append(values, n)
+
+		case string:
+			if k.Field == "id" || k.Field == "age" {
+				return nil, ErrInvalidCursor
+			}
+			values = append(values, v)
+
+		default:
+			return nil, ErrInvalidCursor
+		}
+	}
+
+	return values, nil
+}
+
+func buildKeyset(keys []Sort, values []interface{}, args []interface{}) (string, []interface{}) {
+	base := len(args)
+	args = append(args, values...)
+
+	alternatives := []string{}
+
+	for i, k := range keys {
+		parts := []string{}
+
+		for j := 0; j < i; j++ {
+			parts = append(parts, fmt.Sprintf("%s = $%d", sortExpressions[keys[j].Field], base+j+1))
+		}
+
+		if k.Desc {
+			parts = append(parts, fmt.Sprintf("%s < $%d", sortExpressions[k.Field], base+i+1))
+		} else {
+			parts = append(parts, fmt.Sprintf("%s > $%d", sortExpressions[k.Field], base+i+1))
+		}
+
+		alternatives = append(alternatives, "("+strings.Join(parts, " AND ")+")")
+	}
+
+	return "(" + strings.Join(alternatives, " OR ") + ")", args
+}
+
+type SelectQuery struct {
+	ID             string
+	Limit          []int
+	Filters        []Filter
+	Sort           []Sort
+	Cursor         string
+	WithTotal      bool
+	IncludeDeleted bool
+}
+
+type SelectResult struct {
+	Rows       []Row
+	NextCursor string
+	HasMore    bool
+	Total      int
+}
+
+func buildSelectQuery(q SelectQuery, keys []Sort, config config.PostgreSQLConfig) (string, []interface{}, error) {
+	args := []interface{}{}
+
+	if q.ID != "" {
+		if q.IncludeDeleted {
+			return fmt.Sprintf("SELECT %s FROM %s WHERE id=$1;", columns, config.Table), append(args, q.ID), nil
+		}
+		return fmt.Sprintf("SELECT %s FROM %s WHERE id=$1 AND deleted_at IS NULL;", columns, config.Table), append(args, q.ID), nil
+	}
+
+	conditions, args, err := buildConditions(q.Filters, args)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if !q.IncludeDeleted {
+		conditions = append(conditions, "deleted_at IS NULL")
+	}
+
+	if q.Cursor != "" {
+		values, err := decodeCursor(q.Cursor, keys)
+		if err != nil {
+			return "", nil, err
+		}
+
+		var keyset string
+
+		keyset, args = buildKeyset(keys, values, args)
+		conditions = append(conditions, keyset)
+	}
+
+	var where string
+
+	if len(conditions) != 0 {
+		where = " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	args = append(args, q.Limit[1]-q.Limit[0]+1, q.Limit[0])
+
+	return fmt.Sprintf("SELECT %s FROM %s%s%s LIMIT $%d OFFSET $%d;", columns, config.Table, where, buildOrderBy(keys), len(args)-1, len(args)), args, nil
+}
+
+func buildCountQuery(q SelectQuery, config config.PostgreSQLConfig) (string, []interface{}, error) {
+	conditions, args, err := buildConditions(q.Filters, []interface{}{})
+	if err != nil {
+		return "", nil, err
+	}
+
+	if !q.IncludeDeleted {
+		conditions = append(conditions, "deleted_at IS NULL")
+	}
+
+	var where string
+
+	if len(conditions) != 0 {
+		where = " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	return fmt.Sprintf("SELECT COUNT(*) FROM %s%s;", config.Table, where), args, nil
+}
+
+func (h Handlers) Select(ctx context.Context, q SelectQuery) (SelectResult, error) {
+	const op = "postgresql.Select()"
+
+	h.log.Debug(
+		"старт транзакции",
+		slog.String("source", source),
+		slog.String("op", op),
+	)
+
+	ctx, cancel := context.WithTimeout(ctx, h.config.Timeout)
+	defer cancel()
+
+	tx, err := h.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return SelectResult{}, err
+	}
+	defer tx.Rollback()
+
+	keys, err := buildSortKeys(q.Sort)
+	if err != nil {
+		return SelectResult{}, err
+	}
+
+	query, args, err := buildSelectQuery(q, keys, h.config)
+	if err != nil {
+		return SelectResult{}, err
+	}
+
+	r, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return SelectResult{}, err
+	}
+	defer r.Close()
+
+	rows := []Row{}
+
+	for r.Next() {
+		row, err := scanRow(r)
+		if err != nil {
+			return SelectResult{}, err
+		}
+		rows = append(rows, row)
+	}
+
+	if r.Err() != nil {
+		return SelectResult{}, r.Err()
+	}
+
+	if q.ID != "" && len(rows) == 0 {
+		return SelectResult{}, sql.ErrNoRows
+	}
+
+	result := SelectResult{
+		Rows: rows,
+	}
+
+	size := q.Limit[1] - q.Limit[0]
+
+	if q.ID == "" && len(rows) > size {
+		result.Rows = rows[:size]
+		result.HasMore = true
+
+		result.NextCursor, err = encodeCursor(keys, rows[size-1])
+		if err != nil {
+			return SelectResult{}, err
+		}
+	}
+
+	if q.ID == "" && q.WithTotal {
+		queryCount, argsCount, err := buildCountQuery(q, h.config)
+		if err != nil {
+			return SelectResult{}, err
+		}
+
+		err = tx.QueryRowContext(ctx, queryCount, argsCount...).Scan(&result.Total)
+		if err != nil {
+			return SelectResult{}, err
+		}
+	}
+
+	h.log.Debug(
+		"транзакция завершена",
+		slog.String("source", source),
+		slog.String("op", op),
+	)
+
+	return result, nil
+}
+
+func (h Handlers) History(ctx context.Context, id string) ([]HistoryEntry, error) {
+	const op = "postgresql.History()"
+
+	h.log.Debug(
+		"старт транзакции",
+		slog.String("source", source),
+		slog.String("op", op),
+		slog.Any("data", []string{id}),
+	)
+
+	ctx, cancel := context.WithTimeout(ctx, h.config.Timeout)
+	defer cancel()
+
+	tx, err := h.DB.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	query := fmt.Sprintf("SELECT id, person_id, operation, actor, changed_at, before, after FROM %s WHERE person_id=$1 ORDER BY changed_at, id;", h.config.HistoryTable)
+
+	r, err := tx.QueryContext(ctx, query, id)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	entries := []HistoryEntry{}
+
+	for r.Next() {
+		var entry HistoryEntry
+		var before []byte
+		var after []byte
+
+		err := r.Scan(&entry.ID, &entry.PersonID, &entry.Operation, &entry.Actor, &entry.ChangedAt, &before, &after)
+		if err != nil {
+			return nil, err
+		}
+
+		entry.Before, err = unmarshalSnapshot(before)
+		if err != nil {
+			return nil, err
+		}
+
+		entry.After, err = unmarshalSnapshot(after)
+		if err != nil {
+			return nil, err
+		}
+
+		entries = append(entries, entry)
+	}
+
+	if r.Err() != nil {
+		return nil, r.Err()
+	}
+
+	if len(entries) == 0 {
+		var exists bool
+
+		err = tx.QueryRowContext(ctx, fmt.Sprintf("SELECT EXISTS(SELECT 1 FROM %s WHERE id=$1);", h.config.Table), id).Scan(&exists)
+		if err != nil {
+			return nil, err
+		}
+
+		if !exists {
+			return nil, sql.ErrNoRows
+		}
+	}
+
+	h.log.Debug(
+		"транзакция завершена",
+		slog.String("source", source),
+		slog.String("op", op),
+	)
+
+	return entries, nil
+}
+
+func (h Handlers) SelectAsOf(ctx context.Context, id string, asOf time.Time) (Row, error) {
+	const op = "postgresql.SelectAsOf()"
+
+	h.log.Debug(
+		"старт транзакции",
+		slog.String("source", source),
+		slog.String("op", op),
+		slog.Any("data", []interface{}{id, asOf}),
+	)
+
+	ctx, cancel := context.WithTimeout(ctx, h.config.Timeout)
+	defer cancel()
+
+	tx, err := h.DB.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return Row{}, err
+	}
+	defer tx.Rollback()
+
+	query := fmt.Sprintf("SELECT after FROM %s WHERE person_id=$1 AND changed_at <= $2 ORDER BY changed_at DESC, id DESC LIMIT 1;", h.config.HistoryTable)
+
+	var after []byte
+
+	err = tx.QueryRowContext(ctx, query, id, asOf).Scan(&after)
+	if err != nil {
+		return Row{}, err
+	}
+
+	row, err := unmarshalSnapshot(after)
+	if err != nil {
+		return Row{}, err
+	}
+
+	if row == nil || row.DeletedAt != nil {
+		return Row{}, sql.ErrNoRows
+	}
+
+	h.log.Debug(
+		"транзакция завершена",
+		slog.String("source", source),
+		slog.String("op", op),
+	)
+
+	return *row, nil
+}
+
+// МОКИ
+
+type UnimplementedHandlers struct{}
+
+func (u UnimplementedHandlers) DeleteByID(ctx context.Context, id string, versions []int) error {
+	return nil
+}
+
+func (u UnimplementedHandlers) UpdateByID(ctx context.Context, id string, data []byte, versions []int) (Row, error) {
+	return Row{}, nil
+}
+
+func (u UnimplementedHandlers) PatchByID(ctx context.Context, id string, patch Patch, versions []int) (Row, error) {
+	return Row{}, nil
+}
+
+func (u UnimplementedHandlers) RestoreByID(ctx context.Context, id string, versions []int) (Row, error) {
+	return Row{}, nil
+}
+
+func (u UnimplementedHandlers) Purge(ctx context.Context, before time.Time) (int64, error) {
+	return 0, nil
+}
+
+func (u UnimplementedHandlers) Create(ctx context.Context, p Person) (Row, error) {
+	return Row{}, nil
+}
+
+func (u UnimplementedHandlers) CreateWith(ctx context.Context, p Person, after AfterCreate) (Row, error) {
+	return Row{}, nil
+}
+
+func (u UnimplementedHandlers) CreateBatch(ctx context.Context, people []Person) ([]Row, error) {
+	return []Row{}, nil
+}
+
+func (u UnimplementedHandlers) Enrich(ctx context.Context, id string, p Person) (Row, error) {
+	return Row{}, nil
+}
+
+func (u UnimplementedHandlers) SetEnrichmentStatus(ctx context.Context, id string, status string) (Row, error) {
+	return Row{}, nil
+}
+
+func (u UnimplementedHandlers) SelectReenrich(ctx context.Context, q ReenrichQuery) ([]Row, error) {
+	return []Row{}, nil
+}
+
+func (u UnimplementedHandlers) Select(ctx context.Context, q SelectQuery) (SelectResult, error) {
+	return SelectResult{Rows: []Row{}}, nil
+}
+
+func (u UnimplementedHandlers) History(ctx context.Context, id string) ([]HistoryEntry, error) {
+	return []HistoryEntry{}, nil
+}
+
+func (u UnimplementedHandlers) SelectAsOf(ctx context.Context, id string, asOf time.Time) (Row, error) {
+	return Row{}, nil
 }
@@ -1,8 +1,11 @@
 package config
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
+	"reflect"
+	"strings"
 	"time"
 
 	"github.com/ilyakaznacheev/cleanenv"
@@ -18,6 +21,19 @@ type Config struct {
 
 	EffectiveMobile EffectiveMobileConfig
 	Storage         StorageConfig
+	Queue           QueueConfig
+}
+
+type QueueConfig struct {
+	Workers      int           `env:"QUEUE_WORKERS" env-required:"true" env-description:"Количество воркеров очереди задач"`
+	PollInterval time.Duration `env:"QUEUE_POLLINTERVAL" env-required:"true" env-description:"Интервал опроса очереди задач, когда она пуста"`
+
+	MaxAttempts   int           `env:"QUEUE_MAXATTEMPTS" env-required:"true" env-description:"Количество попыток выполнения задачи, после которого она считается неисполнимой"`
+	RetryDelay    time.Duration `env:"QUEUE_RETRYDELAY" env-required:"true" env-description:"Начальная задержка перед повторной попыткой выполнения задачи"`
+	RetryMaxDelay time.Duration `env:"QUEUE_RETRYMAXDELAY" env-required:"true" env-description:"Максимальная задержка перед повторной попыткой выполнения задачи"`
+
+	JobTimeout   time.Duration `env:"QUEUE_JOBTIMEOUT" env-required:"true" env-description:"Таймаут на одну попытку выполнения задачи"`
+	DrainTimeout time.Duration `env:"QUEUE_DRAINTIMEOUT" env-required:"true" env-description:"Время на завершение выполняемых задач при остановке"`
 }
 
 type EffectiveMobileConfig struct {
@@ -27,13 +43,119 @@ type EffectiveMobileConfig struct {
 	WriteTimeout time.Duration `env:"SERVER_WRITETIMEOUT" env-required:"true" env-description:"Таймаут сервера на Write"`
 	IdleTimeout  time.Duration `env:"SERVER_IDLETIMEOUT" env-required:"true" env-description:"Таймаут сервера на Idle"`
 
+	RequestTimeout time.Duration `env:"SERVER_REQUESTTIMEOUT" env-required:"true" env-description:"Таймаут на обработку одного запроса"`
+
 	SelectLimit int `env:"SERVER_SELECTLIMIT" env-required:"true" env-description:"Стандартный лимит для пагинации"`
 
-	Client ClientConfig
+	PurgeRetention time.Duration `env:"SERVER_PURGERETENTION" env-required:"true" env-description:"Срок хранения удаленных записей перед очисткой"`
+
+	BatchLimit int `env:"SERVER_BATCHLIMIT" env-required:"true" env-description:"Максимальное количество записей в одном пакетном создании"`
+
+	NationalityHint bool `env:"SERVER_NATIONALITYHINT" env-required:"true" env-description:"Использовать найденную национальность как country_id, если подсказка не передана"`
+
+	EnrichmentPolicy string `env:"SERVER_ENRICHMENTPOLICY" env-required:"true" env-description:"Политика обогащения (strict, best_effort): strict прерывает создание при ошибке любого провайдера, best_effort сохраняет полученные поля"`
+
+	ExportDir string `env:"SERVER_EXPORTDIR" env-required:"true" env-description:"Каталог, в который сохраняются выгрузки записей"`
+
+	Client     ClientConfig
+	Thresholds ThresholdsConfig
+}
+
+type ThresholdsConfig struct {
+	AgeCount int `env:"THRESHOLD_AGECOUNT" env-required:"true" env-description:"Минимальный размер выборки, при котором возраст сохраняется"`
+
+	GenderProbability float64 `env:"THRESHOLD_GENDERPROB" env-required:"true" env-description:"Минимальная вероятность, при которой пол сохраняется"`
+	GenderCount       int     `env:"THRESHOLD_GENDERCOUNT" env-required:"true" env-description:"Минимальный размер выборки, при котором пол сохраняется"`
+
+	NationalityProbability float64 `env:"THRESHOLD_NATIONPROB" env-required:"true" env-description:"Минимальная вероятность, при которой национальность сохраняется"`
+	NationalityCount       int     `env:"THRESHOLD_NATIONCOUNT" env-required:"true" env-description:"Минимальный размер выборки, при котором национальность сохраняется"`
 }
 
 type ClientConfig struct {
-	Timeout time.Duration `env:"CLIENT_TIMEOUT" env-required:"true" env-description:"Таймаут клиента"`
+	Timeout time.Duration `env:"CLIENT_TIMEOUT" env-required:"true" env-description:"Таймаут запроса к провайдеру, если у провайдера не задан собственный"`
+
+	Retries        int           `env:"CLIENT_RETRIES" env-required:"true" env-description:"Количество повторных попыток запроса к провайдеру"`
+	RetryBaseDelay time.Duration `env:"CLIENT_RETRYBASEDELAY" env-required:"true" env-description:"Начальная задержка перед повторной попыткой"`
+	RetryMaxDelay  time.Duration `env:"CLIENT_RETRYMAXDELAY" env-required:"true" env-description:"Максимальная задержка перед повторной попыткой"`
+
+	BreakerThreshold int           `env:"CLIENT_BREAKERTHRESHOLD" env-required:"true" env-description:"Количество ошибок подряд, после которого предохранитель размыкается (0 отключает)"`
+	BreakerCooldown  time.Duration `env:"CLIENT_BREAKERCOOLDOWN" env-required:"true" env-description:"Время, в течение которого предохранитель остается разомкнутым"`
+	BreakerProbes    int           `env:"CLIENT_BREAKERPROBES" env-required:"true" env-description:"Количество пробных запросов в полуоткрытом состоянии"`
+
+	Candidates int `env:"CLIENT_CANDIDATES" env-required:"true" env-description:"Количество наиболее вероятных национальностей, сохраняемых с записью"`
+
+	QuotaReserve int           `env:"CLIENT_QUOTARESERVE" env-required:"true" env-description:"Остаток квоты провайдера, при котором запросы начинают сдерживаться"`
+	QuotaMaxWait time.Duration `env:"CLIENT_QUOTAMAXWAIT" env-required:"true" env-description:"Максимальное время ожидания сброса квоты перед отказом"`
+
+	CacheType  string        `env:"CLIENT_CACHE" env-required:"true" env-description:"Тип кэша результатов обогащения (none, memory, postgresql)"`
+	CacheSize  int           `env:"CLIENT_CACHESIZE" env-required:"true" env-description:"Максимальное количество записей в кэше memory"`
+	CacheTTL   time.Duration `env:"CLIENT_CACHETTL" env-required:"true" env-description:"Время жизни записи в кэше"`
+	CacheTable string        `env:"CLIENT_CACHETABLE" env-required:"true" env-description:"Таблица PostgreSQL для кэша postgresql"`
+
+	AgeProvider         string `env:"CLIENT_AGEPROVIDER" env-required:"true" env-description:"Провайдер, определяющий возраст"`
+	GenderProvider      string `env:"CLIENT_GENDERPROVIDER" env-required:"true" env-description:"Провайдер, определяющий пол"`
+	NationalityProvider string `env:"CLIENT_NATIONPROVIDER" env-required:"true" env-description:"Провайдер, определяющий национальность"`
+
+	ProviderNames []string `env:"CLIENT_PROVIDERS" env-required:"true" env-description:"Провайдеры через запятую, для каждого читаются переменные CLIENT_<ПРОВАЙДЕР>URL, KEY, KEYFILE, TIMEOUT и ENABLED"`
+
+	Providers map[string]ProviderConfig
+}
+
+type ProviderConfig struct {
+	URL     string        `env:"URL" env-required:"true" env-description:"Базовый URL провайдера"`
+	Key     Secret        `env:"KEY" env-description:"API-ключ провайдера"`
+	KeyFile string        `env:"KEYFILE" env-description:"Файл с API-ключом провайдера (имеет приоритет над API-ключом)"`
+	Timeout time.Duration `env:"TIMEOUT" env-required:"true" env-description:"Таймаут запроса к провайдеру"`
+	Enabled bool          `env:"ENABLED" env-required:"true" env-description:"Включен ли провайдер"`
+}
+
+type Secret string
+
+func (s Secret) String() string {
+	if s == "" {
+		return ""
+	}
+	return "REDACTED"
+}
+
+func (s Secret) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.String())
+}
+
+func (c ProviderConfig) LoadKey() (Secret, error) {
+	if c.KeyFile == "" {
+		return c.Key, nil
+	}
+
+	b, err := os.ReadFile(c.KeyFile)
+	if err != nil {
+		return "", err
+	}
+
+	return Secret(strings.TrimSpace(string(b))), nil
+}
+
+func (c *ClientConfig) ReadProviders() error {
+	c.Providers = map[string]ProviderConfig{}
+
+	for _, name := range c.ProviderNames {
+		name = strings.TrimSpace(name)
+
+		node := reflect.New(reflect.StructOf([]reflect.StructField{{
+			Name: "Provider",
+			Type: reflect.TypeOf(ProviderConfig{}),
+			Tag:  reflect.StructTag(fmt.Sprintf("env-prefix:\"CLIENT_%s\"", strings.ToUpper(name))),
+		}}))
+
+		err := cleanenv.ReadEnv(node.Interface())
+		if err != nil {
+			return fmt.Errorf("провайдер %s: %v", name, err)
+		}
+
+		c.Providers[name] = node.Elem().Field(0).Interface().(ProviderConfig)
+	}
+
+	return nil
 }
 
 type StorageConfig struct {
@@ -47,8 +169,13 @@ type PostgreSQLConfig struct {
 	Port     string `env:"POSTGRESQL_PORT" env-required:"true" env-description:"Порт PostgreSQL"`
 	Database string `env:"POSTGRESQL_DBNAME" env-required:"true" env-description:"БД PostgreSQL"`
 	Table    string `env:"POSTGRESQL_TABLE" env-required:"true" env-description:"Таблица PostgreSQL"`
-	SSL      string `env:"POSTGRESQL_SSLMODE" env-required:"true" env-description:"Режим SSL PostgreSQL"`
-	Extra    string `env:"POSTGRESQL_EXTRA" env-description:"Дополнительные опции PostgreSQL"`
+
+	HistoryTable string `env:"POSTGRESQL_HISTORYTABLE" env-required:"true" env-description:"Таблица истории изменений PostgreSQL"`
+	JobsTable    string `env:"POSTGRESQL_JOBSTABLE" env-required:"true" env-description:"Таблица очереди задач PostgreSQL"`
+	SSL          string `env:"POSTGRESQL_SSLMODE" env-required:"true" env-description:"Режим SSL PostgreSQL"`
+	Extra        string `env:"POSTGRESQL_EXTRA" env-description:"Дополнительные опции PostgreSQL"`
+
+	Timeout time.Duration `env:"POSTGRESQL_TIMEOUT" env-required:"true" env-description:"Таймаут на одну операцию PostgreSQL"`
 }
 
 func New() (Config, error) {
@@ -73,5 +200,10 @@ func New() (Config, error) {
 		return Config{}, err
 	}
 
+	err = config.EffectiveMobile.Client.ReadProviders()
+	if err != nil {
+		return Config{}, err
+	}
+
 	return config, nil
 }
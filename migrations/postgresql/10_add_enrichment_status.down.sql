@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_enrichment_status;
+DROP INDEX IF EXISTS idx_needs_review;
+ALTER TABLE people DROP COLUMN IF EXISTS needs_review;
+ALTER TABLE people DROP COLUMN IF EXISTS enrichment_status;
+ALTER TABLE people ADD COLUMN needs_review BOOLEAN GENERATED ALWAYS AS (age IS NULL OR gender IS NULL OR nationality IS NULL) STORED;
+CREATE INDEX IF NOT EXISTS idx_needs_review ON people (needs_review) WHERE needs_review;
\ No newline at end of file
@@ -0,0 +1,6 @@
+ALTER TABLE people ADD COLUMN IF NOT EXISTS enrichment_status VARCHAR(32) NOT NULL DEFAULT 'enriched' CHECK (enrichment_status IN ('pending_enrichment', 'enriched', 'enrichment_failed'));
+DROP INDEX IF EXISTS idx_needs_review;
+ALTER TABLE people DROP COLUMN IF EXISTS needs_review;
+ALTER TABLE people ADD COLUMN needs_review BOOLEAN GENERATED ALWAYS AS (enrichment_status <> 'pending_enrichment' AND (age IS NULL OR gender IS NULL OR nationality IS NULL)) STORED;
+CREATE INDEX IF NOT EXISTS idx_needs_review ON people (needs_review) WHERE needs_review;
+CREATE INDEX IF NOT EXISTS idx_enrichment_status ON people (enrichment_status) WHERE enrichment_status <> 'enriched';
\ No newline at end of file
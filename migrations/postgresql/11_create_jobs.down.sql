@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_jobs_dead;
+DROP INDEX IF EXISTS idx_jobs_running;
+DROP INDEX IF EXISTS idx_jobs_queued;
+DROP TABLE IF EXISTS jobs;
\ No newline at end of file
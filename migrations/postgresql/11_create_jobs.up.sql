@@ -0,0 +1,4 @@
+CREATE TABLE IF NOT EXISTS jobs (id BIGSERIAL PRIMARY KEY, type VARCHAR(32) NOT NULL, payload JSONB NOT NULL, status VARCHAR(16) NOT NULL DEFAULT 'queued' CHECK (status IN ('queued', 'running', 'dead')), attempts INTEGER NOT NULL DEFAULT 0, max_attempts INTEGER NOT NULL, run_at TIMESTAMPTZ NOT NULL DEFAULT now(), locked_until TIMESTAMPTZ, last_error TEXT, actor VARCHAR(100) NOT NULL, created_at TIMESTAMPTZ NOT NULL DEFAULT now(), updated_at TIMESTAMPTZ NOT NULL DEFAULT now());
+CREATE INDEX IF NOT EXISTS idx_jobs_queued ON jobs (run_at, id) WHERE status = 'queued';
+CREATE INDEX IF NOT EXISTS idx_jobs_running ON jobs (locked_until) WHERE status = 'running';
+CREATE INDEX IF NOT EXISTS idx_jobs_dead ON jobs (type, updated_at) WHERE status = 'dead';
\ No newline at end of file
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_created_at;
+ALTER TABLE people DROP COLUMN IF EXISTS created_at;
\ No newline at end of file
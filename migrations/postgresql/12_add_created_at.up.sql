@@ -0,0 +1,3 @@
+ALTER TABLE people ADD COLUMN IF NOT EXISTS created_at TIMESTAMPTZ NOT NULL DEFAULT now();
+UPDATE people SET created_at=h.changed_at FROM people_history h WHERE h.person_id=people.id AND h.operation='create';
+CREATE INDEX IF NOT EXISTS idx_created_at ON people (created_at);
\ No newline at end of file
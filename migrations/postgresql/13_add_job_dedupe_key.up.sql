@@ -0,0 +1,2 @@
+ALTER TABLE jobs ADD COLUMN IF NOT EXISTS dedupe_key VARCHAR(100);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_jobs_dedupe_key ON jobs (type, dedupe_key) WHERE status <> 'dead';
\ No newline at end of file
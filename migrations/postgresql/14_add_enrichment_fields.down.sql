@@ -0,0 +1 @@
+ALTER TABLE people DROP COLUMN IF EXISTS enrichment_fields;
\ No newline at end of file
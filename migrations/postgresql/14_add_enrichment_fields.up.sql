@@ -0,0 +1 @@
+ALTER TABLE people ADD COLUMN IF NOT EXISTS enrichment_fields JSONB;
\ No newline at end of file
@@ -0,0 +1,2 @@
+ALTER TABLE people ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ;
+CREATE INDEX IF NOT EXISTS idx_deleted_at ON people (deleted_at);
\ No newline at end of file
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_people_history_person_id;
+DROP TABLE IF EXISTS people_history;
\ No newline at end of file
@@ -0,0 +1,2 @@
+CREATE TABLE IF NOT EXISTS people_history (id BIGSERIAL PRIMARY KEY, person_id BIGINT NOT NULL, operation VARCHAR(16) NOT NULL, actor VARCHAR(100) NOT NULL, changed_at TIMESTAMPTZ NOT NULL DEFAULT now(), before JSONB, after JSONB);
+CREATE INDEX IF NOT EXISTS idx_people_history_person_id ON people_history (person_id, changed_at);
\ No newline at end of file
@@ -0,0 +1,2 @@
+CREATE TABLE IF NOT EXISTS enrichment_cache (provider VARCHAR(32) NOT NULL, name VARCHAR(100) NOT NULL, value TEXT NOT NULL, expires_at TIMESTAMPTZ NOT NULL, PRIMARY KEY (provider, name));
+CREATE INDEX IF NOT EXISTS idx_enrichment_cache_expires_at ON enrichment_cache (expires_at);
\ No newline at end of file
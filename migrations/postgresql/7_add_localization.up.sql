@@ -0,0 +1 @@
+ALTER TABLE people ADD COLUMN IF NOT EXISTS localization VARCHAR(2);
\ No newline at end of file
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_nationality_probability;
+DROP INDEX IF EXISTS idx_gender_probability;
+ALTER TABLE people DROP COLUMN IF EXISTS nationality_candidates, DROP COLUMN IF EXISTS nationality_count, DROP COLUMN IF EXISTS nationality_probability, DROP COLUMN IF EXISTS gender_count, DROP COLUMN IF EXISTS gender_probability, DROP COLUMN IF EXISTS age_count;
\ No newline at end of file
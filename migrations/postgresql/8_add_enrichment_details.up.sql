@@ -0,0 +1,3 @@
+ALTER TABLE people ADD COLUMN IF NOT EXISTS age_count INTEGER, ADD COLUMN IF NOT EXISTS gender_probability DOUBLE PRECISION, ADD COLUMN IF NOT EXISTS gender_count INTEGER, ADD COLUMN IF NOT EXISTS nationality_probability DOUBLE PRECISION, ADD COLUMN IF NOT EXISTS nationality_count INTEGER, ADD COLUMN IF NOT EXISTS nationality_candidates JSONB;
+CREATE INDEX IF NOT EXISTS idx_gender_probability ON people (gender_probability);
+CREATE INDEX IF NOT EXISTS idx_nationality_probability ON people (nationality_probability);
\ No newline at end of file
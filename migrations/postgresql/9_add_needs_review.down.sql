@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_needs_review;
+ALTER TABLE people DROP COLUMN IF EXISTS needs_review;
+INSERT INTO people_history (person_id, operation, actor, before) SELECT id, 'purge', 'migration', to_jsonb(people) FROM people WHERE age IS NULL OR gender IS NULL OR nationality IS NULL;
+DELETE FROM people WHERE age IS NULL OR gender IS NULL OR nationality IS NULL;
+ALTER TABLE people ALTER COLUMN age SET NOT NULL, ALTER COLUMN gender SET NOT NULL, ALTER COLUMN nationality SET NOT NULL;
\ No newline at end of file
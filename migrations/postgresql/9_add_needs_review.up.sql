@@ -0,0 +1,3 @@
+ALTER TABLE people ALTER COLUMN age DROP NOT NULL, ALTER COLUMN gender DROP NOT NULL, ALTER COLUMN nationality DROP NOT NULL;
+ALTER TABLE people ADD COLUMN IF NOT EXISTS needs_review BOOLEAN GENERATED ALWAYS AS (age IS NULL OR gender IS NULL OR nationality IS NULL) STORED;
+CREATE INDEX IF NOT EXISTS idx_needs_review ON people (needs_review) WHERE needs_review;
\ No newline at end of file
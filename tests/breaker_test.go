@@ -0,0 +1,156 @@
+package tests
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/xoticdsign/effectivemobile/internal/client/breaker"
+)
+
+type breakerStep struct {
+	action        string
+	expectedErr   error
+	expectedState string
+}
+
+func TestBreaker_Unit(t *testing.T) {
+	cooldown := 20 * time.Millisecond
+
+	cases := []struct {
+		name                string
+		inThreshold         int
+		inProbes            int
+		inSteps             []breakerStep
+		expectedTransitions []string
+	}{
+		{
+			name:        "closed to open on threshold case",
+			inThreshold: 3,
+			inProbes:    1,
+			inSteps: []breakerStep{
+				{action: "failure", expectedState: breaker.StateClosed},
+				{action: "failure", expectedState: breaker.StateClosed},
+				{action: "allow", expectedErr: nil, expectedState: breaker.StateClosed},
+				{action: "failure", expectedState: breaker.StateOpen},
+				{action: "allow", expectedErr: breaker.ErrOpen, expectedState: breaker.StateOpen},
+			},
+			expectedTransitions: []string{"closed->open"},
+		},
+		{
+			name:        "success resets failures case",
+			inThreshold: 3,
+			inProbes:    1,
+			inSteps: []breakerStep{
+				{action: "failure", expectedState: breaker.StateClosed},
+				{action: "failure", expectedState: breaker.StateClosed},
+				{action: "success", expectedState: breaker.StateClosed},
+				{action: "failure", expectedState: breaker.StateClosed},
+				{action: "failure", expectedState: breaker.StateClosed},
+				{action: "allow", expectedErr: nil, expectedState: breaker.StateClosed},
+			},
+			expectedTransitions: nil,
+		},
+		{
+			name:        "half-open after cooldown case",
+			inThreshold: 1,
+			inProbes:    1,
+			inSteps: []breakerStep{
+				{action: "failure", expectedState: breaker.StateOpen},
+				{action: "allow", expectedErr: breaker.ErrOpen, expectedState: breaker.StateOpen},
+				{action: "wait", expectedState: breaker.StateHalfOpen},
+				{action: "allow", expectedErr: nil, expectedState: breaker.StateHalfOpen},
+				{action: "allow", expectedErr: breaker.ErrOpen, expectedState: breaker.StateHalfOpen},
+				{action: "success", expectedState: breaker.StateClosed},
+				{action: "allow", expectedErr: nil, expectedState: breaker.StateClosed},
+			},
+			expectedTransitions: []string{"closed->open", "open->half-open", "half-open->closed"},
+		},
+		{
+			name:        "failed probe case",
+			inThreshold: 1,
+			inProbes:    1,
+			inSteps: []breakerStep{
+				{action: "failure", expectedState: breaker.StateOpen},
+				{action: "wait", expectedState: breaker.StateHalfOpen},
+				{action: "allow", expectedErr: nil, expectedState: breaker.StateHalfOpen},
+				{action: "failure", expectedState: breaker.StateOpen},
+				{action: "allow", expectedErr: breaker.ErrOpen, expectedState: breaker.StateOpen},
+			},
+			expectedTransitions: []string{"closed->open", "open->half-open", "half-open->open"},
+		},
+		{
+			name:        "released probe case",
+			inThreshold: 1,
+			inProbes:    1,
+			inSteps: []breakerStep{
+				{action: "failure", expectedState: breaker.StateOpen},
+				{action: "wait", expectedState: breaker.StateHalfOpen},
+				{action: "allow", expectedErr: nil, expectedState: breaker.StateHalfOpen},
+				{action: "release", expectedState: breaker.StateHalfOpen},
+				{action: "allow", expectedErr: nil, expectedState: breaker.StateHalfOpen},
+				{action: "allow", expectedErr: breaker.ErrOpen, expectedState: breaker.StateHalfOpen},
+			},
+			expectedTransitions: []string{"closed->open", "open->half-open"},
+		},
+		{
+			name:        "several probes case",
+			inThreshold: 1,
+			inProbes:    2,
+			inSteps: []breakerStep{
+				{action: "failure", expectedState: breaker.StateOpen},
+				{action: "wait", expectedState: breaker.StateHalfOpen},
+				{action: "allow", expectedErr: nil, expectedState: breaker.StateHalfOpen},
+				{action: "allow", expectedErr: nil, expectedState: breaker.StateHalfOpen},
+				{action: "allow", expectedErr: breaker.ErrOpen, expectedState: breaker.StateHalfOpen},
+			},
+			expectedTransitions: []string{"closed->open", "open->half-open"},
+		},
+		{
+			name:        "disabled case",
+			inThreshold: 0,
+			inProbes:    1,
+			inSteps: []breakerStep{
+				{action: "failure", expectedState: breaker.StateClosed},
+				{action: "failure", expectedState: breaker.StateClosed},
+				{action: "allow", expectedErr: nil, expectedState: breaker.StateClosed},
+			},
+			expectedTransitions: nil,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var transitions []string
+
+			b := breaker.New(c.inThreshold, cooldown, c.inProbes, func(from string, to string) {
+				transitions = append(transitions, fmt.Sprintf("%s->%s", from, to))
+			})
+
+			for i, s := range c.inSteps {
+				switch s.action {
+				case "allow":
+					assert.ErrorIs(t, b.Allow(), s.expectedErr, "step %d", i)
+
+				case "success":
+					b.Success()
+
+				case "failure":
+					b.Failure()
+
+				case "release":
+					b.Release()
+
+				case "wait":
+					time.Sleep(cooldown + 10*time.Millisecond)
+				}
+
+				assert.Equal(t, s.expectedState, b.State(), "step %d", i)
+			}
+
+			assert.Equal(t, c.expectedTransitions, transitions)
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/xoticdsign/effectivemobile/internal/utils/config"
+)
+
+func TestReadProviders_Unit(t *testing.T) {
+	cases := []struct {
+		name              string
+		inNames           []string
+		inEnv             map[string]string
+		expectedProviders map[string]config.ProviderConfig
+		expectedErr       bool
+	}{
+		{
+			name:    "happy case",
+			inNames: []string{"first", " second "},
+			inEnv: map[string]string{
+				"CLIENT_FIRSTURL":      "https://first.local",
+				"CLIENT_FIRSTKEY":      "secret",
+				"CLIENT_FIRSTTIMEOUT":  "2s",
+				"CLIENT_FIRSTENABLED":  "true",
+				"CLIENT_SECONDURL":     "https://second.local",
+				"CLIENT_SECONDTIMEOUT": "3s",
+				"CLIENT_SECONDENABLED": "false",
+			},
+			expectedProviders: map[string]config.ProviderConfig{
+				"first":  {URL: "https://first.local", Key: "secret", Timeout: 2 * time.Second, Enabled: true},
+				"second": {URL: "https://second.local", Timeout: 3 * time.Second, Enabled: false},
+			},
+		},
+		{
+			name:              "no providers case",
+			inNames:           nil,
+			expectedProviders: map[string]config.ProviderConfig{},
+		},
+		{
+			name:    "missing url case",
+			inNames: []string{"third"},
+			inEnv: map[string]string{
+				"CLIENT_THIRDTIMEOUT": "2s",
+				"CLIENT_THIRDENABLED": "true",
+			},
+			expectedErr: true,
+		},
+		{
+			name:    "malformed timeout case",
+			inNames: []string{"fourth"},
+			inEnv: map[string]string{
+				"CLIENT_FOURTHURL":     "https://fourth.local",
+				"CLIENT_FOURTHTIMEOUT": "soon",
+				"CLIENT_FOURTHENABLED": "true",
+			},
+			expectedErr: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			for k, v := range c.inEnv {
+				t.Setenv(k, v)
+			}
+
+			cfg := config.ClientConfig{ProviderNames: c.inNames}
+
+			err := cfg.ReadProviders()
+
+			if c.expectedErr {
+				assert.Error(t, err)
+
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, c.expectedProviders, cfg.Providers)
+		})
+	}
+}
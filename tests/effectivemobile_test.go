@@ -9,11 +9,16 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/stretchr/testify/assert"
 
 	effectivemobileapp "github.com/xoticdsign/effectivemobile/internal/app/effectivemobile"
+	"github.com/xoticdsign/effectivemobile/internal/client"
+	"github.com/xoticdsign/effectivemobile/internal/client/breaker"
+	"github.com/xoticdsign/effectivemobile/internal/client/cache"
+	"github.com/xoticdsign/effectivemobile/internal/client/quota"
 	effectivemobileservice "github.com/xoticdsign/effectivemobile/internal/service/effectivemobile"
 	storage "github.com/xoticdsign/effectivemobile/internal/storage/postgresql"
 	"github.com/xoticdsign/effectivemobile/tests/suite"
@@ -37,6 +42,7 @@ func TestDeleteByID_Functional(t *testing.T) {
 		name         string
 		inMethod     string
 		inTarget     string
+		inIfMatch    string
 		expectedErr  error
 		expectedCode int
 		expectedBody effectivemobileapp.DeleteByIDResponse
@@ -84,12 +90,102 @@ func TestDeleteByID_Functional(t *testing.T) {
 			expectedCode: fiber.StatusInternalServerError,
 			expectedBody: effectivemobileapp.DeleteByIDResponse{},
 		},
+		{
+			name:         "if match case",
+			inMethod:     http.MethodDelete,
+			inTarget:     fmt.Sprintf("/%s/1", effectivemobileapp.DeleteByIDHanlder),
+			inIfMatch:    `"1"`,
+			expectedErr:  nil,
+			expectedCode: fiber.StatusOK,
+			expectedBody: effectivemobileapp.DeleteByIDResponse{
+				Code:    fiber.StatusOK,
+				Message: effectivemobileapp.DeleteByIDSuccess,
+			},
+		},
+		{
+			name:         "weak if match case",
+			inMethod:     http.MethodDelete,
+			inTarget:     fmt.Sprintf("/%s/1", effectivemobileapp.DeleteByIDHanlder),
+			inIfMatch:    `W/"1"`,
+			expectedErr:  nil,
+			expectedCode: fiber.StatusPreconditionFailed,
+			expectedBody: effectivemobileapp.DeleteByIDResponse{},
+		},
+		{
+			name:         "if match list case",
+			inMethod:     http.MethodDelete,
+			inTarget:     fmt.Sprintf("/%s/1", effectivemobileapp.DeleteByIDHanlder),
+			inIfMatch:    `W/"2", "3", "1"`,
+			expectedErr:  nil,
+			expectedCode: fiber.StatusOK,
+			expectedBody: effectivemobileapp.DeleteByIDResponse{
+				Code:    fiber.StatusOK,
+				Message: effectivemobileapp.DeleteByIDSuccess,
+			},
+		},
+		{
+			name:         "if match wildcard case",
+			inMethod:     http.MethodDelete,
+			inTarget:     fmt.Sprintf("/%s/1", effectivemobileapp.DeleteByIDHanlder),
+			inIfMatch:    "*",
+			expectedErr:  nil,
+			expectedCode: fiber.StatusOK,
+			expectedBody: effectivemobileapp.DeleteByIDResponse{
+				Code:    fiber.StatusOK,
+				Message: effectivemobileapp.DeleteByIDSuccess,
+			},
+		},
+		{
+			name:         "weak if match list case",
+			inMethod:     http.MethodDelete,
+			inTarget:     fmt.Sprintf("/%s/1", effectivemobileapp.DeleteByIDHanlder),
+			inIfMatch:    `W/"1", W/"2"`,
+			expectedErr:  nil,
+			expectedCode: fiber.StatusPreconditionFailed,
+			expectedBody: effectivemobileapp.DeleteByIDResponse{},
+		},
+		{
+			name:         "invalid if match list case",
+			inMethod:     http.MethodDelete,
+			inTarget:     fmt.Sprintf("/%s/1", effectivemobileapp.DeleteByIDHanlder),
+			inIfMatch:    `"1", *`,
+			expectedErr:  nil,
+			expectedCode: fiber.StatusBadRequest,
+			expectedBody: effectivemobileapp.DeleteByIDResponse{},
+		},
+		{
+			name:         "invalid if match case",
+			inMethod:     http.MethodDelete,
+			inTarget:     fmt.Sprintf("/%s/1", effectivemobileapp.DeleteByIDHanlder),
+			inIfMatch:    "abc",
+			expectedErr:  nil,
+			expectedCode: fiber.StatusBadRequest,
+			expectedBody: effectivemobileapp.DeleteByIDResponse{},
+		},
+		{
+			name:         "storage precondition case",
+			inMethod:     http.MethodDelete,
+			inTarget:     fmt.Sprintf("/%s/s412", effectivemobileapp.DeleteByIDHanlder),
+			inIfMatch:    `"1"`,
+			expectedErr:  nil,
+			expectedCode: fiber.StatusPreconditionFailed,
+			expectedBody: effectivemobileapp.DeleteByIDResponse{},
+		},
+		{
+			name:         "storage timeout case",
+			inMethod:     http.MethodDelete,
+			inTarget:     fmt.Sprintf("/%s/s504", effectivemobileapp.DeleteByIDHanlder),
+			expectedErr:  nil,
+			expectedCode: fiber.StatusGatewayTimeout,
+			expectedBody: effectivemobileapp.DeleteByIDResponse{},
+		},
 	}
 
 	for _, c := range cases {
 		s.T.Run(c.name, func(t *testing.T) {
 			r := httptest.NewRequest(c.inMethod, c.inTarget, nil)
 			r.Header.Set("Content-Type", "application/json")
+			r.Header.Set("If-Match", c.inIfMatch)
 
 			resp, err := f.Test(r, int(s.Config.EffectiveMobile.Client.Timeout))
 			if err != nil {
@@ -133,8 +229,10 @@ func TestUpdateByID_Functional(t *testing.T) {
 		inMethod     string
 		inBody       effectivemobileapp.UpdateByIDRequest
 		inTarget     string
+		inIfMatch    string
 		expectedErr  error
 		expectedCode int
+		expectedETag string
 		expectedBody effectivemobileapp.UpdateByIDResponse
 	}{
 		{
@@ -146,6 +244,7 @@ func TestUpdateByID_Functional(t *testing.T) {
 			inTarget:     fmt.Sprintf("/%s/1", effectivemobileapp.UpdateByIDHandler),
 			expectedErr:  nil,
 			expectedCode: fiber.StatusOK,
+			expectedETag: `"1"`,
 			expectedBody: effectivemobileapp.UpdateByIDResponse{
 				Code:    fiber.StatusOK,
 				Message: effectivemobileapp.UpdateByIDSuccess,
@@ -196,6 +295,51 @@ func TestUpdateByID_Functional(t *testing.T) {
 			expectedCode: fiber.StatusInternalServerError,
 			expectedBody: effectivemobileapp.UpdateByIDResponse{},
 		},
+		{
+			name:     "if match case",
+			inMethod: http.MethodPut,
+			inBody: effectivemobileapp.UpdateByIDRequest{
+				Name: "test",
+			},
+			inTarget:     fmt.Sprintf("/%s/1", effectivemobileapp.UpdateByIDHandler),
+			inIfMatch:    `"2"`,
+			expectedErr:  nil,
+			expectedCode: fiber.StatusOK,
+			expectedETag: `"3"`,
+			expectedBody: effectivemobileapp.UpdateByIDResponse{
+				Code:    fiber.StatusOK,
+				Message: effectivemobileapp.UpdateByIDSuccess,
+			},
+		},
+		{
+			name:         "invalid if match case",
+			inMethod:     http.MethodPut,
+			inBody:       effectivemobileapp.UpdateByIDRequest{},
+			inTarget:     fmt.Sprintf("/%s/1", effectivemobileapp.UpdateByIDHandler),
+			inIfMatch:    "abc",
+			expectedErr:  nil,
+			expectedCode: fiber.StatusBadRequest,
+			expectedBody: effectivemobileapp.UpdateByIDResponse{},
+		},
+		{
+			name:         "storage precondition case",
+			inMethod:     http.MethodPut,
+			inBody:       effectivemobileapp.UpdateByIDRequest{},
+			inTarget:     fmt.Sprintf("/%s/s412", effectivemobileapp.UpdateByIDHandler),
+			inIfMatch:    `"1"`,
+			expectedErr:  nil,
+			expectedCode: fiber.StatusPreconditionFailed,
+			expectedBody: effectivemobileapp.UpdateByIDResponse{},
+		},
+		{
+			name:         "storage timeout case",
+			inMethod:     http.MethodPut,
+			inBody:       effectivemobileapp.UpdateByIDRequest{},
+			inTarget:     fmt.Sprintf("/%s/s504", effectivemobileapp.UpdateByIDHandler),
+			expectedErr:  nil,
+			expectedCode: fiber.StatusGatewayTimeout,
+			expectedBody: effectivemobileapp.UpdateByIDResponse{},
+		},
 	}
 
 	for _, c := range cases {
@@ -204,6 +348,7 @@ func TestUpdateByID_Functional(t *testing.T) {
 
 			r := httptest.NewRequest(c.inMethod, c.inTarget, bytes.NewBuffer(b))
 			r.Header.Set("Content-Type", "application/json")
+			r.Header.Set("If-Match", c.inIfMatch)
 
 			resp, err := f.Test(r, int(s.Config.EffectiveMobile.Client.Timeout))
 			if err != nil {
@@ -214,6 +359,8 @@ func TestUpdateByID_Functional(t *testing.T) {
 			assert.Equal(t, c.expectedCode, resp.StatusCode)
 
 			if resp.StatusCode == fiber.StatusOK {
+				assert.Equal(t, c.expectedETag, resp.Header.Get("ETag"))
+
 				var body effectivemobileapp.UpdateByIDResponse
 
 				rb, err := io.ReadAll(resp.Body)
@@ -228,7 +375,7 @@ func TestUpdateByID_Functional(t *testing.T) {
 	}
 }
 
-func TestCreate_Functional(t *testing.T) {
+func TestPatchByID_Functional(t *testing.T) {
 	s := suite.New(t)
 
 	h := effectivemobileapp.Handlers{
@@ -240,94 +387,247 @@ func TestCreate_Functional(t *testing.T) {
 	f := fiber.New()
 	defer f.Shutdown()
 
-	f.Post(fmt.Sprintf("/%s", effectivemobileapp.CreateHandler), h.Create)
+	f.Patch(fmt.Sprintf("/%s/%s", effectivemobileapp.PeopleHandler, effectivemobileapp.PatchByIDParameters), h.PatchByID)
 
 	cases := []struct {
-		name         string
-		inMethod     string
-		inBody       effectivemobileapp.CreateRequest
-		inTarget     string
-		expectedErr  error
-		expectedCode int
-		expectedBody effectivemobileapp.CreateResponse
+		name          string
+		inMethod      string
+		inContentType string
+		inBody        string
+		inTarget      string
+		inIfMatch     string
+		expectedErr   error
+		expectedCode  int
+		expectedETag  string
+		expectedBody  effectivemobileapp.PatchByIDResponse
 	}{
 		{
-			name:     "happy case",
-			inMethod: http.MethodPost,
-			inBody: effectivemobileapp.CreateRequest{
-				Name:    "test",
-				Surname: "test",
-			},
-			inTarget:     fmt.Sprintf("/%s", effectivemobileapp.CreateHandler),
-			expectedErr:  nil,
-			expectedCode: fiber.StatusOK,
-			expectedBody: effectivemobileapp.CreateResponse{
+			name:          "happy case",
+			inMethod:      http.MethodPatch,
+			inContentType: "application/merge-patch+json",
+			inBody:        `{"name":"test","age":0,"patronymic":null}`,
+			inTarget:      fmt.Sprintf("/%s/1", effectivemobileapp.PeopleHandler),
+			expectedErr:   nil,
+			expectedCode:  fiber.StatusOK,
+			expectedETag:  `"1"`,
+			expectedBody: effectivemobileapp.PatchByIDResponse{
 				Code:    fiber.StatusOK,
-				Message: effectivemobileapp.CreateSuccess,
+				Message: effectivemobileapp.PatchByIDSuccess,
+				Result:  storage.Row{ID: 1, Version: 1},
 			},
 		},
 		{
-			name:         "bad request case",
-			inMethod:     http.MethodPost,
-			inBody:       effectivemobileapp.CreateRequest{},
-			inTarget:     fmt.Sprintf("/%s", effectivemobileapp.CreateHandler),
-			expectedErr:  nil,
-			expectedCode: fiber.StatusBadRequest,
-			expectedBody: effectivemobileapp.CreateResponse{},
+			name:          "json content type case",
+			inMethod:      http.MethodPatch,
+			inContentType: fiber.MIMEApplicationJSON,
+			inBody:        `{}`,
+			inTarget:      fmt.Sprintf("/%s/1", effectivemobileapp.PeopleHandler),
+			expectedErr:   nil,
+			expectedCode:  fiber.StatusOK,
+			expectedETag:  `"1"`,
+			expectedBody: effectivemobileapp.PatchByIDResponse{
+				Code:    fiber.StatusOK,
+				Message: effectivemobileapp.PatchByIDSuccess,
+				Result:  storage.Row{ID: 1, Version: 1},
+			},
 		},
 		{
-			name:         "worng method case",
-			inMethod:     http.MethodGet,
-			inBody:       effectivemobileapp.CreateRequest{},
-			inTarget:     fmt.Sprintf("/%s", effectivemobileapp.CreateHandler),
-			expectedErr:  nil,
-			expectedCode: fiber.StatusMethodNotAllowed,
-			expectedBody: effectivemobileapp.CreateResponse{},
+			name:          "unknown field case",
+			inMethod:      http.MethodPatch,
+			inContentType: "application/merge-patch+json",
+			inBody:        `{"nickname":"test"}`,
+			inTarget:      fmt.Sprintf("/%s/1", effectivemobileapp.PeopleHandler),
+			expectedErr:   nil,
+			expectedCode:  fiber.StatusBadRequest,
+			expectedBody:  effectivemobileapp.PatchByIDResponse{},
 		},
 		{
-			name:     "storage not found case",
-			inMethod: http.MethodPost,
-			inBody: effectivemobileapp.CreateRequest{
-				Name:    "s404",
-				Surname: "test",
+			name:          "clear enrichment field case",
+			inMethod:      http.MethodPatch,
+			inContentType: "application/merge-patch+json",
+			inBody:        `{"age":null,"gender":null,"nationality":null}`,
+			inTarget:      fmt.Sprintf("/%s/1", effectivemobileapp.PeopleHandler),
+			expectedErr:   nil,
+			expectedCode:  fiber.StatusOK,
+			expectedETag:  `"1"`,
+			expectedBody: effectivemobileapp.PatchByIDResponse{
+				Code:    fiber.StatusOK,
+				Message: effectivemobileapp.PatchByIDSuccess,
+				Result:  storage.Row{ID: 1, Version: 1},
 			},
-			inTarget:     fmt.Sprintf("/%s", effectivemobileapp.CreateHandler),
-			expectedErr:  nil,
-			expectedCode: fiber.StatusNotFound,
-			expectedBody: effectivemobileapp.CreateResponse{},
 		},
 		{
-			name:     "client not found case",
-			inMethod: http.MethodPost,
-			inBody: effectivemobileapp.CreateRequest{
-				Name:    "c404",
-				Surname: "test",
+			name:          "null on required field case",
+			inMethod:      http.MethodPatch,
+			inContentType: "application/merge-patch+json",
+			inBody:        `{"name":null}`,
+			inTarget:      fmt.Sprintf("/%s/1", effectivemobileapp.PeopleHandler),
+			expectedErr:   nil,
+			expectedCode:  fiber.StatusBadRequest,
+			expectedBody:  effectivemobileapp.PatchByIDResponse{},
+		},
+		{
+			name:          "wrong type case",
+			inMethod:      http.MethodPatch,
+			inContentType: "application/merge-patch+json",
+			inBody:        `{"age":"21"}`,
+			inTarget:      fmt.Sprintf("/%s/1", effectivemobileapp.PeopleHandler),
+			expectedErr:   nil,
+			expectedCode:  fiber.StatusBadRequest,
+			expectedBody:  effectivemobileapp.PatchByIDResponse{},
+		},
+		{
+			name:          "not an object case",
+			inMethod:      http.MethodPatch,
+			inContentType: "application/merge-patch+json",
+			inBody:        `["name"]`,
+			inTarget:      fmt.Sprintf("/%s/1", effectivemobileapp.PeopleHandler),
+			expectedErr:   nil,
+			expectedCode:  fiber.StatusBadRequest,
+			expectedBody:  effectivemobileapp.PatchByIDResponse{},
+		},
+		{
+			name:          "unsupported media type case",
+			inMethod:      http.MethodPatch,
+			inContentType: fiber.MIMETextPlain,
+			inBody:        `{"name":"test"}`,
+			inTarget:      fmt.Sprintf("/%s/1", effectivemobileapp.PeopleHandler),
+			expectedErr:   nil,
+			expectedCode:  fiber.StatusUnsupportedMediaType,
+			expectedBody:  effectivemobileapp.PatchByIDResponse{},
+		},
+		{
+			name:          "worng method case",
+			inMethod:      http.MethodPost,
+			inContentType: "application/merge-patch+json",
+			inBody:        `{}`,
+			inTarget:      fmt.Sprintf("/%s/1", effectivemobileapp.PeopleHandler),
+			expectedErr:   nil,
+			expectedCode:  fiber.StatusMethodNotAllowed,
+			expectedBody:  effectivemobileapp.PatchByIDResponse{},
+		},
+		{
+			name:          "storage invalid case",
+			inMethod:      http.MethodPatch,
+			inContentType: "application/merge-patch+json",
+			inBody:        `{}`,
+			inTarget:      fmt.Sprintf("/%s/s400", effectivemobileapp.PeopleHandler),
+			expectedErr:   nil,
+			expectedCode:  fiber.StatusBadRequest,
+			expectedBody:  effectivemobileapp.PatchByIDResponse{},
+		},
+		{
+			name:          "storage not found case",
+			inMethod:      http.MethodPatch,
+			inContentType: "application/merge-patch+json",
+			inBody:        `{}`,
+			inTarget:      fmt.Sprintf("/%s/s404", effectivemobileapp.PeopleHandler),
+			expectedErr:   nil,
+			expectedCode:  fiber.StatusNotFound,
+			expectedBody:  effectivemobileapp.PatchByIDResponse{},
+		},
+		{
+			name:          "storage conflict case",
+			inMethod:      http.MethodPatch,
+			inContentType: "application/merge-patch+json",
+			inBody:        `{}`,
+			inTarget:      fmt.Sprintf("/%s/s409", effectivemobileapp.PeopleHandler),
+			expectedErr:   nil,
+			expectedCode:  fiber.StatusConflict,
+			expectedBody:  effectivemobileapp.PatchByIDResponse{},
+		},
+		{
+			name:          "storage internal case",
+			inMethod:      http.MethodPatch,
+			inContentType: "application/merge-patch+json",
+			inBody:        `{}`,
+			inTarget:      fmt.Sprintf("/%s/s500", effectivemobileapp.PeopleHandler),
+			expectedErr:   nil,
+			expectedCode:  fiber.StatusInternalServerError,
+			expectedBody:  effectivemobileapp.PatchByIDResponse{},
+		},
+		{
+			name:          "if match case",
+			inMethod:      http.MethodPatch,
+			inContentType: "application/merge-patch+json",
+			inBody:        `{"name":"test"}`,
+			inTarget:      fmt.Sprintf("/%s/1", effectivemobileapp.PeopleHandler),
+			inIfMatch:     `"4"`,
+			expectedErr:   nil,
+			expectedCode:  fiber.StatusOK,
+			expectedETag:  `"5"`,
+			expectedBody: effectivemobileapp.PatchByIDResponse{
+				Code:    fiber.StatusOK,
+				Message: effectivemobileapp.PatchByIDSuccess,
+				Result:  storage.Row{ID: 1, Version: 5},
 			},
-			inTarget:     fmt.Sprintf("/%s", effectivemobileapp.CreateHandler),
-			expectedErr:  nil,
-			expectedCode: fiber.StatusNotFound,
-			expectedBody: effectivemobileapp.CreateResponse{},
 		},
 		{
-			name:     "internal case",
-			inMethod: http.MethodPost,
-			inBody: effectivemobileapp.CreateRequest{
-				Name:    "500",
-				Surname: "test",
+			name:          "if match list case",
+			inMethod:      http.MethodPatch,
+			inContentType: "application/merge-patch+json",
+			inBody:        `{"name":"test"}`,
+			inTarget:      fmt.Sprintf("/%s/1", effectivemobileapp.PeopleHandler),
+			inIfMatch:     `"3", W/"7", "4"`,
+			expectedErr:   nil,
+			expectedCode:  fiber.StatusOK,
+			expectedETag:  `"5"`,
+			expectedBody: effectivemobileapp.PatchByIDResponse{
+				Code:    fiber.StatusOK,
+				Message: effectivemobileapp.PatchByIDSuccess,
+				Result:  storage.Row{ID: 1, Version: 5},
 			},
-			inTarget:     fmt.Sprintf("/%s", effectivemobileapp.CreateHandler),
-			expectedErr:  nil,
-			expectedCode: fiber.StatusInternalServerError,
-			expectedBody: effectivemobileapp.CreateResponse{},
+		},
+		{
+			name:          "weak if match case",
+			inMethod:      http.MethodPatch,
+			inContentType: "application/merge-patch+json",
+			inBody:        `{"name":"test"}`,
+			inTarget:      fmt.Sprintf("/%s/1", effectivemobileapp.PeopleHandler),
+			inIfMatch:     `W/"4"`,
+			expectedErr:   nil,
+			expectedCode:  fiber.StatusPreconditionFailed,
+			expectedBody:  effectivemobileapp.PatchByIDResponse{},
+		},
+		{
+			name:          "invalid if match case",
+			inMethod:      http.MethodPatch,
+			inContentType: "application/merge-patch+json",
+			inBody:        `{"name":"test"}`,
+			inTarget:      fmt.Sprintf("/%s/1", effectivemobileapp.PeopleHandler),
+			inIfMatch:     `"abc"`,
+			expectedErr:   nil,
+			expectedCode:  fiber.StatusBadRequest,
+			expectedBody:  effectivemobileapp.PatchByIDResponse{},
+		},
+		{
+			name:          "storage precondition case",
+			inMethod:      http.MethodPatch,
+			inContentType: "application/merge-patch+json",
+			inBody:        `{"name":"test"}`,
+			inTarget:      fmt.Sprintf("/%s/s412", effectivemobileapp.PeopleHandler),
+			inIfMatch:     `"4"`,
+			expectedErr:   nil,
+			expectedCode:  fiber.StatusPreconditionFailed,
+			expectedBody:  effectivemobileapp.PatchByIDResponse{},
+		},
+		{
+			name:          "storage timeout case",
+			inMethod:      http.MethodPatch,
+			inContentType: "application/merge-patch+json",
+			inBody:        `{}`,
+			inTarget:      fmt.Sprintf("/%s/s504", effectivemobileapp.PeopleHandler),
+			expectedErr:   nil,
+			expectedCode:  fiber.StatusGatewayTimeout,
+			expectedBody:  effectivemobileapp.PatchByIDResponse{},
 		},
 	}
 
 	for _, c := range cases {
 		s.T.Run(c.name, func(t *testing.T) {
-			b, _ := json.Marshal(c.inBody)
-
-			r := httptest.NewRequest(c.inMethod, c.inTarget, bytes.NewBuffer(b))
-			r.Header.Set("Content-Type", "application/json")
+			r := httptest.NewRequest(c.inMethod, c.inTarget, strings.NewReader(c.inBody))
+			r.Header.Set("Content-Type", c.inContentType)
+			r.Header.Set("If-Match", c.inIfMatch)
 
 			resp, err := f.Test(r, int(s.Config.EffectiveMobile.Client.Timeout))
 			if err != nil {
@@ -338,7 +638,9 @@ func TestCreate_Functional(t *testing.T) {
 			assert.Equal(t, c.expectedCode, resp.StatusCode)
 
 			if resp.StatusCode == fiber.StatusOK {
-				var body effectivemobileapp.CreateResponse
+				assert.Equal(t, c.expectedETag, resp.Header.Get("ETag"))
+
+				var body effectivemobileapp.PatchByIDResponse
 
 				rb, err := io.ReadAll(resp.Body)
 				assert.NoError(t, err)
@@ -352,7 +654,7 @@ func TestCreate_Functional(t *testing.T) {
 	}
 }
 
-func TestSelect_Functional(t *testing.T) {
+func TestRestoreByID_Functional(t *testing.T) {
 	s := suite.New(t)
 
 	h := effectivemobileapp.Handlers{
@@ -364,79 +666,1812 @@ func TestSelect_Functional(t *testing.T) {
 	f := fiber.New()
 	defer f.Shutdown()
 
-	f.Get(fmt.Sprintf("/%s/%s", effectivemobileapp.SelectHandler, effectivemobileapp.SelectParameters), h.Select)
+	f.Post(fmt.Sprintf("/%s/%s", effectivemobileapp.PeopleHandler, effectivemobileapp.RestoreByIDParameters), h.RestoreByID)
 
 	cases := []struct {
 		name         string
 		inMethod     string
-		inParameters []string
 		inTarget     string
+		inIfMatch    string
 		expectedErr  error
 		expectedCode int
-		expectedBody effectivemobileapp.SelectResponse
+		expectedETag string
+		expectedBody effectivemobileapp.RestoreByIDResponse
 	}{
 		{
 			name:         "happy case",
-			inMethod:     http.MethodGet,
-			inParameters: nil,
-			inTarget:     fmt.Sprintf("/%s/1", effectivemobileapp.SelectHandler),
+			inMethod:     http.MethodPost,
+			inTarget:     fmt.Sprintf("/%s/1/restore", effectivemobileapp.PeopleHandler),
 			expectedErr:  nil,
 			expectedCode: fiber.StatusOK,
-			expectedBody: effectivemobileapp.SelectResponse{
+			expectedETag: `"1"`,
+			expectedBody: effectivemobileapp.RestoreByIDResponse{
 				Code:    fiber.StatusOK,
-				Message: effectivemobileapp.SelectSuccess,
-				Result:  []storage.Row{},
+				Message: effectivemobileapp.RestoreByIDSuccess,
+				Result:  storage.Row{ID: 1, Version: 1},
 			},
 		},
 		{
-			name:         "bad request case",
-			inMethod:     http.MethodGet,
-			inParameters: nil,
-			inTarget:     fmt.Sprintf("/%s", effectivemobileapp.SelectHandler),
+			name:         "if match case",
+			inMethod:     http.MethodPost,
+			inTarget:     fmt.Sprintf("/%s/1/restore", effectivemobileapp.PeopleHandler),
+			inIfMatch:    `"2"`,
 			expectedErr:  nil,
-			expectedCode: fiber.StatusBadRequest,
-			expectedBody: effectivemobileapp.SelectResponse{},
+			expectedCode: fiber.StatusOK,
+			expectedETag: `"3"`,
+			expectedBody: effectivemobileapp.RestoreByIDResponse{
+				Code:    fiber.StatusOK,
+				Message: effectivemobileapp.RestoreByIDSuccess,
+				Result:  storage.Row{ID: 1, Version: 3},
+			},
 		},
 		{
-			name:         "worng method case",
-			inMethod:     http.MethodPost,
-			inParameters: nil,
-			inTarget:     fmt.Sprintf("/%s/1", effectivemobileapp.SelectHandler),
+			name:         "wrong method case",
+			inMethod:     http.MethodGet,
+			inTarget:     fmt.Sprintf("/%s/1/restore", effectivemobileapp.PeopleHandler),
 			expectedErr:  nil,
 			expectedCode: fiber.StatusMethodNotAllowed,
-			expectedBody: effectivemobileapp.SelectResponse{},
+			expectedBody: effectivemobileapp.RestoreByIDResponse{},
 		},
 		{
 			name:         "storage not found case",
-			inMethod:     http.MethodGet,
-			inParameters: nil,
-			inTarget:     fmt.Sprintf("/%s/s404", effectivemobileapp.SelectHandler),
+			inMethod:     http.MethodPost,
+			inTarget:     fmt.Sprintf("/%s/s404/restore", effectivemobileapp.PeopleHandler),
 			expectedErr:  nil,
 			expectedCode: fiber.StatusNotFound,
-			expectedBody: effectivemobileapp.SelectResponse{},
+			expectedBody: effectivemobileapp.RestoreByIDResponse{},
+		},
+		{
+			name:         "storage not deleted case",
+			inMethod:     http.MethodPost,
+			inTarget:     fmt.Sprintf("/%s/s409/restore", effectivemobileapp.PeopleHandler),
+			expectedErr:  nil,
+			expectedCode: fiber.StatusConflict,
+			expectedBody: effectivemobileapp.RestoreByIDResponse{},
+		},
+		{
+			name:         "invalid if match case",
+			inMethod:     http.MethodPost,
+			inTarget:     fmt.Sprintf("/%s/1/restore", effectivemobileapp.PeopleHandler),
+			inIfMatch:    `"1`,
+			expectedErr:  nil,
+			expectedCode: fiber.StatusBadRequest,
+			expectedBody: effectivemobileapp.RestoreByIDResponse{},
+		},
+		{
+			name:         "storage precondition case",
+			inMethod:     http.MethodPost,
+			inTarget:     fmt.Sprintf("/%s/s412/restore", effectivemobileapp.PeopleHandler),
+			inIfMatch:    `"1"`,
+			expectedErr:  nil,
+			expectedCode: fiber.StatusPreconditionFailed,
+			expectedBody: effectivemobileapp.RestoreByIDResponse{},
 		},
 		{
 			name:         "storage internal case",
-			inMethod:     http.MethodGet,
-			inParameters: nil,
-			inTarget:     fmt.Sprintf("/%s/s500", effectivemobileapp.SelectHandler),
+			inMethod:     http.MethodPost,
+			inTarget:     fmt.Sprintf("/%s/s500/restore", effectivemobileapp.PeopleHandler),
 			expectedErr:  nil,
 			expectedCode: fiber.StatusInternalServerError,
-			expectedBody: effectivemobileapp.SelectResponse{},
+			expectedBody: effectivemobileapp.RestoreByIDResponse{},
+		},
+		{
+			name:         "storage timeout case",
+			inMethod:     http.MethodPost,
+			inTarget:     fmt.Sprintf("/%s/s504/restore", effectivemobileapp.PeopleHandler),
+			expectedErr:  nil,
+			expectedCode: fiber.StatusGatewayTimeout,
+			expectedBody: effectivemobileapp.RestoreByIDResponse{},
 		},
 	}
 
 	for _, c := range cases {
 		s.T.Run(c.name, func(t *testing.T) {
-			var target string
+			r := httptest.NewRequest(c.inMethod, c.inTarget, nil)
+			r.Header.Set("Content-Type", "application/json")
+			r.Header.Set("If-Match", c.inIfMatch)
 
-			if c.inParameters != nil {
+			resp, err := f.Test(r, int(s.Config.EffectiveMobile.Client.Timeout))
+			if err != nil {
+				assert.Equal(t, c.expectedErr, err)
+			}
+			defer resp.Body.Close()
+
+			assert.Equal(t, c.expectedCode, resp.StatusCode)
+
+			if resp.StatusCode == fiber.StatusOK {
+				assert.Equal(t, c.expectedETag, resp.Header.Get("ETag"))
+
+				var body effectivemobileapp.RestoreByIDResponse
+
+				rb, err := io.ReadAll(resp.Body)
+				assert.NoError(t, err)
+
+				err = json.Unmarshal(rb, &body)
+				assert.NoError(t, err)
+
+				assert.Equal(t, c.expectedBody, body)
+			}
+		})
+	}
+}
+
+func TestPurge_Functional(t *testing.T) {
+	s := suite.New(t)
+
+	h := effectivemobileapp.Handlers{
+		Service: effectivemobileservice.UnimplementedHandlers{},
+		Log:     s.Log.Log,
+		Config:  s.Config.EffectiveMobile,
+	}
+
+	f := fiber.New()
+	defer f.Shutdown()
+
+	f.Post(fmt.Sprintf("/%s", effectivemobileapp.PurgeHandler), h.Purge)
+
+	cases := []struct {
+		name         string
+		inMethod     string
+		inTarget     string
+		expectedErr  error
+		expectedCode int
+		expectedBody effectivemobileapp.PurgeResponse
+	}{
+		{
+			name:         "happy case",
+			inMethod:     http.MethodPost,
+			inTarget:     fmt.Sprintf("/%s", effectivemobileapp.PurgeHandler),
+			expectedErr:  nil,
+			expectedCode: fiber.StatusOK,
+			expectedBody: effectivemobileapp.PurgeResponse{
+				Code:    fiber.StatusOK,
+				Message: effectivemobileapp.PurgeSuccess,
+				Purged:  1,
+			},
+		},
+		{
+			name:         "retention case",
+			inMethod:     http.MethodPost,
+			inTarget:     fmt.Sprintf("/%s?retention=24h", effectivemobileapp.PurgeHandler),
+			expectedErr:  nil,
+			expectedCode: fiber.StatusOK,
+			expectedBody: effectivemobileapp.PurgeResponse{
+				Code:    fiber.StatusOK,
+				Message: effectivemobileapp.PurgeSuccess,
+				Purged:  1,
+			},
+		},
+		{
+			name:         "bad retention case",
+			inMethod:     http.MethodPost,
+			inTarget:     fmt.Sprintf("/%s?retention=-1h", effectivemobileapp.PurgeHandler),
+			expectedErr:  nil,
+			expectedCode: fiber.StatusBadRequest,
+			expectedBody: effectivemobileapp.PurgeResponse{},
+		},
+		{
+			name:         "wrong method case",
+			inMethod:     http.MethodGet,
+			inTarget:     fmt.Sprintf("/%s", effectivemobileapp.PurgeHandler),
+			expectedErr:  nil,
+			expectedCode: fiber.StatusMethodNotAllowed,
+			expectedBody: effectivemobileapp.PurgeResponse{},
+		},
+		{
+			name:         "storage internal case",
+			inMethod:     http.MethodPost,
+			inTarget:     fmt.Sprintf("/%s?retention=500h", effectivemobileapp.PurgeHandler),
+			expectedErr:  nil,
+			expectedCode: fiber.StatusInternalServerError,
+			expectedBody: effectivemobileapp.PurgeResponse{},
+		},
+		{
+			name:         "storage timeout case",
+			inMethod:     http.MethodPost,
+			inTarget:     fmt.Sprintf("/%s?retention=504h", effectivemobileapp.PurgeHandler),
+			expectedErr:  nil,
+			expectedCode: fiber.StatusGatewayTimeout,
+			expectedBody: effectivemobileapp.PurgeResponse{},
+		},
+	}
+
+	for _, c := range cases {
+		s.T.Run(c.name, func(t *testing.T) {
+			r := httptest.NewRequest(c.inMethod, c.inTarget, nil)
+			r.Header.Set("Content-Type", "application/json")
+
+			resp, err := f.Test(r, int(s.Config.EffectiveMobile.Client.Timeout))
+			if err != nil {
+				assert.Equal(t, c.expectedErr, err)
+			}
+			defer resp.Body.Close()
+
+			assert.Equal(t, c.expectedCode, resp.StatusCode)
+
+			if resp.StatusCode == fiber.StatusOK {
+				var body effectivemobileapp.PurgeResponse
+
+				rb, err := io.ReadAll(resp.Body)
+				assert.NoError(t, err)
+
+				err = json.Unmarshal(rb, &body)
+				assert.NoError(t, err)
+
+				assert.Equal(t, c.expectedBody, body)
+			}
+		})
+	}
+}
+
+func TestCreate_Functional(t *testing.T) {
+	s := suite.New(t)
+
+	h := effectivemobileapp.Handlers{
+		Service: effectivemobileservice.UnimplementedHandlers{},
+		Log:     s.Log.Log,
+		Config:  s.Config.EffectiveMobile,
+	}
+
+	f := fiber.New()
+	defer f.Shutdown()
+
+	f.Post(fmt.Sprintf("/%s", effectivemobileapp.CreateHandler), h.Create)
+
+	kz := "KZ"
+	age := 21
+
+	cases := []struct {
+		name               string
+		inMethod           string
+		inBody             effectivemobileapp.CreateRequest
+		inTarget           string
+		expectedErr        error
+		expectedCode       int
+		expectedRetryAfter string
+		expectedBody       effectivemobileapp.CreateResponse
+	}{
+		{
+			name:     "happy case",
+			inMethod: http.MethodPost,
+			inBody: effectivemobileapp.CreateRequest{
+				Name:    "test",
+				Surname: "test",
+			},
+			inTarget:     fmt.Sprintf("/%s", effectivemobileapp.CreateHandler),
+			expectedErr:  nil,
+			expectedCode: fiber.StatusCreated,
+			expectedBody: effectivemobileapp.CreateResponse{
+				Code:    fiber.StatusCreated,
+				Message: effectivemobileapp.CreateSuccess,
+				Result: storage.Row{
+					ID:      1,
+					Name:    "test",
+					Surname: "test",
+				},
+			},
+		},
+		{
+			name:     "country hint case",
+			inMethod: http.MethodPost,
+			inBody: effectivemobileapp.CreateRequest{
+				Name:      "test",
+				Surname:   "test",
+				CountryID: " kz",
+			},
+			inTarget:     fmt.Sprintf("/%s", effectivemobileapp.CreateHandler),
+			expectedErr:  nil,
+			expectedCode: fiber.StatusCreated,
+			expectedBody: effectivemobileapp.CreateResponse{
+				Code:    fiber.StatusCreated,
+				Message: effectivemobileapp.CreateSuccess,
+				Result: storage.Row{
+					ID:           1,
+					Name:         "test",
+					Surname:      "test",
+					Localization: &kz,
+				},
+			},
+		},
+		{
+			name:     "bad country hint case",
+			inMethod: http.MethodPost,
+			inBody: effectivemobileapp.CreateRequest{
+				Name:      "test",
+				Surname:   "test",
+				CountryID: "KAZ",
+			},
+			inTarget:     fmt.Sprintf("/%s", effectivemobileapp.CreateHandler),
+			expectedErr:  nil,
+			expectedCode: fiber.StatusBadRequest,
+			expectedBody: effectivemobileapp.CreateResponse{},
+		},
+		{
+			name:         "bad request case",
+			inMethod:     http.MethodPost,
+			inBody:       effectivemobileapp.CreateRequest{},
+			inTarget:     fmt.Sprintf("/%s", effectivemobileapp.CreateHandler),
+			expectedErr:  nil,
+			expectedCode: fiber.StatusBadRequest,
+			expectedBody: effectivemobileapp.CreateResponse{},
+		},
+		{
+			name:         "worng method case",
+			inMethod:     http.MethodGet,
+			inBody:       effectivemobileapp.CreateRequest{},
+			inTarget:     fmt.Sprintf("/%s", effectivemobileapp.CreateHandler),
+			expectedErr:  nil,
+			expectedCode: fiber.StatusMethodNotAllowed,
+			expectedBody: effectivemobileapp.CreateResponse{},
+		},
+		{
+			name:     "storage not found case",
+			inMethod: http.MethodPost,
+			inBody: effectivemobileapp.CreateRequest{
+				Name:    "s404",
+				Surname: "test",
+			},
+			inTarget:     fmt.Sprintf("/%s", effectivemobileapp.CreateHandler),
+			expectedErr:  nil,
+			expectedCode: fiber.StatusNotFound,
+			expectedBody: effectivemobileapp.CreateResponse{},
+		},
+		{
+			name:     "client not found case",
+			inMethod: http.MethodPost,
+			inBody: effectivemobileapp.CreateRequest{
+				Name:    "c404",
+				Surname: "test",
+			},
+			inTarget:     fmt.Sprintf("/%s", effectivemobileapp.CreateHandler),
+			expectedErr:  nil,
+			expectedCode: fiber.StatusNotFound,
+			expectedBody: effectivemobileapp.CreateResponse{},
+		},
+		{
+			name:     "client unavailable case",
+			inMethod: http.MethodPost,
+			inBody: effectivemobileapp.CreateRequest{
+				Name:    "c503",
+				Surname: "test",
+			},
+			inTarget:     fmt.Sprintf("/%s", effectivemobileapp.CreateHandler),
+			expectedErr:  nil,
+			expectedCode: fiber.StatusServiceUnavailable,
+			expectedBody: effectivemobileapp.CreateResponse{},
+		},
+		{
+			name:     "best effort case",
+			inMethod: http.MethodPost,
+			inBody: effectivemobileapp.CreateRequest{
+				Name:    "partial",
+				Surname: "test",
+			},
+			inTarget:     fmt.Sprintf("/%s", effectivemobileapp.CreateHandler),
+			expectedErr:  nil,
+			expectedCode: fiber.StatusCreated,
+			expectedBody: effectivemobileapp.CreateResponse{
+				Code:    fiber.StatusCreated,
+				Message: effectivemobileapp.CreateSuccess,
+				Result: storage.Row{
+					ID:               1,
+					Name:             "partial",
+					Surname:          "test",
+					Age:              &age,
+					NeedsReview:      true,
+					EnrichmentStatus: storage.StatusEnriched,
+					EnrichmentFields: map[string]string{
+						"age":         storage.FieldEnriched,
+						"gender":      storage.FieldFailed,
+						"nationality": storage.FieldFailed,
+					},
+				},
+			},
+		},
+		{
+			name:     "async case",
+			inMethod: http.MethodPost,
+			inBody: effectivemobileapp.CreateRequest{
+				Name:      "test",
+				Surname:   "test",
+				CountryID: "kz",
+			},
+			inTarget:     fmt.Sprintf("/%s?async=true", effectivemobileapp.CreateHandler),
+			expectedErr:  nil,
+			expectedCode: fiber.StatusAccepted,
+			expectedBody: effectivemobileapp.CreateResponse{
+				Code:    fiber.StatusAccepted,
+				Message: effectivemobileapp.CreateAsyncSuccess,
+				Result: storage.Row{
+					ID:               1,
+					Name:             "test",
+					Surname:          "test",
+					Localization:     &kz,
+					EnrichmentStatus: storage.StatusPending,
+				},
+			},
+		},
+		{
+			name:     "async internal case",
+			inMethod: http.MethodPost,
+			inBody: effectivemobileapp.CreateRequest{
+				Name:    "500",
+				Surname: "test",
+			},
+			inTarget:     fmt.Sprintf("/%s?async=true", effectivemobileapp.CreateHandler),
+			expectedErr:  nil,
+			expectedCode: fiber.StatusInternalServerError,
+			expectedBody: effectivemobileapp.CreateResponse{},
+		},
+		{
+			name:     "async timeout case",
+			inMethod: http.MethodPost,
+			inBody: effectivemobileapp.CreateRequest{
+				Name:    "504",
+				Surname: "test",
+			},
+			inTarget:     fmt.Sprintf("/%s?async=true", effectivemobileapp.CreateHandler),
+			expectedErr:  nil,
+			expectedCode: fiber.StatusGatewayTimeout,
+			expectedBody: effectivemobileapp.CreateResponse{},
+		},
+		{
+			name:     "client unauthorized case",
+			inMethod: http.MethodPost,
+			inBody: effectivemobileapp.CreateRequest{
+				Name:    "c401",
+				Surname: "test",
+			},
+			inTarget:     fmt.Sprintf("/%s", effectivemobileapp.CreateHandler),
+			expectedErr:  nil,
+			expectedCode: fiber.StatusServiceUnavailable,
+			expectedBody: effectivemobileapp.CreateResponse{},
+		},
+		{
+			name:     "client payment required case",
+			inMethod: http.MethodPost,
+			inBody: effectivemobileapp.CreateRequest{
+				Name:    "c402",
+				Surname: "test",
+			},
+			inTarget:     fmt.Sprintf("/%s", effectivemobileapp.CreateHandler),
+			expectedErr:  nil,
+			expectedCode: fiber.StatusServiceUnavailable,
+			expectedBody: effectivemobileapp.CreateResponse{},
+		},
+		{
+			name:     "client rate limited case",
+			inMethod: http.MethodPost,
+			inBody: effectivemobileapp.CreateRequest{
+				Name:    "c429",
+				Surname: "test",
+			},
+			inTarget:           fmt.Sprintf("/%s", effectivemobileapp.CreateHandler),
+			expectedErr:        nil,
+			expectedCode:       fiber.StatusServiceUnavailable,
+			expectedRetryAfter: "2",
+			expectedBody:       effectivemobileapp.CreateResponse{},
+		},
+		{
+			name:     "internal case",
+			inMethod: http.MethodPost,
+			inBody: effectivemobileapp.CreateRequest{
+				Name:    "500",
+				Surname: "test",
+			},
+			inTarget:     fmt.Sprintf("/%s", effectivemobileapp.CreateHandler),
+			expectedErr:  nil,
+			expectedCode: fiber.StatusInternalServerError,
+			expectedBody: effectivemobileapp.CreateResponse{},
+		},
+		{
+			name:     "timeout case",
+			inMethod: http.MethodPost,
+			inBody: effectivemobileapp.CreateRequest{
+				Name:    "504",
+				Surname: "test",
+			},
+			inTarget:     fmt.Sprintf("/%s", effectivemobileapp.CreateHandler),
+			expectedErr:  nil,
+			expectedCode: fiber.StatusGatewayTimeout,
+			expectedBody: effectivemobileapp.CreateResponse{},
+		},
+	}
+
+	for _, c := range cases {
+		s.T.Run(c.name, func(t *testing.T) {
+			b, _ := json.Marshal(c.inBody)
+
+			r := httptest.NewRequest(c.inMethod, c.inTarget, bytes.NewBuffer(b))
+			r.Header.Set("Content-Type", "application/json")
+
+			resp, err := f.Test(r, int(s.Config.EffectiveMobile.Client.Timeout))
+			if err != nil {
+				assert.Equal(t, c.expectedErr, err)
+			}
+			defer resp.Body.Close()
+
+			assert.Equal(t, c.expectedCode, resp.StatusCode)
+			assert.Equal(t, c.expectedRetryAfter, resp.Header.Get("Retry-After"))
+
+			if resp.StatusCode == fiber.StatusCreated || resp.StatusCode == fiber.StatusAccepted {
+				assert.Equal(t, fmt.Sprintf("/%s/%d", effectivemobileapp.SelectHandler, c.expectedBody.Result.ID), resp.Header.Get("Location"))
+
+				var body effectivemobileapp.CreateResponse
+
+				rb, err := io.ReadAll(resp.Body)
+				assert.NoError(t, err)
+
+				err = json.Unmarshal(rb, &body)
+				assert.NoError(t, err)
+
+				assert.Equal(t, c.expectedBody, body)
+			}
+		})
+	}
+}
+
+func TestCreateBatch_Functional(t *testing.T) {
+	s := suite.New(t)
+
+	h := effectivemobileapp.Handlers{
+		Service: effectivemobileservice.UnimplementedHandlers{},
+		Log:     s.Log.Log,
+		Config:  s.Config.EffectiveMobile,
+	}
+
+	f := fiber.New()
+	defer f.Shutdown()
+
+	f.Post(fmt.Sprintf("/%s/%s", effectivemobileapp.PeopleHandler, effectivemobileapp.CreateBatchParameters), h.CreateBatch)
+
+	target := fmt.Sprintf("/%s/%s", effectivemobileapp.PeopleHandler, effectivemobileapp.CreateBatchParameters)
+
+	kz := "KZ"
+
+	tooMany := make([]storage.Person, s.Config.EffectiveMobile.BatchLimit+1)
+	for i := range tooMany {
+		tooMany[i] = storage.Person{Name: "test", Surname: "test"}
+	}
+
+	cases := []struct {
+		name               string
+		inMethod           string
+		inBody             effectivemobileapp.CreateBatchRequest
+		inTarget           string
+		expectedErr        error
+		expectedCode       int
+		expectedRetryAfter string
+		expectedBody       effectivemobileapp.CreateBatchResponse
+	}{
+		{
+			name:     "happy case",
+			inMethod: http.MethodPost,
+			inBody: effectivemobileapp.CreateBatchRequest{
+				People: []storage.Person{
+					{Name: "test", Surname: "test"},
+					{Name: "c404", Surname: "test"},
+					{Name: "test", Surname: "test", Patronymic: "test", CountryID: "kz"},
+				},
+			},
+			inTarget:     target,
+			expectedErr:  nil,
+			expectedCode: fiber.StatusCreated,
+			expectedBody: effectivemobileapp.CreateBatchResponse{
+				Code:    fiber.StatusCreated,
+				Message: effectivemobileapp.CreateBatchSuccess,
+				Result: effectivemobileservice.CreateBatchResult{
+					Created: []storage.Row{
+						{ID: 1, Name: "test", Surname: "test"},
+						{ID: 2, Name: "test", Surname: "test", Localization: &kz},
+					},
+					Skipped: []int{1},
+				},
+			},
+		},
+		{
+			name:         "empty batch case",
+			inMethod:     http.MethodPost,
+			inBody:       effectivemobileapp.CreateBatchRequest{},
+			inTarget:     target,
+			expectedErr:  nil,
+			expectedCode: fiber.StatusBadRequest,
+			expectedBody: effectivemobileapp.CreateBatchResponse{},
+		},
+		{
+			name:     "missing surname case",
+			inMethod: http.MethodPost,
+			inBody: effectivemobileapp.CreateBatchRequest{
+				People: []storage.Person{
+					{Name: "test", Surname: "test"},
+					{Name: "test"},
+				},
+			},
+			inTarget:     target,
+			expectedErr:  nil,
+			expectedCode: fiber.StatusBadRequest,
+			expectedBody: effectivemobileapp.CreateBatchResponse{},
+		},
+		{
+			name:     "bad country hint case",
+			inMethod: http.MethodPost,
+			inBody: effectivemobileapp.CreateBatchRequest{
+				People: []storage.Person{
+					{Name: "test", Surname: "test", CountryID: "K1"},
+				},
+			},
+			inTarget:     target,
+			expectedErr:  nil,
+			expectedCode: fiber.StatusBadRequest,
+			expectedBody: effectivemobileapp.CreateBatchResponse{},
+		},
+		{
+			name:         "batch limit case",
+			inMethod:     http.MethodPost,
+			inBody:       effectivemobileapp.CreateBatchRequest{People: tooMany},
+			inTarget:     target,
+			expectedErr:  nil,
+			expectedCode: fiber.StatusBadRequest,
+			expectedBody: effectivemobileapp.CreateBatchResponse{},
+		},
+		{
+			name:         "worng method case",
+			inMethod:     http.MethodGet,
+			inBody:       effectivemobileapp.CreateBatchRequest{},
+			inTarget:     target,
+			expectedErr:  nil,
+			expectedCode: fiber.StatusMethodNotAllowed,
+			expectedBody: effectivemobileapp.CreateBatchResponse{},
+		},
+		{
+			name:     "client unavailable case",
+			inMethod: http.MethodPost,
+			inBody: effectivemobileapp.CreateBatchRequest{
+				People: []storage.Person{{Name: "c503", Surname: "test"}},
+			},
+			inTarget:     target,
+			expectedErr:  nil,
+			expectedCode: fiber.StatusServiceUnavailable,
+			expectedBody: effectivemobileapp.CreateBatchResponse{},
+		},
+		{
+			name:     "client rate limited case",
+			inMethod: http.MethodPost,
+			inBody: effectivemobileapp.CreateBatchRequest{
+				People: []storage.Person{{Name: "c429", Surname: "test"}},
+			},
+			inTarget:           target,
+			expectedErr:        nil,
+			expectedCode:       fiber.StatusServiceUnavailable,
+			expectedRetryAfter: "2",
+			expectedBody:       effectivemobileapp.CreateBatchResponse{},
+		},
+		{
+			name:     "internal case",
+			inMethod: http.MethodPost,
+			inBody: effectivemobileapp.CreateBatchRequest{
+				People: []storage.Person{{Name: "500", Surname: "test"}},
+			},
+			inTarget:     target,
+			expectedErr:  nil,
+			expectedCode: fiber.StatusInternalServerError,
+			expectedBody: effectivemobileapp.CreateBatchResponse{},
+		},
+		{
+			name:     "timeout case",
+			inMethod: http.MethodPost,
+			inBody: effectivemobileapp.CreateBatchRequest{
+				People: []storage.Person{{Name: "504", Surname: "test"}},
+			},
+			inTarget:     target,
+			expectedErr:  nil,
+			expectedCode: fiber.StatusGatewayTimeout,
+			expectedBody: effectivemobileapp.CreateBatchResponse{},
+		},
+	}
+
+	for _, c := range cases {
+		s.T.Run(c.name, func(t *testing.T) {
+			b, _ := json.Marshal(c.inBody)
+
+			r := httptest.NewRequest(c.inMethod, c.inTarget, bytes.NewBuffer(b))
+			r.Header.Set("Content-Type", "application/json")
+
+			resp, err := f.Test(r, int(s.Config.EffectiveMobile.Client.Timeout))
+			if err != nil {
+				assert.Equal(t, c.expectedErr, err)
+			}
+			defer resp.Body.Close()
+
+			assert.Equal(t, c.expectedCode, resp.StatusCode)
+			assert.Equal(t, c.expectedRetryAfter, resp.Header.Get("Retry-After"))
+
+			if resp.StatusCode == fiber.StatusCreated {
+				var body effectivemobileapp.CreateBatchResponse
+
+				rb, err := io.ReadAll(resp.Body)
+				assert.NoError(t, err)
+
+				err = json.Unmarshal(rb, &body)
+				assert.NoError(t, err)
+
+				assert.Equal(t, c.expectedBody, body)
+			}
+		})
+	}
+}
+
+func TestReenrichByID_Functional(t *testing.T) {
+	s := suite.New(t)
+
+	h := effectivemobileapp.Handlers{
+		Service: effectivemobileservice.UnimplementedHandlers{},
+		Log:     s.Log.Log,
+		Config:  s.Config.EffectiveMobile,
+	}
+
+	f := fiber.New()
+	defer f.Shutdown()
+
+	f.Post(fmt.Sprintf("/%s/%s", effectivemobileapp.PeopleHandler, effectivemobileapp.ReenrichByIDParameters), h.ReenrichByID)
+
+	cases := []struct {
+		name               string
+		inMethod           string
+		inTarget           string
+		expectedErr        error
+		expectedCode       int
+		expectedETag       string
+		expectedRetryAfter string
+		expectedBody       effectivemobileapp.ReenrichByIDResponse
+	}{
+		{
+			name:         "happy case",
+			inMethod:     http.MethodPost,
+			inTarget:     fmt.Sprintf("/%s/1/enrich", effectivemobileapp.PeopleHandler),
+			expectedErr:  nil,
+			expectedCode: fiber.StatusOK,
+			expectedETag: `"2"`,
+			expectedBody: effectivemobileapp.ReenrichByIDResponse{
+				Code:    fiber.StatusOK,
+				Message: effectivemobileapp.ReenrichByIDSuccess,
+				Result:  storage.Row{ID: 1, Version: 2, EnrichmentStatus: storage.StatusEnriched},
+			},
+		},
+		{
+			name:         "wrong method case",
+			inMethod:     http.MethodGet,
+			inTarget:     fmt.Sprintf("/%s/1/enrich", effectivemobileapp.PeopleHandler),
+			expectedErr:  nil,
+			expectedCode: fiber.StatusMethodNotAllowed,
+			expectedBody: effectivemobileapp.ReenrichByIDResponse{},
+		},
+		{
+			name:         "storage not found case",
+			inMethod:     http.MethodPost,
+			inTarget:     fmt.Sprintf("/%s/s404/enrich", effectivemobileapp.PeopleHandler),
+			expectedErr:  nil,
+			expectedCode: fiber.StatusNotFound,
+			expectedBody: effectivemobileapp.ReenrichByIDResponse{},
+		},
+		{
+			name:         "client not found case",
+			inMethod:     http.MethodPost,
+			inTarget:     fmt.Sprintf("/%s/c404/enrich", effectivemobileapp.PeopleHandler),
+			expectedErr:  nil,
+			expectedCode: fiber.StatusNotFound,
+			expectedBody: effectivemobileapp.ReenrichByIDResponse{},
+		},
+		{
+			name:         "client unavailable case",
+			inMethod:     http.MethodPost,
+			inTarget:     fmt.Sprintf("/%s/c503/enrich", effectivemobileapp.PeopleHandler),
+			expectedErr:  nil,
+			expectedCode: fiber.StatusServiceUnavailable,
+			expectedBody: effectivemobileapp.ReenrichByIDResponse{},
+		},
+		{
+			name:               "client rate limited case",
+			inMethod:           http.MethodPost,
+			inTarget:           fmt.Sprintf("/%s/c429/enrich", effectivemobileapp.PeopleHandler),
+			expectedErr:        nil,
+			expectedCode:       fiber.StatusServiceUnavailable,
+			expectedRetryAfter: "2",
+			expectedBody:       effectivemobileapp.ReenrichByIDResponse{},
+		},
+		{
+			name:         "storage internal case",
+			inMethod:     http.MethodPost,
+			inTarget:     fmt.Sprintf("/%s/s500/enrich", effectivemobileapp.PeopleHandler),
+			expectedErr:  nil,
+			expectedCode: fiber.StatusInternalServerError,
+			expectedBody: effectivemobileapp.ReenrichByIDResponse{},
+		},
+		{
+			name:         "storage timeout case",
+			inMethod:     http.MethodPost,
+			inTarget:     fmt.Sprintf("/%s/s504/enrich", effectivemobileapp.PeopleHandler),
+			expectedErr:  nil,
+			expectedCode: fiber.StatusGatewayTimeout,
+			expectedBody: effectivemobileapp.ReenrichByIDResponse{},
+		},
+	}
+
+	for _, c := range cases {
+		s.T.Run(c.name, func(t *testing.T) {
+			r := httptest.NewRequest(c.inMethod, c.inTarget, nil)
+			r.Header.Set("Content-Type", "application/json")
+
+			resp, err := f.Test(r, int(s.Config.EffectiveMobile.Client.Timeout))
+			if err != nil {
+				assert.Equal(t, c.expectedErr, err)
+			}
+			defer resp.Body.Close()
+
+			assert.Equal(t, c.expectedCode, resp.StatusCode)
+			assert.Equal(t, c.expectedRetryAfter, resp.Header.Get("Retry-After"))
+
+			if resp.StatusCode == fiber.StatusOK {
+				assert.Equal(t, c.expectedETag, resp.Header.Get("ETag"))
+
+				var body effectivemobileapp.ReenrichByIDResponse
+
+				rb, err := io.ReadAll(resp.Body)
+				assert.NoError(t, err)
+
+				err = json.Unmarshal(rb, &body)
+				assert.NoError(t, err)
+
+				assert.Equal(t, c.expectedBody, body)
+			}
+		})
+	}
+}
+
+func TestReenrich_Functional(t *testing.T) {
+	s := suite.New(t)
+
+	h := effectivemobileapp.Handlers{
+		Service: effectivemobileservice.UnimplementedHandlers{},
+		Log:     s.Log.Log,
+		Config:  s.Config.EffectiveMobile,
+	}
+
+	f := fiber.New()
+	defer f.Shutdown()
+
+	f.Post(fmt.Sprintf("/%s", effectivemobileapp.ReenrichHandler), h.Reenrich)
+
+	before := func(year int) *time.Time {
+		t := time.Date(year, 1, 1, 0, 0, 0, 0, time.UTC)
+		return &t
+	}
+
+	cases := []struct {
+		name         string
+		inMethod     string
+		inTarget     string
+		inBody       interface{}
+		expectedErr  error
+		expectedCode int
+		expectedBody effectivemobileapp.ReenrichResponse
+	}{
+		{
+			name:     "happy case",
+			inMethod: http.MethodPost,
+			inTarget: fmt.Sprintf("/%s", effectivemobileapp.ReenrichHandler),
+			inBody: effectivemobileapp.ReenrichRequest{
+				Nationality:   []string{"ru", "KZ"},
+				CreatedBefore: before(2024),
+				LowConfidence: true,
+			},
+			expectedErr:  nil,
+			expectedCode: fiber.StatusAccepted,
+			expectedBody: effectivemobileapp.ReenrichResponse{
+				Code:    fiber.StatusAccepted,
+				Message: effectivemobileapp.ReenrichSuccess,
+				JobID:   1,
+			},
+		},
+		{
+			name:         "low confidence case",
+			inMethod:     http.MethodPost,
+			inTarget:     fmt.Sprintf("/%s", effectivemobileapp.ReenrichHandler),
+			inBody:       effectivemobileapp.ReenrichRequest{LowConfidence: true},
+			expectedErr:  nil,
+			expectedCode: fiber.StatusAccepted,
+			expectedBody: effectivemobileapp.ReenrichResponse{
+				Code:    fiber.StatusAccepted,
+				Message: effectivemobileapp.ReenrichSuccess,
+				JobID:   1,
+			},
+		},
+		{
+			name:         "no filters case",
+			inMethod:     http.MethodPost,
+			inTarget:     fmt.Sprintf("/%s", effectivemobileapp.ReenrichHandler),
+			inBody:       effectivemobileapp.ReenrichRequest{},
+			expectedErr:  nil,
+			expectedCode: fiber.StatusBadRequest,
+			expectedBody: effectivemobileapp.ReenrichResponse{},
+		},
+		{
+			name:         "bad nationality case",
+			inMethod:     http.MethodPost,
+			inTarget:     fmt.Sprintf("/%s", effectivemobileapp.ReenrichHandler),
+			inBody:       effectivemobileapp.ReenrichRequest{Nationality: []string{"RUS"}},
+			expectedErr:  nil,
+			expectedCode: fiber.StatusBadRequest,
+			expectedBody: effectivemobileapp.ReenrichResponse{},
+		},
+		{
+			name:         "bad created before case",
+			inMethod:     http.MethodPost,
+			inTarget:     fmt.Sprintf("/%s", effectivemobileapp.ReenrichHandler),
+			inBody:       map[string]interface{}{"created_before": "yesterday"},
+			expectedErr:  nil,
+			expectedCode: fiber.StatusBadRequest,
+			expectedBody: effectivemobileapp.ReenrichResponse{},
+		},
+		{
+			name:         "wrong method case",
+			inMethod:     http.MethodGet,
+			inTarget:     fmt.Sprintf("/%s", effectivemobileapp.ReenrichHandler),
+			inBody:       effectivemobileapp.ReenrichRequest{LowConfidence: true},
+			expectedErr:  nil,
+			expectedCode: fiber.StatusMethodNotAllowed,
+			expectedBody: effectivemobileapp.ReenrichResponse{},
+		},
+		{
+			name:         "storage internal case",
+			inMethod:     http.MethodPost,
+			inTarget:     fmt.Sprintf("/%s", effectivemobileapp.ReenrichHandler),
+			inBody:       effectivemobileapp.ReenrichRequest{CreatedBefore: before(500)},
+			expectedErr:  nil,
+			expectedCode: fiber.StatusInternalServerError,
+			expectedBody: effectivemobileapp.ReenrichResponse{},
+		},
+		{
+			name:         "storage timeout case",
+			inMethod:     http.MethodPost,
+			inTarget:     fmt.Sprintf("/%s", effectivemobileapp.ReenrichHandler),
+			inBody:       effectivemobileapp.ReenrichRequest{CreatedBefore: before(504)},
+			expectedErr:  nil,
+			expectedCode: fiber.StatusGatewayTimeout,
+			expectedBody: effectivemobileapp.ReenrichResponse{},
+		},
+	}
+
+	for _, c := range cases {
+		s.T.Run(c.name, func(t *testing.T) {
+			b, _ := json.Marshal(c.inBody)
+
+			r := httptest.NewRequest(c.inMethod, c.inTarget, bytes.NewBuffer(b))
+			r.Header.Set("Content-Type", "application/json")
+
+			resp, err := f.Test(r, int(s.Config.EffectiveMobile.Client.Timeout))
+			if err != nil {
+				assert.Equal(t, c.expectedErr, err)
+			}
+			defer resp.Body.Close()
+
+			assert.Equal(t, c.expectedCode, resp.StatusCode)
+
+			if resp.StatusCode == fiber.StatusAccepted {
+				var body effectivemobileapp.ReenrichResponse
+
+				rb, err := io.ReadAll(resp.Body)
+				assert.NoError(t, err)
+
+				err = json.Unmarshal(rb, &body)
+				assert.NoError(t, err)
+
+				assert.Equal(t, c.expectedBody, body)
+			}
+		})
+	}
+}
+
+func TestExport_Functional(t *testing.T) {
+	s := suite.New(t)
+
+	h := effectivemobileapp.Handlers{
+		Service: effectivemobileservice.UnimplementedHandlers{},
+		Log:     s.Log.Log,
+		Config:  s.Config.EffectiveMobile,
+	}
+
+	f := fiber.New()
+	defer f.Shutdown()
+
+	f.Post(fmt.Sprintf("/%s", effectivemobileapp.ExportHandler), h.Export)
+
+	cases := []struct {
+		name         string
+		inMethod     string
+		inTarget     string
+		expectedErr  error
+		expectedCode int
+		expectedBody effectivemobileapp.ExportResponse
+	}{
+		{
+			name:         "happy case",
+			inMethod:     http.MethodPost,
+			inTarget:     fmt.Sprintf("/%s?nationality=RU&age_gte=30", effectivemobileapp.ExportHandler),
+			expectedErr:  nil,
+			expectedCode: fiber.StatusAccepted,
+			expectedBody: effectivemobileapp.ExportResponse{
+				Code:    fiber.StatusAccepted,
+				Message: effectivemobileapp.ExportSuccess,
+				JobID:   1,
+			},
+		},
+		{
+			name:         "no filters case",
+			inMethod:     http.MethodPost,
+			inTarget:     fmt.Sprintf("/%s", effectivemobileapp.ExportHandler),
+			expectedErr:  nil,
+			expectedCode: fiber.StatusAccepted,
+			expectedBody: effectivemobileapp.ExportResponse{
+				Code:    fiber.StatusAccepted,
+				Message: effectivemobileapp.ExportSuccess,
+				JobID:   1,
+			},
+		},
+		{
+			name:         "bad filter case",
+			inMethod:     http.MethodPost,
+			inTarget:     fmt.Sprintf("/%s?age_gte=old", effectivemobileapp.ExportHandler),
+			expectedErr:  nil,
+			expectedCode: fiber.StatusBadRequest,
+			expectedBody: effectivemobileapp.ExportResponse{},
+		},
+		{
+			name:         "wrong method case",
+			inMethod:     http.MethodGet,
+			inTarget:     fmt.Sprintf("/%s", effectivemobileapp.ExportHandler),
+			expectedErr:  nil,
+			expectedCode: fiber.StatusMethodNotAllowed,
+			expectedBody: effectivemobileapp.ExportResponse{},
+		},
+		{
+			name:         "storage internal case",
+			inMethod:     http.MethodPost,
+			inTarget:     fmt.Sprintf("/%s?name=s500", effectivemobileapp.ExportHandler),
+			expectedErr:  nil,
+			expectedCode: fiber.StatusInternalServerError,
+			expectedBody: effectivemobileapp.ExportResponse{},
+		},
+		{
+			name:         "storage timeout case",
+			inMethod:     http.MethodPost,
+			inTarget:     fmt.Sprintf("/%s?name=s504", effectivemobileapp.ExportHandler),
+			expectedErr:  nil,
+			expectedCode: fiber.StatusGatewayTimeout,
+			expectedBody: effectivemobileapp.ExportResponse{},
+		},
+	}
+
+	for _, c := range cases {
+		s.T.Run(c.name, func(t *testing.T) {
+			r := httptest.NewRequest(c.inMethod, c.inTarget, nil)
+
+			resp, err := f.Test(r, int(s.Config.EffectiveMobile.Client.Timeout))
+			if err != nil {
+				assert.Equal(t, c.expectedErr, err)
+			}
+			defer resp.Body.Close()
+
+			assert.Equal(t, c.expectedCode, resp.StatusCode)
+
+			if resp.StatusCode == fiber.StatusAccepted {
+				var body effectivemobileapp.ExportResponse
+
+				rb, err := io.ReadAll(resp.Body)
+				assert.NoError(t, err)
+
+				err = json.Unmarshal(rb, &body)
+				assert.NoError(t, err)
+
+				assert.Equal(t, c.expectedBody, body)
+			}
+		})
+	}
+}
+
+func TestExportFile_Functional(t *testing.T) {
+	s := suite.New(t)
+
+	h := effectivemobileapp.Handlers{
+		Service: effectivemobileservice.UnimplementedHandlers{},
+		Log:     s.Log.Log,
+		Config:  s.Config.EffectiveMobile,
+	}
+
+	f := fiber.New()
+	defer f.Shutdown()
+
+	f.Get(fmt.Sprintf("/%s/%s", effectivemobileapp.ExportHandler, effectivemobileapp.ExportFileParameters), h.ExportFile)
+
+	cases := []struct {
+		name           string
+		inMethod       string
+		inTarget       string
+		expectedErr    error
+		expectedCode   int
+		expectedBody   string
+		expectedStatus effectivemobileapp.ExportStatusResponse
+	}{
+		{
+			name:         "happy case",
+			inMethod:     http.MethodGet,
+			inTarget:     fmt.Sprintf("/%s/1", effectivemobileapp.ExportHandler),
+			expectedErr:  nil,
+			expectedCode: fiber.StatusOK,
+			expectedBody: "{\"id\":1}\n",
+		},
+		{
+			name:         "pending case",
+			inMethod:     http.MethodGet,
+			inTarget:     fmt.Sprintf("/%s/202", effectivemobileapp.ExportHandler),
+			expectedErr:  nil,
+			expectedCode: fiber.StatusAccepted,
+			expectedStatus: effectivemobileapp.ExportStatusResponse{
+				Code:    fiber.StatusAccepted,
+				Message: effectivemobileapp.ExportPending,
+				JobID:   202,
+				Status:  "queued",
+			},
+		},
+		{
+			name:         "failed case",
+			inMethod:     http.MethodGet,
+			inTarget:     fmt.Sprintf("/%s/410", effectivemobileapp.ExportHandler),
+			expectedErr:  nil,
+			expectedCode: fiber.StatusGone,
+			expectedStatus: effectivemobileapp.ExportStatusResponse{
+				Code:      fiber.StatusGone,
+				Message:   effectivemobileapp.ExportFailed,
+				JobID:     410,
+				Status:    "dead",
+				LastError: "задача не может быть выполнена",
+			},
+		},
+		{
+			name:         "not found case",
+			inMethod:     http.MethodGet,
+			inTarget:     fmt.Sprintf("/%s/404", effectivemobileapp.ExportHandler),
+			expectedErr:  nil,
+			expectedCode: fiber.StatusNotFound,
+		},
+		{
+			name:         "bad id case",
+			inMethod:     http.MethodGet,
+			inTarget:     fmt.Sprintf("/%s/abc", effectivemobileapp.ExportHandler),
+			expectedErr:  nil,
+			expectedCode: fiber.StatusBadRequest,
+		},
+		{
+			name:         "wrong method case",
+			inMethod:     http.MethodPost,
+			inTarget:     fmt.Sprintf("/%s/1", effectivemobileapp.ExportHandler),
+			expectedErr:  nil,
+			expectedCode: fiber.StatusMethodNotAllowed,
+		},
+		{
+			name:         "storage internal case",
+			inMethod:     http.MethodGet,
+			inTarget:     fmt.Sprintf("/%s/500", effectivemobileapp.ExportHandler),
+			expectedErr:  nil,
+			expectedCode: fiber.StatusInternalServerError,
+		},
+		{
+			name:         "storage timeout case",
+			inMethod:     http.MethodGet,
+			inTarget:     fmt.Sprintf("/%s/504", effectivemobileapp.ExportHandler),
+			expectedErr:  nil,
+			expectedCode: fiber.StatusGatewayTimeout,
+		},
+	}
+
+	for _, c := range cases {
+		s.T.Run(c.name, func(t *testing.T) {
+			r := httptest.NewRequest(c.inMethod, c.inTarget, nil)
+
+			resp, err := f.Test(r, int(s.Config.EffectiveMobile.Client.Timeout))
+			if err != nil {
+				assert.Equal(t, c.expectedErr, err)
+			}
+			defer resp.Body.Close()
+
+			assert.Equal(t, c.expectedCode, resp.StatusCode)
+
+			if resp.StatusCode == fiber.StatusOK {
+				rb, err := io.ReadAll(resp.Body)
+				assert.NoError(t, err)
+
+				assert.Equal(t, "application/x-ndjson", resp.Header.Get(fiber.HeaderContentType))
+				assert.Equal(t, c.expectedBody, string(rb))
+			}
+
+			if resp.StatusCode == fiber.StatusAccepted || resp.StatusCode == fiber.StatusGone {
+				var body effectivemobileapp.ExportStatusResponse
+
+				rb, err := io.ReadAll(resp.Body)
+				assert.NoError(t, err)
+
+				err = json.Unmarshal(rb, &body)
+				assert.NoError(t, err)
+
+				assert.Equal(t, c.expectedStatus, body)
+			}
+		})
+	}
+}
+
+func TestSelect_Functional(t *testing.T) {
+	s := suite.New(t)
+
+	h := effectivemobileapp.Handlers{
+		Service: effectivemobileservice.UnimplementedHandlers{},
+		Log:     s.Log.Log,
+		Config:  s.Config.EffectiveMobile,
+	}
+
+	f := fiber.New()
+	defer f.Shutdown()
+
+	f.Get(fmt.Sprintf("/%s/%s", effectivemobileapp.SelectHandler, effectivemobileapp.SelectParameters), h.Select)
+
+	total := 1
+
+	cases := []struct {
+		name         string
+		inMethod     string
+		inParameters []string
+		inTarget     string
+		expectedErr  error
+		expectedCode int
+		expectedLink string
+		expectedETag string
+		expectedRaw  string
+		expectedBody effectivemobileapp.SelectResponse
+	}{
+		{
+			name:         "happy case",
+			inMethod:     http.MethodGet,
+			inParameters: nil,
+			inTarget:     fmt.Sprintf("/%s/1", effectivemobileapp.SelectHandler),
+			expectedErr:  nil,
+			expectedCode: fiber.StatusOK,
+			expectedBody: effectivemobileapp.SelectResponse{
+				Code:    fiber.StatusOK,
+				Message: effectivemobileapp.SelectSuccess,
+				Result:  []storage.Row{},
+			},
+		},
+		{
+			name:         "etag case",
+			inMethod:     http.MethodGet,
+			inParameters: nil,
+			inTarget:     fmt.Sprintf("/%s/etag", effectivemobileapp.SelectHandler),
+			expectedErr:  nil,
+			expectedCode: fiber.StatusOK,
+			expectedETag: `"3"`,
+			expectedBody: effectivemobileapp.SelectResponse{
+				Code:    fiber.StatusOK,
+				Message: effectivemobileapp.SelectSuccess,
+				Result:  []storage.Row{{ID: 1, Version: 3}},
+				End:     1,
+			},
+		},
+		{
+			name:         "bad request case",
+			inMethod:     http.MethodGet,
+			inParameters: nil,
+			inTarget:     fmt.Sprintf("/%s", effectivemobileapp.SelectHandler),
+			expectedErr:  nil,
+			expectedCode: fiber.StatusBadRequest,
+			expectedBody: effectivemobileapp.SelectResponse{},
+		},
+		{
+			name:         "worng method case",
+			inMethod:     http.MethodPost,
+			inParameters: nil,
+			inTarget:     fmt.Sprintf("/%s/1", effectivemobileapp.SelectHandler),
+			expectedErr:  nil,
+			expectedCode: fiber.StatusMethodNotAllowed,
+			expectedBody: effectivemobileapp.SelectResponse{},
+		},
+		{
+			name:         "storage not found case",
+			inMethod:     http.MethodGet,
+			inParameters: nil,
+			inTarget:     fmt.Sprintf("/%s/s404", effectivemobileapp.SelectHandler),
+			expectedErr:  nil,
+			expectedCode: fiber.StatusNotFound,
+			expectedBody: effectivemobileapp.SelectResponse{},
+		},
+		{
+			name:         "storage internal case",
+			inMethod:     http.MethodGet,
+			inParameters: nil,
+			inTarget:     fmt.Sprintf("/%s/s500", effectivemobileapp.SelectHandler),
+			expectedErr:  nil,
+			expectedCode: fiber.StatusInternalServerError,
+			expectedBody: effectivemobileapp.SelectResponse{},
+		},
+		{
+			name:         "storage timeout case",
+			inMethod:     http.MethodGet,
+			inParameters: nil,
+			inTarget:     fmt.Sprintf("/%s/s504", effectivemobileapp.SelectHandler),
+			expectedErr:  nil,
+			expectedCode: fiber.StatusGatewayTimeout,
+			expectedBody: effectivemobileapp.SelectResponse{},
+		},
+		{
+			name:         "filters case",
+			inMethod:     http.MethodGet,
+			inParameters: []string{"nationality=RU,UA,KZ", "gender=female", "age_gte=18", "age_lt=30", "name_prefix=Iv"},
+			inTarget:     fmt.Sprintf("/%s", effectivemobileapp.SelectHandler),
+			expectedErr:  nil,
+			expectedCode: fiber.StatusOK,
+			expectedBody: effectivemobileapp.SelectResponse{
+				Code:    fiber.StatusOK,
+				Message: effectivemobileapp.SelectSuccess,
+				Result:  []storage.Row{},
+			},
+		},
+		{
+			name:         "no matches case",
+			inMethod:     http.MethodGet,
+			inParameters: []string{"name=Nobody"},
+			inTarget:     fmt.Sprintf("/%s", effectivemobileapp.SelectHandler),
+			expectedErr:  nil,
+			expectedCode: fiber.StatusOK,
+			expectedRaw:  `"result":[]`,
+			expectedBody: effectivemobileapp.SelectResponse{
+				Code:    fiber.StatusOK,
+				Message: effectivemobileapp.SelectSuccess,
+				Result:  []storage.Row{},
+			},
+		},
+		{
+			name:         "legacy filter case",
+			inMethod:     http.MethodGet,
+			inParameters: []string{"filter=name", "value=Ivan"},
+			inTarget:     fmt.Sprintf("/%s", effectivemobileapp.SelectHandler),
+			expectedErr:  nil,
+			expectedCode: fiber.StatusOK,
+			expectedBody: effectivemobileapp.SelectResponse{
+				Code:    fiber.StatusOK,
+				Message: effectivemobileapp.SelectSuccess,
+				Result:  []storage.Row{},
+			},
+		},
+		{
+			name:         "invalid range filter case",
+			inMethod:     http.MethodGet,
+			inParameters: []string{"age_gte=abc"},
+			inTarget:     fmt.Sprintf("/%s", effectivemobileapp.SelectHandler),
+			expectedErr:  nil,
+			expectedCode: fiber.StatusBadRequest,
+			expectedBody: effectivemobileapp.SelectResponse{},
+		},
+		{
+			name:         "list in range filter case",
+			inMethod:     http.MethodGet,
+			inParameters: []string{"age_gte=18,20"},
+			inTarget:     fmt.Sprintf("/%s", effectivemobileapp.SelectHandler),
+			expectedErr:  nil,
+			expectedCode: fiber.StatusBadRequest,
+			expectedBody: effectivemobileapp.SelectResponse{},
+		},
+		{
+			name:         "probability filters case",
+			inMethod:     http.MethodGet,
+			inParameters: []string{"gender_probability_gte=0.9", "nationality_probability_lt=0.5", "age_count_gte=100"},
+			inTarget:     fmt.Sprintf("/%s", effectivemobileapp.SelectHandler),
+			expectedErr:  nil,
+			expectedCode: fiber.StatusOK,
+			expectedBody: effectivemobileapp.SelectResponse{
+				Code:    fiber.StatusOK,
+				Message: effectivemobileapp.SelectSuccess,
+				Result:  []storage.Row{},
+			},
+		},
+		{
+			name:         "invalid probability filter case",
+			inMethod:     http.MethodGet,
+			inParameters: []string{"gender_probability_gte=1.5"},
+			inTarget:     fmt.Sprintf("/%s", effectivemobileapp.SelectHandler),
+			expectedErr:  nil,
+			expectedCode: fiber.StatusBadRequest,
+			expectedBody: effectivemobileapp.SelectResponse{},
+		},
+		{
+			name:         "needs review filter case",
+			inMethod:     http.MethodGet,
+			inParameters: []string{"needs_review=true"},
+			inTarget:     fmt.Sprintf("/%s", effectivemobileapp.SelectHandler),
+			expectedErr:  nil,
+			expectedCode: fiber.StatusOK,
+			expectedBody: effectivemobileapp.SelectResponse{
+				Code:    fiber.StatusOK,
+				Message: effectivemobileapp.SelectSuccess,
+				Result:  []storage.Row{},
+			},
+		},
+		{
+			name:         "invalid needs review filter case",
+			inMethod:     http.MethodGet,
+			inParameters: []string{"needs_review=maybe"},
+			inTarget:     fmt.Sprintf("/%s", effectivemobileapp.SelectHandler),
+			expectedErr:  nil,
+			expectedCode: fiber.StatusBadRequest,
+			expectedBody: effectivemobileapp.SelectResponse{},
+		},
+		{
+			name:         "enrichment status filter case",
+			inMethod:     http.MethodGet,
+			inParameters: []string{"enrichment_status=pending_enrichment,enrichment_failed"},
+			inTarget:     fmt.Sprintf("/%s", effectivemobileapp.SelectHandler),
+			expectedErr:  nil,
+			expectedCode: fiber.StatusOK,
+			expectedBody: effectivemobileapp.SelectResponse{
+				Code:    fiber.StatusOK,
+				Message: effectivemobileapp.SelectSuccess,
+				Result:  []storage.Row{},
+			},
+		},
+		{
+			name:         "invalid enrichment status filter case",
+			inMethod:     http.MethodGet,
+			inParameters: []string{"enrichment_status=done"},
+			inTarget:     fmt.Sprintf("/%s", effectivemobileapp.SelectHandler),
+			expectedErr:  nil,
+			expectedCode: fiber.StatusBadRequest,
+			expectedBody: effectivemobileapp.SelectResponse{},
+		},
+		{
+			name:         "legacy probability filter case",
+			inMethod:     http.MethodGet,
+			inParameters: []string{"filter=gender_probability", "value=0.9"},
+			inTarget:     fmt.Sprintf("/%s", effectivemobileapp.SelectHandler),
+			expectedErr:  nil,
+			expectedCode: fiber.StatusBadRequest,
+			expectedBody: effectivemobileapp.SelectResponse{},
+		},
+		{
+			name:         "id with filters case",
+			inMethod:     http.MethodGet,
+			inParameters: []string{"gender=male"},
+			inTarget:     fmt.Sprintf("/%s/1", effectivemobileapp.SelectHandler),
+			expectedErr:  nil,
+			expectedCode: fiber.StatusBadRequest,
+			expectedBody: effectivemobileapp.SelectResponse{},
+		},
+		{
+			name:         "sort case",
+			inMethod:     http.MethodGet,
+			inParameters: []string{"gender=male", "sort=-age,surname,name"},
+			inTarget:     fmt.Sprintf("/%s", effectivemobileapp.SelectHandler),
+			expectedErr:  nil,
+			expectedCode: fiber.StatusOK,
+			expectedBody: effectivemobileapp.SelectResponse{
+				Code:    fiber.StatusOK,
+				Message: effectivemobileapp.SelectSuccess,
+				Result:  []storage.Row{},
+			},
+		},
+		{
+			name:         "unknown sort key case",
+			inMethod:     http.MethodGet,
+			inParameters: []string{"gender=male", "sort=-password"},
+			inTarget:     fmt.Sprintf("/%s", effectivemobileapp.SelectHandler),
+			expectedErr:  nil,
+			expectedCode: fiber.StatusBadRequest,
+			expectedBody: effectivemobileapp.SelectResponse{},
+		},
+		{
+			name:         "duplicate sort key case",
+			inMethod:     http.MethodGet,
+			inParameters: []string{"gender=male", "sort=age,-age"},
+			inTarget:     fmt.Sprintf("/%s", effectivemobileapp.SelectHandler),
+			expectedErr:  nil,
+			expectedCode: fiber.StatusBadRequest,
+			expectedBody: effectivemobileapp.SelectResponse{},
+		},
+		{
+			name:         "cursor case",
+			inMethod:     http.MethodGet,
+			inParameters: []string{"name=more", "sort=-age", "cursor=abc", "limit=10"},
+			inTarget:     fmt.Sprintf("/%s", effectivemobileapp.SelectHandler),
+			expectedErr:  nil,
+			expectedCode: fiber.StatusOK,
+			expectedLink: fmt.Sprintf(`</%s?cursor=next&limit=10&name=more&sort=-age>; rel="next"`, effectivemobileapp.SelectHandler),
+			expectedBody: effectivemobileapp.SelectResponse{
+				Code:       fiber.StatusOK,
+				Message:    effectivemobileapp.SelectSuccess,
+				Result:     []storage.Row{},
+				HasMore:    true,
+				NextCursor: "next",
+			},
+		},
+		{
+			name:         "offset pagination case",
+			inMethod:     http.MethodGet,
+			inParameters: []string{"name=more", "start=10", "end=20", "with_total=true"},
+			inTarget:     fmt.Sprintf("/%s", effectivemobileapp.SelectHandler),
+			expectedErr:  nil,
+			expectedCode: fiber.StatusOK,
+			expectedLink: fmt.Sprintf(`</%[1]s?end=30&name=more&start=20&with_total=true>; rel="next",</%[1]s?end=10&name=more&start=0&with_total=true>; rel="prev"`, effectivemobileapp.SelectHandler),
+			expectedBody: effectivemobileapp.SelectResponse{
+				Code:       fiber.StatusOK,
+				Message:    effectivemobileapp.SelectSuccess,
+				Result:     []storage.Row{},
+				Start:      10,
+				End:        10,
+				HasMore:    true,
+				Total:      &total,
+				NextCursor: "next",
+			},
+		},
+		{
+			name:         "cursor with offset case",
+			inMethod:     http.MethodGet,
+			inParameters: []string{"gender=male", "cursor=more", "start=10"},
+			inTarget:     fmt.Sprintf("/%s", effectivemobileapp.SelectHandler),
+			expectedErr:  nil,
+			expectedCode: fiber.StatusBadRequest,
+			expectedBody: effectivemobileapp.SelectResponse{},
+		},
+		{
+			name:         "invalid cursor case",
+			inMethod:     http.MethodGet,
+			inParameters: []string{"gender=male", "cursor=s400"},
+			inTarget:     fmt.Sprintf("/%s", effectivemobileapp.SelectHandler),
+			expectedErr:  nil,
+			expectedCode: fiber.StatusBadRequest,
+			expectedBody: effectivemobileapp.SelectResponse{},
+		},
+	}
+
+	for _, c := range cases {
+		s.T.Run(c.name, func(t *testing.T) {
+			var target string
+
+			if c.inParameters != nil {
 				target = fmt.Sprintf("%s?%s", c.inTarget, strings.Join(c.inParameters, "&&"))
 			} else {
 				target = c.inTarget
 			}
 
-			r := httptest.NewRequest(c.inMethod, target, nil)
+			r := httptest.NewRequest(c.inMethod, target, nil)
+			r.Header.Set("Content-Type", "application/json")
+
+			resp, err := f.Test(r, int(s.Config.EffectiveMobile.Client.Timeout))
+			if err != nil {
+				assert.Equal(t, c.expectedErr, err)
+			}
+			defer resp.Body.Close()
+
+			assert.Equal(t, c.expectedCode, resp.StatusCode)
+
+			if resp.StatusCode == fiber.StatusOK {
+				assert.Equal(t, c.expectedLink, resp.Header.Get("Link"))
+				assert.Equal(t, c.expectedETag, resp.Header.Get("ETag"))
+
+				var body effectivemobileapp.SelectResponse
+
+				rb, err := io.ReadAll(resp.Body)
+				assert.NoError(t, err)
+
+				if c.expectedRaw != "" {
+					assert.Contains(t, string(rb), c.expectedRaw)
+				}
+
+				err = json.Unmarshal(rb, &body)
+				assert.NoError(t, err)
+
+				assert.Equal(t, c.expectedBody, body)
+			}
+		})
+	}
+}
+
+func TestHistoryByID_Functional(t *testing.T) {
+	s := suite.New(t)
+
+	h := effectivemobileapp.Handlers{
+		Service: effectivemobileservice.UnimplementedHandlers{},
+		Log:     s.Log.Log,
+		Config:  s.Config.EffectiveMobile,
+	}
+
+	f := fiber.New()
+	defer f.Shutdown()
+
+	f.Get(fmt.Sprintf("/%s/%s", effectivemobileapp.PeopleHandler, effectivemobileapp.HistoryByIDParameters), h.HistoryByID)
+
+	cases := []struct {
+		name         string
+		inMethod     string
+		inTarget     string
+		expectedErr  error
+		expectedCode int
+		expectedBody effectivemobileapp.HistoryByIDResponse
+	}{
+		{
+			name:         "happy case",
+			inMethod:     http.MethodGet,
+			inTarget:     fmt.Sprintf("/%s/1/history", effectivemobileapp.PeopleHandler),
+			expectedErr:  nil,
+			expectedCode: fiber.StatusOK,
+			expectedBody: effectivemobileapp.HistoryByIDResponse{
+				Code:    fiber.StatusOK,
+				Message: effectivemobileapp.HistoryByIDSuccess,
+				Result: []storage.HistoryEntry{
+					{
+						ID:        1,
+						PersonID:  1,
+						Operation: storage.OperationCreate,
+						Actor:     "anonymous",
+						After:     &storage.Row{ID: 1, Version: 1},
+					},
+				},
+			},
+		},
+		{
+			name:         "wrong method case",
+			inMethod:     http.MethodPost,
+			inTarget:     fmt.Sprintf("/%s/1/history", effectivemobileapp.PeopleHandler),
+			expectedErr:  nil,
+			expectedCode: fiber.StatusMethodNotAllowed,
+			expectedBody: effectivemobileapp.HistoryByIDResponse{},
+		},
+		{
+			name:         "storage not found case",
+			inMethod:     http.MethodGet,
+			inTarget:     fmt.Sprintf("/%s/s404/history", effectivemobileapp.PeopleHandler),
+			expectedErr:  nil,
+			expectedCode: fiber.StatusNotFound,
+			expectedBody: effectivemobileapp.HistoryByIDResponse{},
+		},
+		{
+			name:         "storage internal case",
+			inMethod:     http.MethodGet,
+			inTarget:     fmt.Sprintf("/%s/s500/history", effectivemobileapp.PeopleHandler),
+			expectedErr:  nil,
+			expectedCode: fiber.StatusInternalServerError,
+			expectedBody: effectivemobileapp.HistoryByIDResponse{},
+		},
+		{
+			name:         "storage timeout case",
+			inMethod:     http.MethodGet,
+			inTarget:     fmt.Sprintf("/%s/s504/history", effectivemobileapp.PeopleHandler),
+			expectedErr:  nil,
+			expectedCode: fiber.StatusGatewayTimeout,
+			expectedBody: effectivemobileapp.HistoryByIDResponse{},
+		},
+	}
+
+	for _, c := range cases {
+		s.T.Run(c.name, func(t *testing.T) {
+			r := httptest.NewRequest(c.inMethod, c.inTarget, nil)
+			r.Header.Set("Content-Type", "application/json")
+
+			resp, err := f.Test(r, int(s.Config.EffectiveMobile.Client.Timeout))
+			if err != nil {
+				assert.Equal(t, c.expectedErr, err)
+			}
+			defer resp.Body.Close()
+
+			assert.Equal(t, c.expectedCode, resp.StatusCode)
+
+			if resp.StatusCode == fiber.StatusOK {
+				var body effectivemobileapp.HistoryByIDResponse
+
+				rb, err := io.ReadAll(resp.Body)
+				assert.NoError(t, err)
+
+				err = json.Unmarshal(rb, &body)
+				assert.NoError(t, err)
+
+				assert.Equal(t, c.expectedBody, body)
+			}
+		})
+	}
+}
+
+func TestSnapshotByID_Functional(t *testing.T) {
+	s := suite.New(t)
+
+	h := effectivemobileapp.Handlers{
+		Service: effectivemobileservice.UnimplementedHandlers{},
+		Log:     s.Log.Log,
+		Config:  s.Config.EffectiveMobile,
+	}
+
+	f := fiber.New()
+	defer f.Shutdown()
+
+	f.Get(fmt.Sprintf("/%s/%s", effectivemobileapp.PeopleHandler, effectivemobileapp.SnapshotByIDParameters), h.SnapshotByID)
+
+	cases := []struct {
+		name         string
+		inMethod     string
+		inTarget     string
+		expectedErr  error
+		expectedCode int
+		expectedBody effectivemobileapp.SnapshotByIDResponse
+	}{
+		{
+			name:         "happy case",
+			inMethod:     http.MethodGet,
+			inTarget:     fmt.Sprintf("/%s/1/snapshot?as_of=2024-01-01T00:00:00Z", effectivemobileapp.PeopleHandler),
+			expectedErr:  nil,
+			expectedCode: fiber.StatusOK,
+			expectedBody: effectivemobileapp.SnapshotByIDResponse{
+				Code:    fiber.StatusOK,
+				Message: effectivemobileapp.SnapshotByIDSuccess,
+				Result:  storage.Row{ID: 1, Version: 1},
+			},
+		},
+		{
+			name:         "absent as of case",
+			inMethod:     http.MethodGet,
+			inTarget:     fmt.Sprintf("/%s/1/snapshot", effectivemobileapp.PeopleHandler),
+			expectedErr:  nil,
+			expectedCode: fiber.StatusBadRequest,
+			expectedBody: effectivemobileapp.SnapshotByIDResponse{},
+		},
+		{
+			name:         "bad as of case",
+			inMethod:     http.MethodGet,
+			inTarget:     fmt.Sprintf("/%s/1/snapshot?as_of=yesterday", effectivemobileapp.PeopleHandler),
+			expectedErr:  nil,
+			expectedCode: fiber.StatusBadRequest,
+			expectedBody: effectivemobileapp.SnapshotByIDResponse{},
+		},
+		{
+			name:         "wrong method case",
+			inMethod:     http.MethodPost,
+			inTarget:     fmt.Sprintf("/%s/1/snapshot?as_of=2024-01-01T00:00:00Z", effectivemobileapp.PeopleHandler),
+			expectedErr:  nil,
+			expectedCode: fiber.StatusMethodNotAllowed,
+			expectedBody: effectivemobileapp.SnapshotByIDResponse{},
+		},
+		{
+			name:         "storage not found case",
+			inMethod:     http.MethodGet,
+			inTarget:     fmt.Sprintf("/%s/s404/snapshot?as_of=2024-01-01T00:00:00Z", effectivemobileapp.PeopleHandler),
+			expectedErr:  nil,
+			expectedCode: fiber.StatusNotFound,
+			expectedBody: effectivemobileapp.SnapshotByIDResponse{},
+		},
+		{
+			name:         "storage internal case",
+			inMethod:     http.MethodGet,
+			inTarget:     fmt.Sprintf("/%s/s500/snapshot?as_of=2024-01-01T00:00:00Z", effectivemobileapp.PeopleHandler),
+			expectedErr:  nil,
+			expectedCode: fiber.StatusInternalServerError,
+			expectedBody: effectivemobileapp.SnapshotByIDResponse{},
+		},
+		{
+			name:         "storage timeout case",
+			inMethod:     http.MethodGet,
+			inTarget:     fmt.Sprintf("/%s/s504/snapshot?as_of=2024-01-01T00:00:00Z", effectivemobileapp.PeopleHandler),
+			expectedErr:  nil,
+			expectedCode: fiber.StatusGatewayTimeout,
+			expectedBody: effectivemobileapp.SnapshotByIDResponse{},
+		},
+	}
+
+	for _, c := range cases {
+		s.T.Run(c.name, func(t *testing.T) {
+			r := httptest.NewRequest(c.inMethod, c.inTarget, nil)
 			r.Header.Set("Content-Type", "application/json")
 
 			resp, err := f.Test(r, int(s.Config.EffectiveMobile.Client.Timeout))
@@ -448,7 +2483,166 @@ func TestSelect_Functional(t *testing.T) {
 			assert.Equal(t, c.expectedCode, resp.StatusCode)
 
 			if resp.StatusCode == fiber.StatusOK {
-				var body effectivemobileapp.SelectResponse
+				var body effectivemobileapp.SnapshotByIDResponse
+
+				rb, err := io.ReadAll(resp.Body)
+				assert.NoError(t, err)
+
+				err = json.Unmarshal(rb, &body)
+				assert.NoError(t, err)
+
+				assert.Equal(t, c.expectedBody, body)
+			}
+		})
+	}
+}
+
+func TestMetrics_Functional(t *testing.T) {
+	s := suite.New(t)
+
+	h := effectivemobileapp.Handlers{
+		Service: effectivemobileservice.UnimplementedHandlers{},
+		Log:     s.Log.Log,
+		Config:  s.Config.EffectiveMobile,
+	}
+
+	f := fiber.New()
+	defer f.Shutdown()
+
+	f.Get(fmt.Sprintf("/%s", effectivemobileapp.MetricsHandler), h.Metrics)
+
+	cases := []struct {
+		name         string
+		inMethod     string
+		inTarget     string
+		expectedErr  error
+		expectedCode int
+		expectedBody effectivemobileapp.MetricsResponse
+	}{
+		{
+			name:         "happy case",
+			inMethod:     http.MethodGet,
+			inTarget:     fmt.Sprintf("/%s", effectivemobileapp.MetricsHandler),
+			expectedErr:  nil,
+			expectedCode: fiber.StatusOK,
+			expectedBody: effectivemobileapp.MetricsResponse{
+				Code:    fiber.StatusOK,
+				Message: effectivemobileapp.MetricsSuccess,
+				Result: client.Stats{
+					Cache: map[string]cache.Stats{
+						client.ProviderAgify: {Hits: 1, Misses: 1},
+					},
+					Breakers: map[string]string{
+						client.ProviderAgify:     breaker.StateClosed,
+						client.ProviderGenderize: breaker.StateOpen,
+					},
+					Quota: map[string]quota.Quota{
+						client.ProviderAgify: {Limit: 1000, Remaining: 999, ResetAt: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)},
+					},
+				},
+			},
+		},
+		{
+			name:         "wrong method case",
+			inMethod:     http.MethodPost,
+			inTarget:     fmt.Sprintf("/%s", effectivemobileapp.MetricsHandler),
+			expectedErr:  nil,
+			expectedCode: fiber.StatusMethodNotAllowed,
+			expectedBody: effectivemobileapp.MetricsResponse{},
+		},
+	}
+
+	for _, c := range cases {
+		s.T.Run(c.name, func(t *testing.T) {
+			r := httptest.NewRequest(c.inMethod, c.inTarget, nil)
+			r.Header.Set("Content-Type", "application/json")
+
+			resp, err := f.Test(r, int(s.Config.EffectiveMobile.Client.Timeout))
+			if err != nil {
+				assert.Equal(t, c.expectedErr, err)
+			}
+			defer resp.Body.Close()
+
+			assert.Equal(t, c.expectedCode, resp.StatusCode)
+
+			if resp.StatusCode == fiber.StatusOK {
+				var body effectivemobileapp.MetricsResponse
+
+				rb, err := io.ReadAll(resp.Body)
+				assert.NoError(t, err)
+
+				err = json.Unmarshal(rb, &body)
+				assert.NoError(t, err)
+
+				assert.Equal(t, c.expectedBody, body)
+			}
+		})
+	}
+}
+
+func TestHealth_Functional(t *testing.T) {
+	s := suite.New(t)
+
+	h := effectivemobileapp.Handlers{
+		Service: effectivemobileservice.UnimplementedHandlers{},
+		Log:     s.Log.Log,
+		Config:  s.Config.EffectiveMobile,
+	}
+
+	f := fiber.New()
+	defer f.Shutdown()
+
+	f.Get(fmt.Sprintf("/%s", effectivemobileapp.HealthHandler), h.Health)
+
+	cases := []struct {
+		name         string
+		inMethod     string
+		inTarget     string
+		expectedErr  error
+		expectedCode int
+		expectedBody effectivemobileapp.HealthResponse
+	}{
+		{
+			name:         "degraded case",
+			inMethod:     http.MethodGet,
+			inTarget:     fmt.Sprintf("/%s", effectivemobileapp.HealthHandler),
+			expectedErr:  nil,
+			expectedCode: fiber.StatusOK,
+			expectedBody: effectivemobileapp.HealthResponse{
+				Code:    fiber.StatusOK,
+				Message: effectivemobileapp.HealthSuccess,
+				Status:  effectivemobileapp.HealthStatusDegraded,
+				Breakers: map[string]string{
+					client.ProviderAgify:     breaker.StateClosed,
+					client.ProviderGenderize: breaker.StateOpen,
+				},
+			},
+		},
+		{
+			name:         "wrong method case",
+			inMethod:     http.MethodPost,
+			inTarget:     fmt.Sprintf("/%s", effectivemobileapp.HealthHandler),
+			expectedErr:  nil,
+			expectedCode: fiber.StatusMethodNotAllowed,
+			expectedBody: effectivemobileapp.HealthResponse{},
+		},
+	}
+
+	for _, c := range cases {
+		s.T.Run(c.name, func(t *testing.T) {
+			r := httptest.NewRequest(c.inMethod, c.inTarget, nil)
+			r.Header.Set("Content-Type", "application/json")
+
+			resp, err := f.Test(r, int(s.Config.EffectiveMobile.Client.Timeout))
+			if err != nil {
+				assert.Equal(t, c.expectedErr, err)
+			}
+			defer resp.Body.Close()
+
+			assert.Equal(t, c.expectedCode, resp.StatusCode)
+
+			if resp.StatusCode == fiber.StatusOK {
+				var body effectivemobileapp.HealthResponse
 
 				rb, err := io.ReadAll(resp.Body)
 				assert.NoError(t, err)
@@ -0,0 +1,246 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/xoticdsign/effectivemobile/internal/client/provider"
+)
+
+func TestProviderTarget_Unit(t *testing.T) {
+	cases := []struct {
+		name           string
+		inProvider     provider.Provider
+		inBase         string
+		inNames        []string
+		inCountryID    string
+		inKey          string
+		expectedTarget string
+	}{
+		{
+			name:           "single name case",
+			inProvider:     provider.Agify{},
+			inBase:         "https://api.agify.io",
+			inNames:        []string{"Dmitriy"},
+			expectedTarget: "https://api.agify.io?name=Dmitriy",
+		},
+		{
+			name:           "several names case",
+			inProvider:     provider.Genderize{},
+			inBase:         "https://api.genderize.io",
+			inNames:        []string{"Dmitriy", "Anna"},
+			expectedTarget: "https://api.genderize.io?name[]=Dmitriy&name[]=Anna",
+		},
+		{
+			name:           "country and key case",
+			inProvider:     provider.Agify{},
+			inBase:         "https://api.agify.io",
+			inNames:        []string{"Dmitriy"},
+			inCountryID:    "RU",
+			inKey:          "secret",
+			expectedTarget: "https://api.agify.io?name=Dmitriy&country_id=RU&apikey=secret",
+		},
+		{
+			name:           "escaped values case",
+			inProvider:     provider.Genderize{},
+			inBase:         "https://api.genderize.io",
+			inNames:        []string{"Анна Мария", "O'Neil"},
+			inKey:          "a&b=c",
+			expectedTarget: "https://api.genderize.io?name[]=%D0%90%D0%BD%D0%BD%D0%B0+%D0%9C%D0%B0%D1%80%D0%B8%D1%8F&name[]=O%27Neil&apikey=a%26b%3Dc",
+		},
+		{
+			name:           "base with query case",
+			inProvider:     provider.Agify{},
+			inBase:         "https://proxy.local/agify?region=eu",
+			inNames:        []string{"Dmitriy"},
+			inKey:          "secret",
+			expectedTarget: "https://proxy.local/agify?region=eu&name=Dmitriy&apikey=secret",
+		},
+		{
+			name:           "nationalize ignores country case",
+			inProvider:     provider.Nationalize{},
+			inBase:         "https://api.nationalize.io",
+			inNames:        []string{"Dmitriy", "Anna"},
+			inCountryID:    "RU",
+			inKey:          "secret",
+			expectedTarget: "https://api.nationalize.io?name[]=Dmitriy&name[]=Anna&apikey=secret",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			target := c.inProvider.Target(c.inBase, c.inNames, c.inCountryID, c.inKey)
+
+			assert.Equal(t, c.expectedTarget, target)
+		})
+	}
+}
+
+func TestProviderDecode_Unit(t *testing.T) {
+	cases := []struct {
+		name              string
+		inProvider        provider.Provider
+		inNames           []string
+		inBody            string
+		expectedEstimates map[string]provider.Estimate
+		expectedErr       bool
+	}{
+		{
+			name:       "agify single case",
+			inProvider: provider.Agify{},
+			inNames:    []string{"Dmitriy"},
+			inBody:     `{"count":1000,"name":"Dmitriy","age":42}`,
+			expectedEstimates: map[string]provider.Estimate{
+				"Dmitriy": {Age: 42, Count: 1000},
+			},
+		},
+		{
+			name:       "agify batch case",
+			inProvider: provider.Agify{},
+			inNames:    []string{"Dmitriy", "Anna"},
+			inBody:     `[{"count":1000,"name":"Dmitriy","age":42},{"count":500,"name":"Anna","age":30}]`,
+			expectedEstimates: map[string]provider.Estimate{
+				"Dmitriy": {Age: 42, Count: 1000},
+				"Anna":    {Age: 30, Count: 500},
+			},
+		},
+		{
+			name:       "agify unknown name case",
+			inProvider: provider.Agify{},
+			inNames:    []string{"Dmitriy", "Xyzzy"},
+			inBody:     `[{"count":1000,"name":"Dmitriy","age":42},{"count":0,"name":"Xyzzy","age":null}]`,
+			expectedEstimates: map[string]provider.Estimate{
+				"Dmitriy": {Age: 42, Count: 1000},
+			},
+		},
+		{
+			name:       "genderize single case",
+			inProvider: provider.Genderize{},
+			inNames:    []string{"Dmitriy"},
+			inBody:     `{"count":1000,"name":"Dmitriy","gender":"male","probability":0.99}`,
+			expectedEstimates: map[string]provider.Estimate{
+				"Dmitriy": {Gender: "male", Probability: 0.99, Count: 1000},
+			},
+		},
+		{
+			name:       "genderize batch case",
+			inProvider: provider.Genderize{},
+			inNames:    []string{"Dmitriy", "Anna"},
+			inBody:     `[{"count":1000,"name":"Dmitriy","gender":"male","probability":0.99},{"count":0,"name":"Anna","gender":null,"probability":0}]`,
+			expectedEstimates: map[string]provider.Estimate{
+				"Dmitriy": {Gender: "male", Probability: 0.99, Count: 1000},
+			},
+		},
+		{
+			name:       "nationalize single case",
+			inProvider: provider.Nationalize{},
+			inNames:    []string{"Dmitriy"},
+			inBody:     `{"count":1000,"name":"Dmitriy","country":[{"country_id":"RU","probability":0.6},{"country_id":"UA","probability":0.2}]}`,
+			expectedEstimates: map[string]provider.Estimate{
+				"Dmitriy": {Count: 1000, Countries: []provider.Country{{CountryID: "RU", Probability: 0.6}, {CountryID: "UA", Probability: 0.2}}},
+			},
+		},
+		{
+			name:       "nationalize batch case",
+			inProvider: provider.Nationalize{},
+			inNames:    []string{"Dmitriy", "Anna"},
+			inBody:     `[{"count":1000,"name":"Dmitriy","country":[{"country_id":"RU","probability":0.6}]},{"count":200,"name":"Anna","country":[]}]`,
+			expectedEstimates: map[string]provider.Estimate{
+				"Dmitriy": {Count: 1000, Countries: []provider.Country{{CountryID: "RU", Probability: 0.6}}},
+				"Anna":    {Count: 200, Countries: []provider.Country{}},
+			},
+		},
+		{
+			name:        "malformed body case",
+			inProvider:  provider.Agify{},
+			inNames:     []string{"Dmitriy"},
+			inBody:      `{"count":`,
+			expectedErr: true,
+		},
+		{
+			name:        "object for batch case",
+			inProvider:  provider.Genderize{},
+			inNames:     []string{"Dmitriy", "Anna"},
+			inBody:      `{"count":1000,"name":"Dmitriy","gender":"male","probability":0.99}`,
+			expectedErr: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			estimates, err := c.inProvider.Decode(c.inNames, []byte(c.inBody))
+
+			if c.expectedErr {
+				assert.Error(t, err)
+
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, c.expectedEstimates, estimates)
+		})
+	}
+}
+
+func TestRegistry_Unit(t *testing.T) {
+	cases := []struct {
+		name          string
+		inProviders   []provider.Provider
+		inRegister    provider.Provider
+		inGet         string
+		expectedNames []string
+		expectedErr   error
+		expectedGet   error
+	}{
+		{
+			name:          "default providers case",
+			inProviders:   []provider.Provider{provider.Agify{}, provider.Genderize{}, provider.Nationalize{}},
+			inGet:         provider.NameGenderize,
+			expectedNames: []string{provider.NameAgify, provider.NameGenderize, provider.NameNationalize},
+		},
+		{
+			name:          "register case",
+			inProviders:   []provider.Provider{provider.Agify{}},
+			inRegister:    provider.Nationalize{},
+			inGet:         provider.NameNationalize,
+			expectedNames: []string{provider.NameAgify, provider.NameNationalize},
+		},
+		{
+			name:          "duplicate case",
+			inProviders:   []provider.Provider{provider.Agify{}},
+			inRegister:    provider.Agify{},
+			inGet:         provider.NameAgify,
+			expectedNames: []string{provider.NameAgify},
+			expectedErr:   provider.ErrExists,
+		},
+		{
+			name:          "unknown case",
+			inProviders:   []provider.Provider{provider.Agify{}},
+			inGet:         "unknown",
+			expectedNames: []string{provider.NameAgify},
+			expectedGet:   provider.ErrUnknown,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			r := provider.NewRegistry(c.inProviders...)
+
+			if c.inRegister != nil {
+				err := r.Register(c.inRegister)
+
+				assert.ErrorIs(t, err, c.expectedErr)
+			}
+
+			p, err := r.Get(c.inGet)
+
+			assert.ErrorIs(t, err, c.expectedGet)
+			if c.expectedGet == nil {
+				assert.Equal(t, c.inGet, p.Name())
+			}
+			assert.Equal(t, c.expectedNames, r.Names())
+		})
+	}
+
+	assert.Equal(t, []string{provider.NameAgify, provider.NameGenderize, provider.NameNationalize}, provider.Default.Names())
+}
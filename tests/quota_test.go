@@ -0,0 +1,119 @@
+package tests
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/xoticdsign/effectivemobile/internal/client/quota"
+)
+
+func TestQuota_Unit(t *testing.T) {
+	cases := []struct {
+		name          string
+		inReserve     int
+		inHeader      map[string]string
+		inExhaust     time.Duration
+		inAcquires    int
+		expectedKnown bool
+		expectedMin   time.Duration
+		expectedMax   time.Duration
+	}{
+		{
+			name:          "unknown quota case",
+			inReserve:     0,
+			inAcquires:    3,
+			expectedKnown: false,
+		},
+		{
+			name:      "malformed header case",
+			inReserve: 0,
+			inHeader: map[string]string{
+				quota.HeaderLimit:     "100",
+				quota.HeaderRemaining: "none",
+				quota.HeaderReset:     "60",
+			},
+			inAcquires:    3,
+			expectedKnown: false,
+		},
+		{
+			name:      "remaining case",
+			inReserve: 0,
+			inHeader: map[string]string{
+				quota.HeaderLimit:     "100",
+				quota.HeaderRemaining: "3",
+				quota.HeaderReset:     "1",
+			},
+			inAcquires:    3,
+			expectedKnown: true,
+			expectedMin:   900 * time.Millisecond,
+			expectedMax:   time.Second,
+		},
+		{
+			name:      "reserve case",
+			inReserve: 2,
+			inHeader: map[string]string{
+				quota.HeaderLimit:     "100",
+				quota.HeaderRemaining: "3",
+				quota.HeaderReset:     "1",
+			},
+			inAcquires:    1,
+			expectedKnown: true,
+			expectedMin:   900 * time.Millisecond,
+			expectedMax:   time.Second,
+		},
+		{
+			name:          "exhausted case",
+			inReserve:     0,
+			inExhaust:     100 * time.Millisecond,
+			inAcquires:    0,
+			expectedKnown: true,
+			expectedMin:   50 * time.Millisecond,
+			expectedMax:   100 * time.Millisecond,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			q := quota.New(c.inReserve)
+
+			if c.inHeader != nil {
+				header := http.Header{}
+				for k, v := range c.inHeader {
+					header.Set(k, v)
+				}
+				q.Update(header)
+			}
+
+			if c.inExhaust > 0 {
+				q.Exhaust(c.inExhaust)
+			}
+
+			for range c.inAcquires {
+				assert.Zero(t, q.Acquire())
+			}
+
+			_, known := q.Quota()
+			assert.Equal(t, c.expectedKnown, known)
+
+			if !c.expectedKnown {
+				assert.Zero(t, q.Acquire())
+
+				return
+			}
+
+			wait := q.Acquire()
+			assert.GreaterOrEqual(t, wait, c.expectedMin)
+			assert.LessOrEqual(t, wait, c.expectedMax)
+
+			assert.Eventually(t, func() bool {
+				return q.Acquire() == 0
+			}, 2*c.expectedMax, 10*time.Millisecond)
+
+			_, known = q.Quota()
+			assert.False(t, known)
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+
+	effectivemobileapp "github.com/xoticdsign/effectivemobile/internal/app/effectivemobile"
+)
+
+func TestRequestContext_Functional(t *testing.T) {
+	cases := []struct {
+		name         string
+		inTimeout    time.Duration
+		inDisconnect bool
+		expectedErr  error
+	}{
+		{
+			name:         "disconnect case",
+			inTimeout:    10 * time.Second,
+			inDisconnect: true,
+			expectedErr:  context.Canceled,
+		},
+		{
+			name:         "timeout case",
+			inTimeout:    50 * time.Millisecond,
+			inDisconnect: false,
+			expectedErr:  context.DeadlineExceeded,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			errs := make(chan error, 1)
+
+			f := fiber.New(fiber.Config{DisableStartupMessage: true})
+			f.Use(effectivemobileapp.RequestContext(c.inTimeout))
+			f.Get("/", func(c *fiber.Ctx) error {
+				<-c.UserContext().Done()
+				errs <- c.UserContext().Err()
+
+				return nil
+			})
+
+			ln, err := net.Listen("tcp", "127.0.0.1:0")
+			assert.NoError(t, err)
+
+			go f.Listener(ln)
+			defer f.Shutdown()
+
+			conn, err := net.Dial("tcp", ln.Addr().String())
+			assert.NoError(t, err)
+			defer conn.Close()
+
+			_, err = fmt.Fprint(conn, "GET / HTTP/1.1\r\nHost: localhost\r\n\r\n")
+			assert.NoError(t, err)
+
+			if c.inDisconnect {
+				conn.Close()
+			}
+
+			select {
+			case err := <-errs:
+				assert.ErrorIs(t, err, c.expectedErr)
+
+			case <-time.After(5 * time.Second):
+				t.Fatal("request context hasn't been done")
+			}
+		})
+	}
+}
@@ -35,6 +35,11 @@ func New(t *testing.T) *Suite {
 		panic(err)
 	}
 
+	err = config.EffectiveMobile.Client.ReadProviders()
+	if err != nil {
+		panic(err)
+	}
+
 	return &Suite{
 		T: t,
 		Log: &logger.Logger{